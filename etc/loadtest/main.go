@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -16,6 +19,7 @@ const prod = "game.gymcadia.com"
 const local = "localhost:6060"
 
 var addr = flag.String("addr", prod, "http service address")
+var api = flag.String("api", "https://"+prod+"/api", "url of the login/register api")
 
 type EventType int
 type BaseEvent struct {
@@ -29,6 +33,34 @@ type KeyBoardEvent struct {
 	Id    int    `json:"id"`
 }
 
+type LoginEvent struct {
+	EventType EventType         `json:"eventType"`
+	Payload   map[string]string `json:"payload"`
+}
+
+// register creates a throwaway account and returns its session token
+func register() string {
+	credentials, _ := json.Marshal(map[string]string{
+		"username": fmt.Sprintf("load_%d", rand.Intn(1000000000)),
+		"password": "loadtest-password",
+	})
+
+	resp, err := http.Post(*api+"/register", "application/json", bytes.NewReader(credentials))
+	if err != nil {
+		log.Fatal("register:", err)
+	}
+	defer resp.Body.Close()
+
+	session := struct {
+		Token string `json:"token"`
+		Error string `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil || session.Token == "" {
+		log.Fatal("register:", err, session.Error)
+	}
+	return session.Token
+}
+
 func ReaderCoro(c *websocket.Conn) {
 	defer c.Close()
 	for {
@@ -52,9 +84,24 @@ func runClient() {
 
 	go ReaderCoro(c)
 
+	err = c.WriteJSON(LoginEvent{EventType: 15, Payload: map[string]string{"token": register()}})
+	if err != nil {
+		log.Fatal("login:", err)
+	}
+
 	Keys := []string{"w", "a", "s", "d"}
+	pressed := ""
 	time.Sleep(time.Millisecond * 100)
 	for i := 0; i > -1; i++ {
+		// the server moves the player while a key is held -> release the last key first
+		if pressed != "" {
+			err := c.WriteJSON(BaseEvent{EventType: 3, Payload: KeyBoardEvent{Key: pressed, Value: 0}})
+			if err != nil {
+				fmt.Println(err)
+				break
+			}
+		}
+
 		randomIndex := rand.Intn(len(Keys))
 		pick := Keys[randomIndex]
 		p := &KeyBoardEvent{
@@ -68,6 +115,7 @@ func runClient() {
 		}
 
 		err := c.WriteJSON(*e)
+		pressed = pick
 
 		//j, err := json.Marshal(e)
 		//c.WriteMessage(websocket.TextMessage, j)
@@ -0,0 +1,52 @@
+interface SessionResponse {
+    token: string
+    username: string
+}
+
+interface ErrorResponse {
+    error: string
+}
+
+// login and register both return a session token that is send with the login event
+export async function requestSession(action: "login" | "register", username: string, password: string): Promise<string> {
+    const response = await fetch(`${process.env.API_URL}/${action}`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ username, password })
+    })
+
+    const body: SessionResponse | ErrorResponse = await response.json()
+    if (!response.ok || "error" in body) {
+        throw new Error("error" in body ? body.error : `${action} failed`)
+    }
+
+    return body.token
+}
+
+// shows the login form until the player logged in or registered successfully
+export function showLoginForm(): Promise<string> {
+    const loginDiv = document.getElementById("loginDiv")
+    const usernameInput = document.getElementById("loginUsername") as HTMLInputElement
+    const passwordInput = document.getElementById("loginPassword") as HTMLInputElement
+    const errorText = document.getElementById("loginError")
+    const loginButton = document.getElementById("loginButton")
+    const registerButton = document.getElementById("registerButton")
+
+    loginDiv.style.display = "flex"
+
+    return new Promise((resolve) => {
+        const submit = async (action: "login" | "register") => {
+            errorText.innerText = ""
+            try {
+                const token = await requestSession(action, usernameInput.value, passwordInput.value)
+                loginDiv.style.display = "none"
+                resolve(token)
+            } catch (e) {
+                errorText.innerText = e.message
+            }
+        }
+
+        loginButton.onclick = () => submit("login")
+        registerButton.onclick = () => submit("register")
+    })
+}
@@ -1,5 +1,6 @@
 import { IItem, Item } from "../types/item"
 import Vector, { IVector } from "../types/vector"
+import { WireSchema } from "./wire"
 
 export enum EVENT_TYPES {
     NEW_USER_EVENT = 0,
@@ -29,7 +30,18 @@ export enum EVENT_TYPES {
     REMOVE_ITEM_EVENT = 25,
     UPDATE_INVENTORY_ITEM_EVENT = 26,
     PLAYER_CLICKED_INEVNTORY_ITEM_EVENT = 27,
-    UPDATE_EQUIPPED_INVENTORY_ITEM_EVENT = 28
+    UPDATE_EQUIPPED_INVENTORY_ITEM_EVENT = 28,
+    LOGIN_FAILED_EVENT = 29,
+    WIRE_SCHEMA_EVENT = 30,
+    CELL_STATE_EVENT = 31,
+    STATE_ACK_EVENT = 32,
+    CRAFT_EVENT = 33,
+    REPAIR_EVENT = 34,
+    PLAYER_DIED_EVENT = 35,
+    PLAYER_RESPAWNED_EVENT = 36,
+    SET_RESPAWN_EVENT = 37,
+    USE_ITEM_EVENT = 38,
+    PLAYER_STATS_EVENT = 39
 }
 
 export function createVector(x: number, y: number): Vector {
@@ -65,8 +77,32 @@ export function isNewPlayerEvent(value: any): value is NewPlayerEvent {
 export interface GameConfig {
     gridCellSize: number
     subCells: number
-    playerStepSize: number
     subCellSize: number
+    playerSpeed: number
+    movementTick: number
+    terrainSpeed: { [terrainType: string]: number }
+    lootRange: number
+    recipes: Recipe[]
+    buildables: Buildable[]
+}
+
+export interface Buildable {
+    resourceType: string
+    costs: ResourceMin[]
+    hitpoints: number
+    // width and height in sub cells
+    footprint: number
+    isSolid: boolean
+}
+
+export interface Recipe {
+    id: string
+    ingredients: ResourceMin[]
+    // resource type that has to be close to the player, empty if it can be crafted everywhere
+    station: string
+    // output is either a resource or an item
+    resource: ResourceMin
+    item: string
 }
 
 export interface UserInitEvent extends BaseEvent {
@@ -80,7 +116,8 @@ export interface UserInitEvent extends BaseEvent {
     gameConfig: GameConfig
     resources: ResourceMin[]
     items: IItem[]
-    equippedItems: string[] // uuids
+    equipment: { [slot: string]: string } // slot -> uuid of the item
+    stats: Stats
 }
 
 export function isUserInitEvent(value: any): value is UserInitEvent {
@@ -139,6 +176,9 @@ export interface IResource {
     hitpoints: Hitpoints
     isSolid: boolean,
     isLootable: boolean,
+    owner: string, // uuid of the player that built it
+    team: string,
+    decaysAt: number, // unix time, 0 if it doesn't decay
 }
 
 export interface ResourcePositionsEvent extends BaseEvent {
@@ -212,6 +252,7 @@ export function isRemoveGridCellEvent(value: any): value is RemoveGridCellEvent
 export interface SubCell {
     pos: Vector,
     terrainType: string
+    biome: string
 }
 
 export interface CellDataEvent extends BaseEvent {
@@ -237,6 +278,7 @@ export interface INpc {
     hitpoints: Hitpoints
     npcType: string
     attackSpeed: number
+    sprite: string
 }
 
 export interface NpcListEvent extends BaseEvent {
@@ -332,6 +374,7 @@ export function isUpdateInventoryItemEvent(value: any): value is UpdateInventory
 export interface UpdateEquippedInventoryItemEvent {
     eventType: string
     uuid: string
+    slot: string
     isEquipped: boolean
 }
 
@@ -342,6 +385,114 @@ export function isUpdateEquippedInventoryItemEvent(value: any): value is UpdateE
     )
 }
 
+export interface LoginFailedEvent extends BaseEvent {
+    reason: string
+}
+
+export function isLoginFailedEvent(value: any): value is LoginFailedEvent {
+    return (
+        isBaseEvent(value) &&
+        value.eventType === EVENT_TYPES.LOGIN_FAILED_EVENT
+    )
+}
+
+export interface WireSchemaEvent extends BaseEvent {
+    events: WireSchema
+}
+
+export function isWireSchemaEvent(value: any): value is WireSchemaEvent {
+    return (
+        isBaseEvent(value) &&
+        value.eventType === EVENT_TYPES.WIRE_SCHEMA_EVENT
+    )
+}
+
+export enum EntityKind {
+    Npc = 0,
+    Resource = 1
+}
+
+// only changed fields are set, npc/resource is set if the entity is new for us
+export interface EntityDelta {
+    id: string
+    kind: EntityKind
+    pos?: IVector
+    hitpoints?: Hitpoints
+    npc?: INpc
+    resource?: IResource
+}
+
+export interface CellStateEvent extends BaseEvent {
+    gridCellKey: string
+    seq: number
+    baseSeq: number
+    keyframe: boolean
+    entities: EntityDelta[]
+    removed: string[]
+}
+
+export function isCellStateEvent(value: any): value is CellStateEvent {
+    return (
+        isBaseEvent(value) &&
+        value.eventType === EVENT_TYPES.CELL_STATE_EVENT
+    )
+}
+
+export interface PlayerDiedEvent extends BaseEvent {
+    playerId: number
+    pos: IVector
+    respawnIn: number // ms
+}
+
+export function isPlayerDiedEvent(value: any): value is PlayerDiedEvent {
+    return (
+        isBaseEvent(value) &&
+        value.eventType === EVENT_TYPES.PLAYER_DIED_EVENT
+    )
+}
+
+export interface PlayerRespawnedEvent extends BaseEvent {
+    playerId: number
+    pos: IVector
+    hitpoints: Hitpoints
+}
+
+export function isPlayerRespawnedEvent(value: any): value is PlayerRespawnedEvent {
+    return (
+        isBaseEvent(value) &&
+        value.eventType === EVENT_TYPES.PLAYER_RESPAWNED_EVENT
+    )
+}
+
+export interface Attributes {
+    strength: number
+    agility: number
+    vitality: number
+}
+
+// derived by the server from the base attributes and the equipped items
+export interface Stats {
+    attributes: Attributes
+    maxHitpoints: number
+    minDamage: number
+    maxDamage: number
+    critChance: number // 0 to 1
+    attackSpeed: number // percent of the base attack speed
+    absorb: number
+    damageReduction: number // 0 to 1
+}
+
+export interface PlayerStatsEvent extends BaseEvent {
+    stats: Stats
+}
+
+export function isPlayerStatsEvent(value: any): value is PlayerStatsEvent {
+    return (
+        isBaseEvent(value) &&
+        value.eventType === EVENT_TYPES.PLAYER_STATS_EVENT
+    )
+}
+
 type EventTypes =
     NewPlayerEvent |
     UserInitEvent |
@@ -355,6 +506,7 @@ type EventTypes =
     RemoveGridCellEvent |
     MultipleEvents |
     CellDataEvent |
+    CellStateEvent |
     NpcListEvent |
     NpcTargetPositionEvent |
     UpdatePlayerEvent |
@@ -448,17 +600,57 @@ export function getPlayerPlacedResourceEvent(resourceType: string, pos: Vector):
     return JSON.stringify(e)
 }
 
+interface CraftEvent extends BaseEvent {
+    payload: {
+        recipeId: string
+    }
+}
+
+export function getCraftEvent(recipeId: string): string {
+    const e: CraftEvent = {
+        "eventType": EVENT_TYPES.CRAFT_EVENT,
+        "payload": {
+            "recipeId": recipeId
+        }
+    }
+    return JSON.stringify(e)
+}
+
+interface RepairEvent extends BaseEvent {
+    payload: {
+        id: number
+    }
+}
+
+export function getRepairEvent(resourceId: number): string {
+    const e: RepairEvent = {
+        "eventType": EVENT_TYPES.REPAIR_EVENT,
+        "payload": {
+            "id": resourceId
+        }
+    }
+    return JSON.stringify(e)
+}
+
+// sets the respawn point to where the player stands
+export function getSetRespawnEvent(): string {
+    const e: BaseEvent = {
+        "eventType": EVENT_TYPES.SET_RESPAWN_EVENT
+    }
+    return JSON.stringify(e)
+}
+
 interface LoginPlayerEvent extends BaseEvent {
     payload: {
-        uuid: string,
+        token: string,
     }
 }
 
-export function getLoginPlayerEvent(uuid: string): string {
+export function getLoginPlayerEvent(token: string): string {
     const e: LoginPlayerEvent = {
         "eventType": EVENT_TYPES.LOGIN_PLAYER_EVENT,
         "payload": {
-            "uuid": uuid
+            "token": token
         }
     }
     return JSON.stringify(e)
@@ -515,4 +707,41 @@ export function getPlayerClickedInventoryItemEvent(uuid: string): string {
         }
     }
     return JSON.stringify(e)
-}
\ No newline at end of file
+}
+interface UseItemEvent extends BaseEvent {
+    payload: {
+        uuid: string
+    }
+}
+
+// consumes one item of a consumable stack
+export function getUseItemEvent(uuid: string): string {
+    const e: UseItemEvent = {
+        "eventType": EVENT_TYPES.USE_ITEM_EVENT,
+        "payload": {
+            "uuid": uuid
+        }
+    }
+    return JSON.stringify(e)
+}
+
+export interface StateAck {
+    gridCellKey: string
+    seq: number
+}
+
+interface StateAckEvent extends BaseEvent {
+    payload: {
+        acks: StateAck[]
+    }
+}
+
+export function getStateAckEvent(acks: StateAck[]): string {
+    const e: StateAckEvent = {
+        "eventType": EVENT_TYPES.STATE_ACK_EVENT,
+        "payload": {
+            "acks": acks
+        }
+    }
+    return JSON.stringify(e)
+}
@@ -0,0 +1,149 @@
+// decoder for the binary wire format of the server (server/root/wire.go)
+// the server sends the schema as first json message, everything after it is binary
+
+export const BINARY_SUBPROTOCOL = "ws-game.binary.v1"
+export const JSON_SUBPROTOCOL = "ws-game.json.v1"
+
+export interface WireType {
+    kind: string
+    elem?: WireType
+    key?: WireType
+    fields?: WireField[]
+}
+
+export interface WireField {
+    name: string
+    type: WireType
+}
+
+export type WireSchema = { [eventType: string]: WireType }
+
+const textDecoder = new TextDecoder()
+
+class WireReader {
+    view: DataView
+    bytes: Uint8Array
+    offset: number
+
+    constructor(buffer: ArrayBuffer) {
+        this.view = new DataView(buffer)
+        this.bytes = new Uint8Array(buffer)
+        this.offset = 0
+    }
+
+    byte(): number {
+        return this.bytes[this.offset++]
+    }
+
+    uvarint(): number {
+        // no bit shifts, they only work for 32 bit
+        let value = 0
+        let multiplier = 1
+        for (; ;) {
+            const b = this.byte()
+            value += (b & 0x7f) * multiplier
+            if (b < 0x80) {
+                return value
+            }
+            multiplier *= 128
+        }
+    }
+
+    varint(): number {
+        // zigzag
+        const u = this.uvarint()
+        return u % 2 === 0 ? u / 2 : -(u + 1) / 2
+    }
+
+    string(length: number): string {
+        const s = textDecoder.decode(this.bytes.subarray(this.offset, this.offset + length))
+        this.offset += length
+        return s
+    }
+
+    uuid(): string {
+        const hex: string[] = []
+        for (let i = 0; i < 16; i++) {
+            hex.push(("0" + this.byte().toString(16)).slice(-2))
+        }
+        const s = hex.join("")
+        return `${s.slice(0, 8)}-${s.slice(8, 12)}-${s.slice(12, 16)}-${s.slice(16, 20)}-${s.slice(20)}`
+    }
+}
+
+function decodeValue(r: WireReader, t: WireType, schema: WireSchema): any {
+    switch (t.kind) {
+        case "bool":
+            return r.byte() === 1
+        case "int":
+            return r.varint()
+        case "uint":
+            return r.uvarint()
+        case "float32": {
+            const v = r.view.getFloat32(r.offset, true)
+            r.offset += 4
+            return v
+        }
+        case "float64": {
+            const v = r.view.getFloat64(r.offset, true)
+            r.offset += 8
+            return v
+        }
+        case "string":
+            return r.string(r.uvarint())
+        case "uuid": {
+            const prefix = r.uvarint()
+            return prefix === 0 ? r.uuid() : r.string(prefix - 1)
+        }
+        case "optional":
+            return r.byte() === 0 ? null : decodeValue(r, t.elem, schema)
+        case "list": {
+            const length = r.uvarint()
+            const list: any[] = []
+            for (let i = 0; i < length; i++) {
+                list.push(decodeValue(r, t.elem, schema))
+            }
+            return list
+        }
+        case "map": {
+            const length = r.uvarint()
+            const map: { [key: string]: any } = {}
+            for (let i = 0; i < length; i++) {
+                const key = decodeValue(r, t.key, schema)
+                map[key] = decodeValue(r, t.elem, schema)
+            }
+            return map
+        }
+        case "struct": {
+            const obj: { [key: string]: any } = {}
+            t.fields.forEach(f => {
+                obj[f.name] = decodeValue(r, f.type, schema)
+            })
+            return obj
+        }
+        case "event":
+            return decodeEvent(r, schema)
+    }
+    throw new Error(`unknown wire type ${t.kind}`)
+}
+
+function decodeEvent(r: WireReader, schema: WireSchema): any {
+    const eventType = r.varint()
+    const eventSchema = schema[eventType]
+    if (!eventSchema) {
+        throw new Error(`event type ${eventType} is not in the wire schema`)
+    }
+
+    // first field is the event type that was already read
+    const [eventTypeField, ...fields] = eventSchema.fields
+    const event: { [key: string]: any } = { [eventTypeField.name]: eventType }
+    fields.forEach(f => {
+        event[f.name] = decodeValue(r, f.type, schema)
+    })
+    return event
+}
+
+// decodes a binary message into the same object JSON.parse would return for the json encoding
+export function decodeBinaryEvent(buffer: ArrayBuffer, schema: WireSchema): any {
+    return decodeEvent(new WireReader(buffer), schema)
+}
@@ -8,6 +8,8 @@ import React from 'react';
 import * as ReactDOM from 'react-dom';
 import { UserStore } from './userStore';
 import { getHumanTiles } from './sprites/player';
+import { LocalStorageWrapper } from './localStorageWrapper';
+import { showLoginForm } from './auth';
 
 const load = (app: PIXI.Application, asset: string) => {
     return new Promise<void>((resolve) => {
@@ -55,6 +57,12 @@ const main = async () => {
     }
 
 
+    // players without a stored session have to login first
+    const localStorageWrapper = new LocalStorageWrapper()
+    if (localStorageWrapper.token.length === 0) {
+        localStorageWrapper.setToken(await showLoginForm())
+    }
+
     // Init Stores
     const userStore = new UserStore()
 
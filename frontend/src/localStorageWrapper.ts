@@ -1,23 +1,28 @@
-const UUID_KEY = 'secret_uuid'
+const TOKEN_KEY = 'session_token'
 
 export class LocalStorageWrapper {
-    uuid: string
+    token: string
 
     constructor() {
-        this.uuid = ""
-        let existingUUID: string | undefined = localStorage.getItem(UUID_KEY)
+        this.token = ""
+        let existingToken: string | null = localStorage.getItem(TOKEN_KEY)
 
-        if (existingUUID !== null) {
-            console.log(`client already has an uuid: ${existingUUID}`)
-            this.uuid = existingUUID
+        if (existingToken !== null) {
+            console.log(`client already has a session`)
+            this.token = existingToken
         } else {
-            console.log(`client has no uuid`)
+            console.log(`client has no session`)
         }
     }
 
-    setUUID(uuid: string) {
-        if (uuid.length == 0) return
-        this.uuid = uuid
-        localStorage.setItem(UUID_KEY, uuid)
+    setToken(token: string) {
+        if (token.length == 0) return
+        this.token = token
+        localStorage.setItem(TOKEN_KEY, token)
     }
-}
\ No newline at end of file
+
+    clearToken() {
+        this.token = ""
+        localStorage.removeItem(TOKEN_KEY)
+    }
+}
@@ -1,5 +1,5 @@
 import { Application, Container, Sprite } from 'pixi.js';
-import { isPlayerTargetPositionEvent, createVector, isUpdateResourceEvent, isResourcePositionsEvent, isRemovePlayerEvent, isNewPlayerEvent, RemovePlayerEvent, PlayerTargetPositionEvent, NewPlayerEvent, UserInitEvent, getPlayerPlacedResourceEvent, isUpdateInventoryEvent, isRemoveGridCellEvent, RemoveGridCellEvent, isMultipleEvents, getLoginPlayerEvent, isCellDataEvent, UpdateInventoryEvent, isNpcListEvent, isNpcTargetPositionEvent, NpcTargetPositionEvent, isUserInitEvent, GameConfig, isUpdateNpcEvent, isUpdatePlayerEvent, UpdatePlayerEvent, isNpcAttackAnimEvent, isItemPositionsEvent, isRemoveItemEvent, isUpdateInventoryItemEvent, isUpdateEquippedInventoryItemEvent, getLootResourceEvent, getHitResourceEvent } from './events/events';
+import { isPlayerTargetPositionEvent, createVector, isUpdateResourceEvent, isResourcePositionsEvent, isRemovePlayerEvent, isNewPlayerEvent, RemovePlayerEvent, PlayerTargetPositionEvent, NewPlayerEvent, UserInitEvent, getPlayerPlacedResourceEvent, isUpdateInventoryEvent, isRemoveGridCellEvent, RemoveGridCellEvent, isMultipleEvents, getLoginPlayerEvent, isCellDataEvent, UpdateInventoryEvent, isNpcListEvent, isNpcTargetPositionEvent, NpcTargetPositionEvent, isUserInitEvent, GameConfig, isUpdateNpcEvent, isUpdatePlayerEvent, UpdatePlayerEvent, isNpcAttackAnimEvent, isItemPositionsEvent, isRemoveItemEvent, isUpdateInventoryItemEvent, isUpdateEquippedInventoryItemEvent, getLootResourceEvent, getHitResourceEvent, getRepairEvent, isLoginFailedEvent, isWireSchemaEvent, isCellStateEvent, isPlayerDiedEvent, PlayerDiedEvent, isPlayerRespawnedEvent, PlayerRespawnedEvent, getSetRespawnEvent, isPlayerStatsEvent } from './events/events';
 import { Player } from './types/player';
 import Vector from './types/vector';
 import { getOtherPlayerSprite, getOwnPlayerSprite } from './sprites/player';
@@ -13,18 +13,25 @@ import { KeyboardHandler } from './modules/KeyboardHandler';
 import { SoundHandler } from './modules/SoundHandler';
 import TextHandler from './modules/TextHandler';
 import NpcHandler from './modules/NpcHandler';
+import StateHandler from './modules/StateHandler';
 import ResourceHandler, { getTextureFromResourceType } from './modules/ResourceHandler';
 import MiniMapHandler from './modules/MinimapHandler';
 import InventoryHandler from './modules/InventoryHandler';
 import BuilderHandler from './modules/BuilderHandler';
+import CraftingHandler from './modules/CraftingHandler';
 import ItemHandler from './modules/ItemHandler';
 import { isResource, Resource } from './types/resource';
 import Npc from './types/npc';
+import { BINARY_SUBPROTOCOL, decodeBinaryEvent, JSON_SUBPROTOCOL, WireSchema } from './events/wire';
+
+// the own player is snapped to the server position if the predicted position is off by more than this
+const MAX_PREDICTION_ERROR = 150
 
 export class Game extends Container {
     app: Application;
     keyHandler: KeyboardHandler
     ws: WebSocket
+    wireSchema?: WireSchema
 
     gameConfig: GameConfig
 
@@ -34,10 +41,12 @@ export class Game extends Container {
     tilemapHandler: TilemapHandler
     soundHandler: SoundHandler
     npcHandler: NpcHandler
+    stateHandler: StateHandler
     resourceHandler: ResourceHandler
     miniMapHandler: MiniMapHandler
     inventoryHandler: InventoryHandler
     builderHandler: BuilderHandler
+    craftingHandler: CraftingHandler
     itemHandler: ItemHandler
     hoveredElement: undefined | Resource | Npc
 
@@ -60,7 +69,9 @@ export class Game extends Container {
 
         this.userStore = userStore
 
-        this.ws = new WebSocket(process.env.WS_API)
+        // the server picks the binary protocol if it supports it, otherwise everything stays json
+        this.ws = new WebSocket(process.env.WS_API, [BINARY_SUBPROTOCOL, JSON_SUBPROTOCOL])
+        this.ws.binaryType = "arraybuffer"
 
         this.localStorageWrapper = new LocalStorageWrapper()
         this.inventoryHandler = new InventoryHandler(this.ws)
@@ -71,17 +82,31 @@ export class Game extends Container {
             console.log(error)
         }
 
+        this.ws.onclose = (e) => {
+            console.log(`disconnected: ${e.reason}`)
+        }
+
         this.ws.onopen = () => {
             console.log("connected!")
             // Imediatly after websocket connection is opened
-            this.ws.send(getLoginPlayerEvent(this.localStorageWrapper.uuid))
+            this.ws.send(getLoginPlayerEvent(this.localStorageWrapper.token))
         }
 
         this.ws.onmessage = (m) => {
-            if (typeof (m.data) != "string") {
+            let parsed: any
+            if (typeof (m.data) == "string") {
+                parsed = JSON.parse(m.data)
+            } else if (this.wireSchema) {
+                parsed = decodeBinaryEvent(m.data, this.wireSchema)
+            } else {
+                console.log("binary message before the wire schema was received")
+                return
+            }
+
+            if (isWireSchemaEvent(parsed)) {
+                this.wireSchema = parsed.events
                 return
             }
-            let parsed: any = JSON.parse(m.data)
 
             if (isMultipleEvents(parsed)) {
                 parsed.events.forEach(e => this.processEvent(e))
@@ -95,9 +120,10 @@ export class Game extends Container {
     }
 
     initWorld() {
-        this.keyHandler = new KeyboardHandler()
+        this.keyHandler = new KeyboardHandler(this.ws)
         this.soundHandler = new SoundHandler()
-        this.builderHandler = new BuilderHandler()
+        this.builderHandler = new BuilderHandler(this.gameConfig.buildables)
+        this.craftingHandler = new CraftingHandler(this.ws, this.gameConfig.recipes)
 
         this.miniMapHandler = new MiniMapHandler(this.player.currentPos)
 
@@ -115,6 +141,8 @@ export class Game extends Container {
         this.npcHandler = new NpcHandler()
         this.npcHandler.container.zIndex = 2
 
+        this.stateHandler = new StateHandler()
+
         this.textHandler = new TextHandler()
         this.textHandler.container.zIndex = 3
 
@@ -138,6 +166,8 @@ export class Game extends Container {
         this.addChild(this.inventoryHandler.container)
         this.addChild(this.builderHandler.container)
         this.addChild(this.builderHandler.toggleButton)
+        this.addChild(this.craftingHandler.container)
+        this.addChild(this.craftingHandler.toggleButton)
 
         this.cursorPos = createVector(0, 0)
 
@@ -173,10 +203,12 @@ export class Game extends Container {
             // if (!this.inventoryStore.canBuildResource(this.inventoryStore.selectedRecipe)) return
 
             // Players cursor position relativ to players position and locked to world grid
-            const pX = this.player.currentPos.x % 50
-            const pY = this.player.currentPos.y % 50
-            const x1 = Math.floor((cX + 25 + pX) / 50) * 50
-            const y1 = Math.floor((cY + 25 + pY) / 50) * 50
+            const tileSize = this.gameConfig.subCellSize
+            const pX = this.player.currentPos.x % tileSize
+            const pY = this.player.currentPos.y % tileSize
+            // center of the hovered sub cell, the server snaps the footprint to it
+            const x1 = Math.floor((cX + pX) / tileSize) * tileSize + tileSize / 2
+            const y1 = Math.floor((cY + pY) / tileSize) * tileSize + tileSize / 2
             const x = -(SCREEN_SIZE / 2) + x1 - pX + this.player.currentPos.x
             const y = -(SCREEN_SIZE / 2) + y1 - pY + this.player.currentPos.y
             const spawn = createVector(Math.trunc(x), Math.trunc(y))
@@ -186,6 +218,22 @@ export class Game extends Container {
         addEventListener('mouseup', () => {
             this.player.mouseDown = false
         });
+
+        // repairs the hovered structure, the server checks owner, range and materials
+        addEventListener('keydown', (e: KeyboardEvent) => {
+            if (e.key !== "r" || e.repeat) return
+            if (this.hoveredElement === undefined || !isResource(this.hoveredElement) || this.hoveredElement.isLootable) return
+
+            this.ws.send(getRepairEvent(this.hoveredElement.id))
+        });
+
+        // the player respawns where they stood when pressing p
+        addEventListener('keydown', (e: KeyboardEvent) => {
+            if (e.key !== "p" || e.repeat || this.player.dead) return
+
+            this.ws.send(getSetRespawnEvent())
+            this.textHandler.addItem("respawn point set", this.player.currentPos.copy(), "0xffffff")
+        });
         // bind update function
         this.update = this.update.bind(this);
 
@@ -196,7 +244,13 @@ export class Game extends Container {
 
     // Todo write short description of events
     processEvent(parsed: any) {
-        if (isPlayerTargetPositionEvent(parsed)) {
+        if (isLoginFailedEvent(parsed)) {
+
+            // session expired or account is logged in somewhere else -> show login again
+            console.log(`login failed: ${parsed.reason}`)
+            this.localStorageWrapper.clearToken()
+            location.reload()
+        } else if (isPlayerTargetPositionEvent(parsed)) {
 
             this.handlePlayerTargetPositionEvent(parsed)
         } else if (isUpdateInventoryEvent(parsed)) {
@@ -230,6 +284,9 @@ export class Game extends Container {
 
             this.miniMapHandler.addTiles(parsed)
             this.tilemapHandler.processCellDataEvent(parsed)
+        } else if (isCellStateEvent(parsed)) {
+
+            this.stateHandler.handleCellStateEvent(parsed, this)
         } else if (isNpcListEvent(parsed)) {
 
             this.npcHandler.handleNpcListEvent(parsed, this)
@@ -260,6 +317,16 @@ export class Game extends Container {
         } else if (isUpdateEquippedInventoryItemEvent(parsed)) {
 
             this.inventoryHandler.handleUpdateEquippedInventoryItemEvent(parsed)
+        } else if (isPlayerDiedEvent(parsed)) {
+
+            this.handlePlayerDiedEvent(parsed)
+        } else if (isPlayerRespawnedEvent(parsed)) {
+
+            this.handlePlayerRespawnedEvent(parsed)
+        } else if (isPlayerStatsEvent(parsed)) {
+
+            this.player.updateStats(parsed.stats)
+            this.inventoryHandler.setStats(parsed.stats)
         }
 
     }
@@ -289,12 +356,13 @@ export class Game extends Container {
         }
 
         // Translates cursor to relativ player position and grid
-        const pX = this.player.currentPos.x % 50
-        const pY = this.player.currentPos.y % 50
+        const tileSize = this.gameConfig.subCellSize
+        const pX = this.player.currentPos.x % tileSize
+        const pY = this.player.currentPos.y % tileSize
         const cX = this.cursorPos.x
         const cY = this.cursorPos.y
-        const x1 = Math.floor((cX + 25 + pX) / 50) * 50
-        const y1 = Math.floor((cY + 25 + pY) / 50) * 50
+        const x1 = Math.floor((cX + pX) / tileSize) * tileSize + tileSize / 2
+        const y1 = Math.floor((cY + pY) / tileSize) * tileSize + tileSize / 2
         this.cursorSprite.position.x = x1 - pX - SCREEN_SIZE / 2
         this.cursorSprite.position.y = y1 - pY - SCREEN_SIZE / 2
 
@@ -306,19 +374,23 @@ export class Game extends Container {
         // update npc positions
         this.npcHandler.update()
 
+        this.stateHandler.flushAcks(this.ws)
+
         this.attackAndLoot(delta)
     }
 
     attackAndLoot(delta: number) {
         this.player.updateCooldown(delta)
 
+        if (this.player.dead) return
+
         const hasTarget = this.hoveredElement !== undefined
         if (!hasTarget) return
 
         if (!this.player.mouseDown) return
         if (!this.player.canDoAction()) return
 
-        const isInRange = this.hoveredElement.pos.dist(this.player.currentPos) <= 150
+        const isInRange = this.hoveredElement.pos.dist(this.player.currentPos) <= this.gameConfig.lootRange
         if (!isInRange) return
 
         if (isResource(this.hoveredElement) && !this.hoveredElement.isLootable || !isResource(this.hoveredElement)) {
@@ -327,7 +399,7 @@ export class Game extends Container {
 
         const e: string = this.hoveredElement.gotClicked()
         this.ws.send(e)
-        this.player.actionCooldown = 0
+        this.player.startActionCooldown()
     }
 
     handleRemovePlayerEvent(parsed: RemovePlayerEvent) {
@@ -347,13 +419,15 @@ export class Game extends Container {
 
         this.npcHandler.removeGridCellNpcs(gridCellKey)
 
+        this.stateHandler.removeGridCell(gridCellKey)
+
         this.itemHandler.removeItemsInCell(gridCellKey)
     }
 
     handlePlayerTargetPositionEvent(parsed: PlayerTargetPositionEvent) {
         if (parsed.id === this.player.id) {
             const newPos = createVector(parsed.pos.x, parsed.pos.y)
-            if (newPos.dist(this.player.targetPos) > 1000 || parsed.force) {
+            if (newPos.dist(this.player.targetPos) > MAX_PREDICTION_ERROR || parsed.force) {
                 // only update players target pos with server side pos if a threshold is exceeded
                 this.player.targetPos = newPos
             }
@@ -401,7 +475,6 @@ export class Game extends Container {
         // - equipped items
         // - etc.
 
-        this.localStorageWrapper.setUUID(parsed.uuid)
         this.player = new Player(parsed.id, createVector(SCREEN_SIZE / 2, SCREEN_SIZE / 2), getOwnPlayerSprite(), parsed.hitpoints, false)
 
         this.player.currentPos.x = 0
@@ -418,7 +491,9 @@ export class Game extends Container {
 
         this.gameConfig = parsed.gameConfig
 
-        this.inventoryHandler.init(parsed.resources, parsed.items, parsed.equippedItems)
+        this.inventoryHandler.init(parsed.resources, parsed.items, parsed.equipment)
+        this.player.updateStats(parsed.stats)
+        this.inventoryHandler.setStats(parsed.stats)
 
 
         this.initWorld()
@@ -432,6 +507,39 @@ export class Game extends Container {
         this.inventoryHandler.handleUpdateInventoryEvent(parsed)
     }
 
+    handlePlayerDiedEvent(event: PlayerDiedEvent) {
+        if (this.player.id === event.playerId) {
+            this.player.die()
+            this.player.mouseDown = false
+
+            const pos = this.player.currentPos.copy()
+            pos.y -= 50
+            this.textHandler.addItem(`you died, respawn in ${Math.ceil(event.respawnIn / 1000)}s`, pos, '0xff0000')
+        } else {
+            const player = this.players.get(event.playerId)
+            if (player) {
+                player.die()
+            }
+        }
+    }
+
+    handlePlayerRespawnedEvent(event: PlayerRespawnedEvent) {
+        const pos = createVector(event.pos.x, event.pos.y)
+
+        if (this.player.id === event.playerId) {
+            this.player.respawn(pos, event.hitpoints)
+            this.player.updateHealthbar(this.player.spriteContainer)
+            this.updateWorldContainer()
+            this.updateMinimap()
+        } else {
+            const player = this.players.get(event.playerId)
+            if (player) {
+                player.respawn(pos, event.hitpoints)
+                player.updateHealthbar(player.spriteContainer)
+            }
+        }
+    }
+
     handleUpdatePlayerEvent(event: UpdatePlayerEvent) {
         const { playerId, hitpoints } = event
 
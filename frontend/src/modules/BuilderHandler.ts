@@ -1,16 +1,7 @@
 import { Container, Graphics, Loader, Sprite, Text } from "pixi.js";
+import { Buildable } from "../events/events";
 import { getTextureFromResourceType, itemTextures } from "./ResourceHandler";
 
-interface Ingredient {
-    resourceType: string
-    amount: number
-}
-
-interface IRecipe {
-    ingredients: Ingredient[],
-    buildResourceType: string,
-}
-
 const borderSize = 5
 class RecipeItem {
     lastYPosition: number
@@ -18,9 +9,9 @@ class RecipeItem {
     container: Container
     background: Graphics
     active: boolean
-    recipe: IRecipe
+    recipe: Buildable
 
-    constructor(previousUsedYPosition: number, recipe: IRecipe, builderHandler: BuilderHandler) {
+    constructor(previousUsedYPosition: number, recipe: Buildable, builderHandler: BuilderHandler) {
         this.recipe = recipe
         this.active = false
 
@@ -35,18 +26,18 @@ class RecipeItem {
         this.background.visible = this.active
         this.container.addChild(this.background)
 
-        const sprite = new Sprite(getTextureFromResourceType(recipe.buildResourceType))
+        const sprite = new Sprite(getTextureFromResourceType(recipe.resourceType))
         sprite.interactive = true
         sprite.on("click", () => {
             // disables all before selecting a new recipe
-            builderHandler.disableOtherRecipes(recipe.buildResourceType)
-            if (builderHandler.selectedBuildResourceType == recipe.buildResourceType) {
+            builderHandler.disableOtherRecipes(recipe.resourceType)
+            if (builderHandler.selectedBuildResourceType == recipe.resourceType) {
                 console.log("deselect!")
                 builderHandler.selectedBuildResourceType = ""
                 this.active = false
                 this.background.visible = this.active
             } else {
-                builderHandler.selectedBuildResourceType = recipe.buildResourceType
+                builderHandler.selectedBuildResourceType = recipe.resourceType
                 builderHandler.selectedAt = Date.now()
                 this.active = true
                 this.background.visible = this.active
@@ -54,9 +45,9 @@ class RecipeItem {
         })
         this.container.addChild(sprite)
 
-        // add texts with ingredients
-        recipe.ingredients.forEach(ing => {
-            const ingredientText = new Text(`${ing.resourceType} x ${ing.amount}`, { fontFamily: 'Arial Black', fontSize: 12, fill: 0x000000, align: 'center' });
+        // add texts with costs
+        recipe.costs.forEach(cost => {
+            const ingredientText = new Text(`${cost.resourceType} x ${cost.quantity}`, { fontFamily: 'Arial Black', fontSize: 12, fill: 0x000000, align: 'center' });
             ingredientText.position.set(70, internalY)
             this.container.addChild(ingredientText)
             internalY += 15
@@ -79,46 +70,17 @@ class RecipeItem {
     }
 }
 
-// Todo: get this from backend
-const getRecipeBook = (): IRecipe[] => {
-
-    const blockadeRecipe: IRecipe = {
-        ingredients: [
-            {
-                resourceType: "brick",
-                amount: 5
-            }
-        ],
-        buildResourceType: "blockade"
-    }
-
-    const woodBlockadeRecipe: IRecipe = {
-        ingredients: [
-            {
-                resourceType: "log",
-                amount: 5
-            }
-        ],
-        buildResourceType: "woodBlockade"
-    }
-
-    return [
-        blockadeRecipe,
-        woodBlockadeRecipe,
-    ]
-}
-
 class BuilderHandler {
     container: Container;
     toggleButton: Sprite;
     selectedBuildResourceType: string
     selectedAt: number
-    recipes: IRecipe[]
+    recipes: Buildable[]
     recipeItems: RecipeItem[]
 
-    constructor() {
+    constructor(buildables: Buildable[]) {
         this.selectedBuildResourceType = ""
-        this.recipes = getRecipeBook()
+        this.recipes = buildables
         this.initLayout()
 
         // add recipes to container
@@ -132,7 +94,7 @@ class BuilderHandler {
     }
 
     disableOtherRecipes(selectedResourceType: string) {
-        this.recipeItems.filter(r => r.recipe.buildResourceType !== selectedResourceType).forEach(r => {
+        this.recipeItems.filter(r => r.recipe.resourceType !== selectedResourceType).forEach(r => {
             r.deactivate()
         })
     }
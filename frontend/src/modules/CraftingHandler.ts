@@ -0,0 +1,105 @@
+import { Container, Graphics, Sprite, Text } from "pixi.js";
+import { getCraftEvent, Recipe } from "../events/events";
+import { getTextureFromResourceType, itemTextures } from "./ResourceHandler";
+
+const borderSize = 5
+const width = 200
+const height = 400
+
+class CraftingRecipeItem {
+    lastYPosition: number
+    container: Container
+
+    constructor(previousUsedYPosition: number, recipe: Recipe, ws: WebSocket) {
+        this.container = new Container()
+        let internalY = 10
+        this.container.position.set(10, previousUsedYPosition)
+
+        const output = recipe.item !== "" ? recipe.item : recipe.resource.resourceType
+        const sprite = new Sprite(getTextureFromResourceType(output))
+        sprite.interactive = true
+        sprite.on("click", () => {
+            // the server checks ingredients and station, the inventory gets updated by its events
+            ws.send(getCraftEvent(recipe.id))
+        })
+        this.container.addChild(sprite)
+
+        const ingredients = recipe.ingredients.map(ing => `${ing.resourceType} x ${ing.quantity}`)
+        if (recipe.station !== "") {
+            ingredients.push(`at ${recipe.station}`)
+        }
+
+        ingredients.forEach(line => {
+            const text = new Text(line, { fontFamily: 'Arial Black', fontSize: 12, fill: 0x000000, align: 'center' });
+            text.position.set(70, internalY)
+            this.container.addChild(text)
+            internalY += 15
+        })
+
+        internalY += 10
+        const line = new Graphics();
+        line.beginFill(0x4d4d4d);
+        line.drawRect(-borderSize, internalY, width - (borderSize * 2), 2);
+        line.endFill();
+        this.container.addChild(line)
+        internalY += 5
+
+        this.lastYPosition = previousUsedYPosition + internalY
+    }
+}
+
+// menu with the crafting recipes of the server, opened with the anvil next to the builder icon
+class CraftingHandler {
+    container: Container
+    toggleButton: Sprite
+
+    constructor(ws: WebSocket, recipes: Recipe[]) {
+        this.initLayout()
+
+        let previousUsedYPosition = 10
+        recipes.forEach(r => {
+            const recipeItem = new CraftingRecipeItem(previousUsedYPosition, r, ws)
+            this.container.addChild(recipeItem.container)
+            previousUsedYPosition = recipeItem.lastYPosition
+        })
+    }
+
+    initLayout() {
+        this.container = new Container()
+        this.container.visible = false
+        this.container.position.set(30 + width + 10, 30)
+
+        const background = new Graphics();
+        background.beginFill(0x4d4d4d);
+        background.drawRect(0, 0, width, height);
+        background.endFill();
+        this.container.addChild(background)
+
+        const backgroundInfill = new Graphics();
+        backgroundInfill.beginFill(0xBFBFBF);
+        backgroundInfill.drawRect(borderSize, borderSize, width - (borderSize * 2), height - (borderSize * 2));
+        backgroundInfill.endFill();
+        this.container.addChild(backgroundInfill)
+
+        const closeButton = new Sprite(itemTextures.button)
+        closeButton.position.set(170 - borderSize, -2 + borderSize)
+        closeButton.interactive = true
+        closeButton.on("click", () => {
+            this.toggle()
+        })
+        this.container.addChild(closeButton)
+
+        this.toggleButton = new Sprite(itemTextures.anvil)
+        this.toggleButton.position.set(0, 40)
+        this.toggleButton.interactive = true
+        this.toggleButton.on("click", () => {
+            this.toggle()
+        })
+    }
+
+    toggle() {
+        this.container.visible = !this.container.visible
+    }
+}
+
+export default CraftingHandler
@@ -1,6 +1,6 @@
 import { Container, Graphics, Sprite, Text, Texture } from "pixi.js";
 import { SCREEN_SIZE } from "../etc/const";
-import { ResourceMin, UpdateEquippedInventoryItemEvent, UpdateInventoryEvent, UpdateInventoryItemEvent } from "../events/events";
+import { ResourceMin, Stats, UpdateEquippedInventoryItemEvent, UpdateInventoryEvent, UpdateInventoryItemEvent } from "../events/events";
 import { IItem, Item } from "../types/item";
 import { getTextureFromResourceType } from "./ResourceHandler";
 
@@ -16,6 +16,7 @@ class InventoryHandler {
     resources: InventoryResource[]
     items: Item[]
     equippedItems: string[]
+    stats?: Stats
 
     ws: WebSocket
 
@@ -37,7 +38,7 @@ class InventoryHandler {
         this.container.addChild(background)
     }
 
-    init(resources: ResourceMin[], items: IItem[], equippedItems: string[]) {
+    init(resources: ResourceMin[], items: IItem[], equipment: { [slot: string]: string }) {
         resources.forEach(r => {
             this.addResource(r)
         })
@@ -47,7 +48,7 @@ class InventoryHandler {
             return item
         })
 
-        this.equippedItems = equippedItems
+        this.equippedItems = Object.values(equipment)
 
         this.render()
     }
@@ -126,6 +127,30 @@ class InventoryHandler {
 
             this.container.addChild(item.container)
         })
+
+        this.renderStats()
+    }
+
+    setStats(stats: Stats) {
+        this.stats = stats
+        this.render()
+    }
+
+    private renderStats() {
+        if (!this.stats) return
+
+        const { attributes, minDamage, maxDamage, critChance, attackSpeed, damageReduction } = this.stats
+        const lines = [
+            `str ${attributes.strength}  agi ${attributes.agility}  vit ${attributes.vitality}`,
+            `dmg ${minDamage}-${maxDamage}  crit ${Math.round(critChance * 100)}%`,
+            `speed ${attackSpeed}%  absorb ${Math.round(damageReduction * 100)}%`,
+        ]
+        const text = new Text(lines.join("\n"), {
+            fontFamily: 'Arial Black', fontSize: 12, fill: 0x000000, align: 'right'
+        });
+        text.anchor.set(1, 0)
+        text.position.set(SCREEN_SIZE - 5, 5)
+        this.container.addChild(text)
     }
 
     // add; update; remove if 0
@@ -142,11 +167,20 @@ class InventoryHandler {
     private addItem(event: UpdateInventoryItemEvent) {
         if (event.remove) {
             this.items = this.items.filter(i => i.raw.uuid !== event.item.uuid)
+            return
+        }
+
+        // stacks of consumables are updated in place
+        const item = new Item(event.item, this.ws, true)
+        const ix = this.items.findIndex(i => i.raw.uuid === event.item.uuid)
+        if (ix >= 0) {
+            this.items[ix] = item
         } else {
-            this.items.push(new Item(event.item, this.ws, true))
+            this.items.push(item)
         }
     }
 
+    // swapping items sends an unequip event for the replaced item
     handleUpdateEquippedInventoryItemEvent(event: UpdateEquippedInventoryItemEvent) {
         if (event.isEquipped) {
             this.equippedItems.push(event.uuid)
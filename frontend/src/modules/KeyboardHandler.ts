@@ -1,14 +1,18 @@
-import { getKeyBoardEvent, KeyStates } from "../events/events"
+import { createVector, getKeyBoardEvent, KeyStates } from "../events/events"
 import { Game } from "../main"
+import Vector from "../types/vector"
 
 export const VALID_KEYS = ["w", "a", "s", "d"]
 
+// pixi calls update with delta = 1 at 60 fps
+const FRAME_DURATION_MS = 1000 / 60
+
 export class KeyboardHandler {
     keys: Map<String, KeyStates>
-    actionCooldown: number
+    ws: WebSocket
 
-    constructor() {
-        this.actionCooldown = 0
+    constructor(ws: WebSocket) {
+        this.ws = ws
         this.keys = new Map()
         VALID_KEYS.forEach(k => {
             this.keys.set(k, KeyStates.UP)
@@ -24,56 +28,52 @@ export class KeyboardHandler {
                 this.keyChange(e.key, KeyStates.UP)
             }
         });
+        // keyup events are lost if the window loses focus -> release everything
+        window.addEventListener('blur', () => {
+            VALID_KEYS.forEach(k => this.keyChange(k, KeyStates.UP))
+        });
     }
 
     keyChange(key: string, value: KeyStates) {
-        if (this.keys.get(key) !== value && value == KeyStates.DOWN) {
-            // change in key state
-            // send to server
-            this.keys.set(key, value)
+        if (!this.keys.has(key) || this.keys.get(key) === value) {
+            return
         }
 
-        if (this.keys.get(key) === KeyStates.DOWN && value === KeyStates.UP) {
-            this.keys.set(key, KeyStates.UP)
-        }
+        // the server moves the player while a key is held, so only changes are sent
+        this.keys.set(key, value)
+        this.ws.send(getKeyBoardEvent(key, value))
     }
 
+    // predicts the movement the server does for the held keys, corrections arrive as forced target positions
     handleKeyBoard(delta: number, game: Game) {
-        this.actionCooldown -= delta
+        // the server ignores input of dead players
+        if (game.player.dead) return
+
+        const dir = createVector(0, 0)
+        if (this.keys.get("w") === KeyStates.DOWN) dir.y -= 1
+        if (this.keys.get("a") === KeyStates.DOWN) dir.x -= 1
+        if (this.keys.get("s") === KeyStates.DOWN) dir.y += 1
+        if (this.keys.get("d") === KeyStates.DOWN) dir.x += 1
 
-        if (this.actionCooldown > 0) {
+        if (dir.mag() === 0) {
             return
         }
 
-        VALID_KEYS.forEach(key => {
-            const value = this.keys.get(key)
-            if (value == KeyStates.DOWN) {
-
-                const newPos = game.player.targetPos.copy()
+        const speed = game.gameConfig.playerSpeed * this.terrainSpeed(game, game.player.targetPos) * (delta * FRAME_DURATION_MS) / game.gameConfig.movementTick
+        const newPos = game.player.targetPos.copy().add(dir.normalize().mult(speed))
 
-                switch (key) {
-                    case "w":
-                        newPos.y -= game.gameConfig.playerStepSize
-                        break
-                    case "a":
-                        newPos.x -= game.gameConfig.playerStepSize
-                        break
-                    case "s":
-                        newPos.y += game.gameConfig.playerStepSize
-                        break
-                    case "d":
-                        newPos.x += game.gameConfig.playerStepSize
-                        break
-                }
-
-                const hasCollision = game.resourceHandler.resources.filter(r => r.isSolid).some(r => r.pos.dist(newPos) < 40)
-                if (!hasCollision) {
-                    game.player.targetPos = newPos
-                    game.ws.send(getKeyBoardEvent(key, value))
-                    this.actionCooldown = 5
-                }
-            }
-        })
+        const hasCollision = game.resourceHandler.resources.filter(r => r.isSolid).some(r => r.pos.dist(newPos) < 40)
+        if (!hasCollision && this.terrainSpeed(game, newPos) > 0) {
+            game.player.targetPos = newPos
+        }
+    }
 
+    // same multipliers the server uses, unknown terrain doesn't slow down the prediction
+    terrainSpeed(game: Game, pos: Vector): number {
+        const terrain = game.tilemapHandler.terrainAt(pos)
+        if (terrain === undefined || !(terrain in game.gameConfig.terrainSpeed)) {
+            return 1
+        }
+        return game.gameConfig.terrainSpeed[terrain]
     }
-}
\ No newline at end of file
+}
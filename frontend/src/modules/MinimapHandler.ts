@@ -73,16 +73,10 @@ class MiniMapHandler {
         // let subCelX =aroundZero(playerPos.x) ? 0 : Math.floor(Math.abs(playerPos.x) / gridCellSize)
         // let subCelY =aroundZero(playerPos.y) ? 0 : Math.floor(Math.abs(playerPos.y) / gridCellSize)
 
-        // Calculating the players gridCell from his position
-        // Example:
-        // GCS -> GridCellSize = 1000
-        // pos.x -1600 pos.y 1100
-        // divide with GCS 
-        // pos.x -1.6 pos.y 1.1
-        // if we floor this we would get cell -2 1 but we want -1 1
-        // To work around this we use the absolute value, divide it by the gridcellsize,floor it and add the sign back afterwards 
-        const gridCelX = Math.floor(Math.abs(playerPos.x) / gridCellSize) * (playerPos.x < 0 ? -1 : 1)
-        const gridCelY = Math.floor(Math.abs(playerPos.y) / gridCellSize) * (playerPos.y < 0 ? -1 : 1)
+        // Calculating the players gridCell from his position, cells left of and above 0#0 are negative
+        // like on the server, e.g. pos.x -1600 is in cell -2
+        const gridCelX = Math.floor(playerPos.x / gridCellSize)
+        const gridCelY = Math.floor(playerPos.y / gridCellSize)
 
         if (this.tilemap !== undefined) {
             this.tilemap.destroy()
@@ -1,8 +1,8 @@
 import { Container } from "pixi.js"
-import { createVector, NpcAttackAnimEvent, NpcListEvent, NpcTargetPositionEvent, UpdateNpcEvent } from "../events/events"
+import { createVector, Hitpoints, INpc, NpcAttackAnimEvent, NpcListEvent, NpcTargetPositionEvent, UpdateNpcEvent } from "../events/events"
 import { Game } from "../main"
 import Npc, { spawnDeadAnim } from "../types/npc"
-import Vector from "../types/vector"
+import Vector, { IVector } from "../types/vector"
 
 
 class NpcHandler {
@@ -27,8 +27,49 @@ class NpcHandler {
         // update npc array after new npc listings event
     }
 
+    addNpc(gridCellKey: string, serial: INpc, game: Game) {
+        const npcs = this.npcMap.get(gridCellKey) || []
+        const n = new Npc(serial, game.ws, game.player, game)
+        this.container.addChild(n.container)
+        npcs.push(n)
+        this.npcMap.set(gridCellKey, npcs)
+    }
+
+    // removeNpc removes an npc without dead animation, dying npcs are handled by handleUpdateNpcEvent
+    removeNpc(gridCellKey: string, uuid: string) {
+        const npcs = this.npcMap.get(gridCellKey)
+        if (!npcs) {
+            return
+        }
+
+        const npc = npcs.find(n => n.UUID === uuid)
+        if (!npc) {
+            return
+        }
+
+        this.container.removeChild(npc.container)
+        npc.container.destroy()
+        this.npcMap.set(gridCellKey, npcs.filter(n => n.UUID !== uuid))
+    }
+
+    setNpcTargetPos(gridCellKey: string, uuid: string, pos: IVector) {
+        const npc = (this.npcMap.get(gridCellKey) || []).find(n => n.UUID === uuid)
+        if (npc) {
+            npc.targetPos = createVector(pos.x, pos.y)
+        }
+    }
+
+    setNpcHitpoints(gridCellKey: string, uuid: string, hitpoints: Hitpoints) {
+        const npc = (this.npcMap.get(gridCellKey) || []).find(n => n.UUID === uuid)
+        if (npc) {
+            npc.hitPoints.current = hitpoints.current
+            npc.hitPoints.max = hitpoints.max
+            npc.updateHealthbar(npc.container)
+        }
+    }
+
     removeGridCellNpcs(gridCellKey: string) {
-        this.npcMap.get(gridCellKey).forEach(npc => {
+        (this.npcMap.get(gridCellKey) || []).forEach(npc => {
             npc.container.destroy()
             this.container.removeChild(npc.container)
         })
@@ -1,5 +1,5 @@
 import { BaseTexture, Container, Spritesheet, Texture } from "pixi.js";
-import { createVector, ResourcePositionsEvent, UpdateResourceEvent } from "../events/events";
+import { createVector, Hitpoints, IResource, ResourcePositionsEvent, UpdateResourceEvent } from "../events/events";
 import { Game } from "../main";
 import { Resource } from "../types/resource";
 
@@ -15,6 +15,11 @@ interface ItemTextures {
     woodBlockade?: Texture
     stone?: Texture
     sword?: Texture
+    axe?: Texture
+    hammer?: Texture
+    ironIngot?: Texture
+    furnace?: Texture
+    anvil?: Texture
 }
 
 export function getItemTexture(path: string): ItemTextures {
@@ -84,6 +89,31 @@ export function getItemTexture(path: string): ItemTextures {
             sourceSize: { w: 32, h: 32 },
             spriteSourceSize: { x: 0, y: 0, w: 32, h: 32 }
         },
+        "axe": {
+            frame: { x: 1 * 32, y: 10 * 32, w: 32, h: 32 },
+            sourceSize: { w: 32, h: 32 },
+            spriteSourceSize: { x: 0, y: 0, w: 32, h: 32 }
+        },
+        "hammer": {
+            frame: { x: 4 * 32, y: 10 * 32, w: 32, h: 32 },
+            sourceSize: { w: 32, h: 32 },
+            spriteSourceSize: { x: 0, y: 0, w: 32, h: 32 }
+        },
+        "ironIngot": {
+            frame: { x: 3 * 32, y: 17 * 32, w: 32, h: 32 },
+            sourceSize: { w: 32, h: 32 },
+            spriteSourceSize: { x: 0, y: 0, w: 32, h: 32 }
+        },
+        "furnace": {
+            frame: { x: 7 * 32, y: 19 * 32, w: 32, h: 32 },
+            sourceSize: { w: 32, h: 32 },
+            spriteSourceSize: { x: 0, y: 0, w: 32, h: 32 }
+        },
+        "anvil": {
+            frame: { x: 4 * 32, y: 4 * 32, w: 32, h: 32 },
+            sourceSize: { w: 32, h: 32 },
+            spriteSourceSize: { x: 0, y: 0, w: 32, h: 32 }
+        },
     }
 
     const frameNames = [
@@ -96,7 +126,12 @@ export function getItemTexture(path: string): ItemTextures {
         "blockade",
         "woodBlockade",
         "stone",
-        "sword"
+        "sword",
+        "axe",
+        "hammer",
+        "ironIngot",
+        "furnace",
+        "anvil"
     ]
 
     atlas.frames = frames
@@ -123,6 +158,11 @@ export function getItemTexture(path: string): ItemTextures {
         itemTextures.woodBlockade = spritesheet.animations.frameNames[7]
         itemTextures.stone = spritesheet.animations.frameNames[8]
         itemTextures.sword = spritesheet.animations.frameNames[9]
+        itemTextures.axe = spritesheet.animations.frameNames[10]
+        itemTextures.hammer = spritesheet.animations.frameNames[11]
+        itemTextures.ironIngot = spritesheet.animations.frameNames[12]
+        itemTextures.furnace = spritesheet.animations.frameNames[13]
+        itemTextures.anvil = spritesheet.animations.frameNames[14]
     });
 
     return itemTextures
@@ -154,6 +194,14 @@ export const getTextureFromResourceType = (resourceType: string): Texture => {
         return itemTextures.stone
     } else if (resourceType == "sword") {
         return itemTextures.sword
+    } else if (resourceType == "axe") {
+        return itemTextures.axe
+    } else if (resourceType == "hammer") {
+        return itemTextures.hammer
+    } else if (resourceType == "ironIngot") {
+        return itemTextures.ironIngot
+    } else if (resourceType == "furnace") {
+        return itemTextures.furnace
     }
     console.error(`no sprite for ${resourceType}`)
     return itemTextures.placeholder
@@ -172,12 +220,46 @@ class ResourceHandler {
     }
 
     handleAddResourceEvent(parsed: ResourcePositionsEvent, game: Game) {
-        parsed.resources.forEach(r => {
-            const pos = createVector(r.pos.x, r.pos.y)
-            const resource: Resource = new Resource(r.gridCellKey, r.id, r.quantity, r.resourceType, pos, r.hitpoints, r.isSolid, r.isLootable, game)
-            this.resources.push(resource)
-            this.container.addChild(resource.container)
-        })
+        parsed.resources.forEach(r => this.addResource(r, game))
+    }
+
+    addResource(r: IResource, game: Game) {
+        // respawned resources arrive as event and with the cell state
+        if (this.resources.some(known => known.id === r.id)) {
+            return
+        }
+
+        const pos = createVector(r.pos.x, r.pos.y)
+        const resource: Resource = new Resource(r.gridCellKey, r.id, r.quantity, r.resourceType, pos, r.hitpoints, r.isSolid, r.isLootable, game)
+        this.resources.push(resource)
+        this.container.addChild(resource.container)
+    }
+
+    removeResource(id: number) {
+        const r = this.resources.find(r => r.id === id)
+        if (!r) {
+            return
+        }
+
+        r.container.children.forEach(c => c.destroy())
+        r.container.destroy()
+        this.container.removeChild(r.container)
+        this.resources = this.resources.filter(rO => rO.id !== id)
+    }
+
+    setResourceHitpoints(id: number, hitpoints: Hitpoints) {
+        const r = this.resources.find(r => r.id === id)
+        if (!r) {
+            return
+        }
+
+        r.hitPoints.current = hitpoints.current
+        r.hitPoints.max = hitpoints.max
+        if (r.hitPoints.current <= 0) {
+            this.removeResource(id)
+        } else {
+            r.updateHealthbar(r.container)
+        }
     }
 
     removeGridCellResources(gridCellKey: string) {
@@ -187,7 +269,7 @@ class ResourceHandler {
             this.container.removeChild(r.container)
         })
 
-        this.resources.filter(r => r.gridCellKey !== gridCellKey)
+        this.resources = this.resources.filter(r => r.gridCellKey !== gridCellKey)
     }
 
 
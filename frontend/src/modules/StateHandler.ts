@@ -0,0 +1,149 @@
+import { CellStateEvent, EntityDelta, EntityKind, getStateAckEvent, Hitpoints, INpc, IResource, StateAck } from "../events/events"
+import { Game } from "../main"
+import { IVector } from "../types/vector"
+
+// the server sends deltas relative to a snapshot we acked, keep enough of them around
+const MAX_SNAPSHOTS = 64
+
+interface EntityState {
+    kind: EntityKind
+    pos: IVector
+    hitpoints: Hitpoints
+    npc?: INpc
+    resource?: IResource
+}
+
+type Snapshot = Map<string, EntityState>
+
+interface CellState {
+    latest: number
+    snapshots: Map<number, Snapshot>
+}
+
+// applies the npc/resource state of the grid cells and acks it to the server
+class StateHandler {
+    cells: Map<string, CellState>
+    acks: StateAck[]
+
+    constructor() {
+        this.cells = new Map()
+        this.acks = []
+    }
+
+    handleCellStateEvent(event: CellStateEvent, game: Game) {
+        const { gridCellKey, seq, baseSeq, keyframe, entities, removed } = event
+
+        let cell = this.cells.get(gridCellKey)
+        if (!cell) {
+            cell = { latest: 0, snapshots: new Map() }
+            this.cells.set(gridCellKey, cell)
+        }
+
+        const displayed = cell.snapshots.get(cell.latest) || new Map()
+
+        let base: Snapshot = new Map()
+        if (!keyframe) {
+            base = cell.snapshots.get(baseSeq)
+            if (!base) {
+                // baseline is gone, the server sends a keyframe once it notices the missing acks
+                return
+            }
+        }
+
+        const snapshot: Snapshot = new Map(base)
+        entities.forEach(delta => {
+            // keyframes don't repeat the spawn data of entities we already know
+            const old = snapshot.get(delta.id) || (keyframe ? displayed.get(delta.id) : undefined)
+            const state = old ? { ...old } : spawnState(delta)
+            if (!state) {
+                return
+            }
+
+            if (delta.pos) {
+                state.pos = delta.pos
+            }
+            if (delta.hitpoints) {
+                state.hitpoints = delta.hitpoints
+            }
+            snapshot.set(delta.id, state)
+        })
+        removed.forEach(id => snapshot.delete(id))
+
+        this.render(gridCellKey, displayed, snapshot, game)
+
+        cell.latest = seq
+        cell.snapshots.set(seq, snapshot)
+        Array.from(cell.snapshots.keys())
+            .filter(s => s <= seq - MAX_SNAPSHOTS)
+            .forEach(s => cell.snapshots.delete(s))
+
+        this.acks.push({ gridCellKey, seq })
+    }
+
+    // render updates the handlers with the difference between what is displayed and the new snapshot
+    render(gridCellKey: string, displayed: Snapshot, snapshot: Snapshot, game: Game) {
+        snapshot.forEach((state, id) => {
+            const old = displayed.get(id)
+
+            if (!old) {
+                if (state.kind === EntityKind.Npc) {
+                    game.npcHandler.addNpc(gridCellKey, { ...state.npc, pos: state.pos, hitpoints: { ...state.hitpoints } }, game)
+                } else {
+                    game.resourceHandler.addResource({ ...state.resource, hitpoints: { ...state.hitpoints } }, game)
+                }
+                return
+            }
+
+            if (state.kind === EntityKind.Npc && (old.pos.x !== state.pos.x || old.pos.y !== state.pos.y)) {
+                game.npcHandler.setNpcTargetPos(gridCellKey, id, state.pos)
+            }
+
+            if (old.hitpoints.current !== state.hitpoints.current || old.hitpoints.max !== state.hitpoints.max) {
+                if (state.kind === EntityKind.Npc) {
+                    game.npcHandler.setNpcHitpoints(gridCellKey, id, state.hitpoints)
+                } else {
+                    game.resourceHandler.setResourceHitpoints(Number(id), state.hitpoints)
+                }
+            }
+        })
+
+        displayed.forEach((state, id) => {
+            if (snapshot.has(id)) {
+                return
+            }
+
+            if (state.kind === EntityKind.Npc) {
+                game.npcHandler.removeNpc(gridCellKey, id)
+            } else {
+                game.resourceHandler.removeResource(Number(id))
+            }
+        })
+    }
+
+    removeGridCell(gridCellKey: string) {
+        this.cells.delete(gridCellKey)
+    }
+
+    // acks are collected and sent once per frame
+    flushAcks(ws: WebSocket) {
+        if (this.acks.length === 0) {
+            return
+        }
+
+        ws.send(getStateAckEvent(this.acks))
+        this.acks = []
+    }
+}
+
+function spawnState(delta: EntityDelta): EntityState | undefined {
+    if (delta.npc) {
+        return { kind: delta.kind, pos: delta.npc.pos, hitpoints: delta.npc.hitpoints, npc: delta.npc }
+    }
+    if (delta.resource) {
+        return { kind: delta.kind, pos: delta.resource.pos, hitpoints: delta.resource.hitpoints, resource: delta.resource }
+    }
+    // we don't know this entity and got no spawn data for it
+    return undefined
+}
+
+export default StateHandler
@@ -2,15 +2,45 @@ import { CompositeTilemap } from "@pixi/tilemap";
 import { Container } from "pixi.js";
 import { CellDataEvent, GameConfig } from "../events/events";
 import { isBetween, randInt } from "../etc/math";
+import Vector from "../types/vector";
+
+// terrain with a single tile
+const terrainTiles: { [terrainType: string]: string } = {
+    "Water": "assets/water.png",
+    "ShallowWater": "assets/shallowWater.png",
+}
+
+// terrain with three tile variants (assets/<name>1-3.png), one is picked per sub cell
+const terrainTileVariants: { [terrainType: string]: string } = {
+    "Grass": "grass",
+    "Sand": "sand",
+    "Rock": "rock",
+    "Mud": "mud",
+    "Snow": "snow",
+}
+
+const randomTileVariant = (name: string): string => {
+    const r = randInt(0, 100)
+    if (r < 50) {
+        return `assets/${name}1.png`
+    }
+    if (isBetween(r, 50, 75)) {
+        return `assets/${name}2.png`
+    }
+    return `assets/${name}3.png`
+}
 
 class TilemapHandler {
     terrainTileMap: Map<string, CompositeTilemap>
+    // terrain types of the sub cells per grid cell, indexed by x * subCells + y
+    terrainTypes: Map<string, string[]>
     container: Container
     gameConfig: GameConfig
 
     constructor(gameConfig: GameConfig) {
         this.gameConfig = gameConfig
         this.terrainTileMap = new Map()
+        this.terrainTypes = new Map()
         this.container = new Container()
         this.container.interactive = true
     }
@@ -21,54 +51,44 @@ class TilemapHandler {
 
     processCellDataEvent(event: CellDataEvent) {
         const cellTilemap = new CompositeTilemap()
+        const terrainTypes: string[] = []
 
         event.subCells.forEach(sc => {
+            terrainTypes[sc.pos.x * this.gameConfig.subCells + sc.pos.y] = sc.terrainType
 
             const scPosX = event.pos.x * this.gameConfig.gridCellSize + (sc.pos.x * this.gameConfig.subCellSize)
             const scPosY = event.pos.y * this.gameConfig.gridCellSize + (sc.pos.y * this.gameConfig.subCellSize)
 
 
-            if (sc.terrainType == "Grass") {
-                const r = randInt(0, 100)
-                if (r < 50) {
-                    cellTilemap.tile("assets/grass1.png", scPosX, scPosY)
-                }
-                if (isBetween(r, 50, 75)) {
-                    cellTilemap.tile("assets/grass2.png", scPosX, scPosY)
-                }
-                if (isBetween(r, 76, 100)) {
-                    cellTilemap.tile("assets/grass3.png", scPosX, scPosY)
-                }
-            }
-
-            if (sc.terrainType == "Water") {
-                cellTilemap.tile("assets/water.png", scPosX, scPosY)
-            }
-
-            if (sc.terrainType == "ShallowWater") {
-                cellTilemap.tile("assets/shallowWater.png", scPosX, scPosY)
+            if (sc.terrainType in terrainTiles) {
+                cellTilemap.tile(terrainTiles[sc.terrainType], scPosX, scPosY)
+            } else if (sc.terrainType in terrainTileVariants) {
+                cellTilemap.tile(randomTileVariant(terrainTileVariants[sc.terrainType]), scPosX, scPosY)
             }
-
-            if (sc.terrainType == "Sand") {
-                const r = randInt(0, 100)
-                if (r < 50) {
-                    cellTilemap.tile("assets/sand1.png", scPosX, scPosY)
-                }
-                if (isBetween(r, 50, 75)) {
-                    cellTilemap.tile("assets/sand2.png", scPosX, scPosY)
-                }
-                if (isBetween(r, 76, 100)) {
-                    cellTilemap.tile("assets/sand3.png", scPosX, scPosY)
-                }
-            }
-
         })
 
+        this.terrainTypes.set(event.gridCellKey, terrainTypes)
         this.terrainTileMap.set(event.gridCellKey, cellTilemap)
         this.container.addChild(cellTilemap)
     }
 
+    // returns undefined if the grid cell data was not received yet
+    terrainAt(pos: Vector): string | undefined {
+        const subX = Math.floor(pos.x / this.gameConfig.subCellSize)
+        const subY = Math.floor(pos.y / this.gameConfig.subCellSize)
+        const cellX = Math.floor(subX / this.gameConfig.subCells)
+        const cellY = Math.floor(subY / this.gameConfig.subCells)
+
+        const terrainTypes = this.terrainTypes.get(`${cellX}#${cellY}`)
+        if (!terrainTypes) {
+            return undefined
+        }
+
+        return terrainTypes[(subX - cellX * this.gameConfig.subCells) * this.gameConfig.subCells + (subY - cellY * this.gameConfig.subCells)]
+    }
+
     removeGridCellTiles(gridCellKey: string) {
+        this.terrainTypes.delete(gridCellKey)
         if (this.terrainTileMap.has(gridCellKey)) {
 
             // remove tiles from container
@@ -1,5 +1,5 @@
 import { Container, Graphics, Sprite, Text } from "pixi.js"
-import { createVector, getPlayerClickedGroundItemEvent, getPlayerClickedInventoryItemEvent } from "../events/events"
+import { createVector, getPlayerClickedGroundItemEvent, getPlayerClickedInventoryItemEvent, getUseItemEvent } from "../events/events"
 import { getTextureFromResourceType } from "../modules/ResourceHandler"
 import Vector, { IVector } from "./vector"
 
@@ -23,8 +23,10 @@ export interface IItem {
     maxDamage: number
     absorb: number
     attackSpeed: number
+    heal: number // consumables only
 
     boni: Boni[]
+    requirements: Boni[] // min attributes to equip it
 }
 
 // item object used in client for visualization with pixijs sprites
@@ -81,8 +83,12 @@ export class Item {
 
         this.sprite.on("click", () => {
             if (isInventoryItem) {
-                //activate / equip / use item in inventory -> send event to backend etc.
-                ws.send(getPlayerClickedInventoryItemEvent(this.raw.uuid))
+                // consumables are used, everything else gets equipped
+                if (this.raw.itemType === "consumableItem") {
+                    ws.send(getUseItemEvent(this.raw.uuid))
+                } else {
+                    ws.send(getPlayerClickedInventoryItemEvent(this.raw.uuid))
+                }
             } else {
                 console.log("clicked item with rarity ", this.raw.rarity)
                 ws.send(getPlayerClickedGroundItemEvent(this.raw.uuid, this.gridCellPos))
@@ -126,7 +132,7 @@ export class Item {
         this.tooltipContainer.visible = false
         let yPos = 2
         const background = new Graphics();
-        if (this.raw.itemType === "weaponItem") {
+        if (this.raw.itemType === "weaponItem" || this.raw.itemType === "armourItem") {
             this.tooltipContainer.addChild(background)
 
 
@@ -137,16 +143,31 @@ export class Item {
             yPos += step
             this.tooltipContainer.addChild(rarityText)
 
-            const dmgText = new Text(String(`Dmg: ${this.raw.minDamage} to ${this.raw.maxDamage}`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
-            dmgText.position.set(2, yPos)
+            const qualityText = new Text(String(`Quality: ${this.raw.quality}%`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
+            qualityText.position.set(2, yPos)
             yPos += step
-            this.tooltipContainer.addChild(dmgText)
+            this.tooltipContainer.addChild(qualityText)
 
+            if (this.raw.itemType === "weaponItem") {
+                const dmgText = new Text(String(`Dmg: ${this.raw.minDamage} to ${this.raw.maxDamage}`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
+                dmgText.position.set(2, yPos)
+                yPos += step
+                this.tooltipContainer.addChild(dmgText)
+            }
 
-            const atkSpeedText = new Text(String(`AtkSpeed: ${this.raw.attackSpeed}`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
-            atkSpeedText.position.set(2, yPos)
-            yPos += step
-            this.tooltipContainer.addChild(atkSpeedText)
+            if (this.raw.absorb > 0) {
+                const absorbText = new Text(String(`Absorb: ${this.raw.absorb}`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
+                absorbText.position.set(2, yPos)
+                yPos += step
+                this.tooltipContainer.addChild(absorbText)
+            }
+
+            if (this.raw.attackSpeed !== 0) {
+                const atkSpeedText = new Text(String(`AtkSpeed: ${this.raw.attackSpeed > 0 ? '+' : ''}${this.raw.attackSpeed}%`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
+                atkSpeedText.position.set(2, yPos)
+                yPos += step
+                this.tooltipContainer.addChild(atkSpeedText)
+            }
 
             yPos += step
 
@@ -156,6 +177,29 @@ export class Item {
                 yPos += step
                 this.tooltipContainer.addChild(boniText)
             })
+
+            // items from before requirements existed have none
+            const requirements = this.raw.requirements || []
+            requirements.forEach(r => {
+                const requirementText = new Text(String(`requires ${r.attribute} ${r.value}`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffd27f, align: 'center' })
+                requirementText.position.set(2, yPos)
+                yPos += step
+                this.tooltipContainer.addChild(requirementText)
+            })
+        } else if (this.raw.itemType === "consumableItem") {
+            this.tooltipContainer.addChild(background)
+
+            const step = 15
+
+            const healText = new Text(String(`Heals ${this.raw.heal}`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
+            healText.position.set(2, yPos)
+            yPos += step
+            this.tooltipContainer.addChild(healText)
+
+            const quantityText = new Text(String(`${this.raw.quantity} left`), { fontFamily: 'Arial Black', fontSize: 12, fill: 0xffffff, align: 'center' })
+            quantityText.position.set(2, yPos)
+            yPos += step
+            this.tooltipContainer.addChild(quantityText)
         }
 
         background.beginFill(0xaaaaaa);
@@ -58,13 +58,29 @@ export function getTexturesFromSpriteSheet(name: string, path: string, numFrames
     return textures
 }
 
-const deadKnightAnim = getTexturesFromSpriteSheet("knight_dead", 'assets/npcs/knight/dead/sprite_sheet.png', 15, 96, 64)
-const walkingKnightAnim = getTexturesFromSpriteSheet("knight_walk", 'assets/npcs/knight/walking/sprite_sheet.png', 8, 96, 64)
-const idleKnightAnim = getTexturesFromSpriteSheet("knight_idle", 'assets/npcs/knight/idle/sprite_sheet.png', 15, 64, 64)
-const attackKnightAnim = getTexturesFromSpriteSheet("knight_attack", 'assets/npcs/knight/attack/sprite_sheet.png', 17, 144, 64)
+interface NpcAnimations {
+    dead: Texture[]
+    walking: Texture[]
+    idle: Texture[]
+    attacking: Texture[]
+}
+
+// animations per sprite of the npc archetypes, unknown sprites are drawn as knights
+const npcAnimations: { [sprite: string]: NpcAnimations } = {
+    knight: {
+        dead: getTexturesFromSpriteSheet("knight_dead", 'assets/npcs/knight/dead/sprite_sheet.png', 15, 96, 64),
+        walking: getTexturesFromSpriteSheet("knight_walk", 'assets/npcs/knight/walking/sprite_sheet.png', 8, 96, 64),
+        idle: getTexturesFromSpriteSheet("knight_idle", 'assets/npcs/knight/idle/sprite_sheet.png', 15, 64, 64),
+        attacking: getTexturesFromSpriteSheet("knight_attack", 'assets/npcs/knight/attack/sprite_sheet.png', 17, 144, 64),
+    },
+}
+
+function getAnimations(sprite: string): NpcAnimations {
+    return npcAnimations[sprite] ?? npcAnimations.knight
+}
 
 export function spawnDeadAnim(container: Container, npc: Npc) {
-    const anim = new AnimatedSprite(deadKnightAnim);
+    const anim = new AnimatedSprite(npc.animations.dead);
     anim.play()
     anim.animationSpeed = 0.25
     anim.loop = false
@@ -87,6 +103,7 @@ class Npc extends HasHitpoints implements IsClickable {
     targetPos: Vector
     hitpoints: Hitpoints
     npcType: string
+    animations: NpcAnimations
     movesRight: boolean
     attackSpeed: number
 
@@ -109,6 +126,8 @@ class Npc extends HasHitpoints implements IsClickable {
         this.UUID = serial.UUID
         this.hitpoints = serial.hitpoints
         this.attackSpeed = serial.attackSpeed
+        this.npcType = serial.npcType
+        this.animations = getAnimations(serial.sprite)
 
         this.pos = createVector(serial.pos.x, serial.pos.y)
         this.targetPos = this.pos.copy()
@@ -165,10 +184,10 @@ class Npc extends HasHitpoints implements IsClickable {
         if (this.activeAnimation === AnimationNames.Walking) return
 
         if (this.sprite === undefined) {
-            this.sprite = new AnimatedSprite(walkingKnightAnim)
+            this.sprite = new AnimatedSprite(this.animations.walking)
         }
 
-        this.sprite.textures = walkingKnightAnim
+        this.sprite.textures = this.animations.walking
         this.sprite.animationSpeed = 0.3;
         this.sprite.anchor.set(0.5)
         this.sprite.scale.set(2, 2)
@@ -180,10 +199,10 @@ class Npc extends HasHitpoints implements IsClickable {
         if (!force && (this.activeAnimation === AnimationNames.Idle)) return
 
         if (this.sprite === undefined) {
-            this.sprite = new AnimatedSprite(idleKnightAnim)
+            this.sprite = new AnimatedSprite(this.animations.idle)
         }
 
-        this.sprite.textures = idleKnightAnim
+        this.sprite.textures = this.animations.idle
         this.sprite.animationSpeed = 0.4;
         this.sprite.anchor.set(0.5)
         this.sprite.scale.set(2, 2)
@@ -196,10 +215,10 @@ class Npc extends HasHitpoints implements IsClickable {
         if (this.activeAnimation === AnimationNames.Attacking) return
 
         if (this.sprite === undefined) {
-            this.sprite = new AnimatedSprite(attackKnightAnim)
+            this.sprite = new AnimatedSprite(this.animations.attacking)
         }
 
-        this.sprite.textures = attackKnightAnim
+        this.sprite.textures = this.animations.attacking
         const calcedAnimSpeed = 2 - ((this.attackSpeed * 50) / 750);
         this.sprite.animationSpeed = calcedAnimSpeed <= 0 ? 0.1 : calcedAnimSpeed
         this.sprite.anchor.set(0.5)
@@ -1,5 +1,5 @@
 import Vector from "./vector"
-import { createVector, Hitpoints } from "../events/events"
+import { createVector, Hitpoints, Stats } from "../events/events"
 import { AnimatedSprite, Container } from "pixi.js"
 import { PLAYER_SPRITE_SCALE } from "../sprites/player"
 import { HasHitpoints } from "./resource"
@@ -8,6 +8,9 @@ import { getTexturesFromSpriteSheet } from "./npc"
 const deadAnimationTextures = getTexturesFromSpriteSheet("player_dead", 'assets/human/dead/sprite_sheet.png', 5, 48, 64)
 
 
+// frames between two actions at 100% attack speed, 500ms at 60 fps like BaseActionInterval of the server
+const BASE_ACTION_FRAMES = 30
+
 const slash_animation = getTexturesFromSpriteSheet("slash_animation", 'assets/slash_animation.png', 5, 32, 32)
 
 
@@ -28,6 +31,9 @@ export class Player extends HasHitpoints {
     slashAnimation: AnimatedSprite
     attackSpeed: number
     actionCooldown: number
+    actionSpeed: number // percent, from the stats
+    dead: boolean
+    deadAnimation?: AnimatedSprite
 
     constructor(id: number, pos: Vector, sprite: AnimatedSprite, hitpoints: Hitpoints, isOtherPlayer = true, mouseDown = false) {
         super(hitpoints, -35)
@@ -70,6 +76,8 @@ export class Player extends HasHitpoints {
         this.spriteContainer.addChild(this.slashAnimation)
 
         this.actionCooldown = 0
+        this.actionSpeed = 100
+        this.dead = false
     }
 
     canDoAction(): boolean {
@@ -79,6 +87,15 @@ export class Player extends HasHitpoints {
         return false
     }
 
+    updateStats(stats: Stats) {
+        this.actionSpeed = stats.attackSpeed
+    }
+
+    // the next action is possible once deltaCount passes the cooldown
+    startActionCooldown() {
+        this.actionCooldown = this.deltaCount + BASE_ACTION_FRAMES * 100 / this.actionSpeed
+    }
+
     updateCooldown(delta: number) {
         this.deltaCount += delta
     }
@@ -112,14 +129,40 @@ export class Player extends HasHitpoints {
         this.hitPoints = hitpoints
 
         if (this.hitPoints.current <= 0) {
-            this.sprite.scale.set(0, 0)
-            const anim = new AnimatedSprite(deadAnimationTextures);
-            this.spriteContainer.addChild(anim)
-            anim.scale.set(2, 2)
-            anim.anchor.set(0.5, 0.5)
-            anim.loop = false
-            anim.play()
-            anim.animationSpeed = 0.05
+            this.die()
+        }
+    }
+
+    die() {
+        if (this.dead) return
+        this.dead = true
+
+        this.sprite.scale.set(0, 0)
+        const anim = new AnimatedSprite(deadAnimationTextures);
+        this.spriteContainer.addChild(anim)
+        anim.scale.set(2, 2)
+        anim.anchor.set(0.5, 0.5)
+        anim.loop = false
+        anim.play()
+        anim.animationSpeed = 0.05
+        this.deadAnimation = anim
+    }
+
+    // the server moved the player to its respawn point, no interpolation from the corpse
+    respawn(pos: Vector, hitpoints: Hitpoints) {
+        this.dead = false
+        if (this.deadAnimation) {
+            this.spriteContainer.removeChild(this.deadAnimation)
+            this.deadAnimation = undefined
+        }
+        this.sprite.scale.set(PLAYER_SPRITE_SCALE, PLAYER_SPRITE_SCALE)
+
+        this.hitPoints = hitpoints
+        this.currentPos = pos.copy()
+        this.targetPos = pos.copy()
+        this.posChanged = true
+        if (this.isOtherPlayer) {
+            this.spriteContainer.position.set(pos.x, pos.y)
         }
     }
 
@@ -0,0 +1,25 @@
+package ai
+
+import "ws-game/shared"
+
+// Agent is what a behavior tree controls. The game implements it for npcs, tests can use a fake
+// without any cells or connected players.
+type Agent interface {
+	Pos() shared.Vector
+	SpawnPos() shared.Vector
+	Hitpoints() shared.Hitpoints
+	Rand() shared.Rand
+
+	// the target is the player the agent fights
+	HasTarget() bool
+	TargetPos() shared.Vector
+	FindTarget(radius int) bool // notices enemies within radius and picks (or keeps) the target, false if there is none
+	DropTarget()
+	ClearThreat() // forgets every enemy
+
+	MoveTowards(pos shared.Vector) bool // one step towards pos, false if pos can't be reached
+	ResetToSpawn()                      // puts the agent back to its spawn, for agents that can't walk back
+	Heal()
+	Attack(ranged bool)
+	CallForHelp(radius int) int // allies within radius join the fight against the target, returns how many
+}
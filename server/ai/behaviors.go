@@ -0,0 +1,286 @@
+package ai
+
+import "ws-game/shared"
+
+type Idle string
+
+const (
+	Stand  Idle = "stand"  // wait at the spawn
+	Wander Idle = "wander" // walk to random points around the spawn
+	Patrol Idle = "patrol" // walk a loop around the spawn
+)
+
+const (
+	// attacks from further away than this are ranged
+	MeleeRange = 75
+	// agents closer than this to their spawn are at home
+	HomeRadius = 50
+	// an agent calls for help at most once in this many ticks
+	helpCooldownTicks = 100
+	// wandering agents wait between this and twice as many ticks before they walk again
+	wanderPauseTicks = 40
+)
+
+// Params describe how an agent behaves, npcs take them from their archetype
+type Params struct {
+	AggroRadius int // players closer than this get attacked
+	LeashRange  int // chasing a target further away from the spawn makes the agent return
+	AttackRange int // MeleeRange or more
+	AttackSpeed int // ticks between attacks
+	StepTicks   int // ticks to wait between two steps while chasing, fleeing or roaming
+
+	FleeBelow  float64 // part of the max hitpoints below which the agent runs away from its target, 0 never flees
+	HelpRadius int     // allies within this radius join the fight, 0 doesn't call for help
+
+	Idle       Idle
+	RoamRadius int // how far from the spawn wandering and patrolling agents walk
+}
+
+// NewTree builds the behavior tree of one agent. The branches are tried in order every tick:
+// return to the spawn after being pulled too far, flee at low hitpoints, fight and at last idle.
+func NewTree(p Params) Node {
+	engage := Sequence{&acquireTarget{radius: p.AggroRadius}}
+	if p.HelpRadius > 0 {
+		engage = append(engage, Succeed{Child: &Cooldown{Ticks: helpCooldownTicks, Child: &callForHelp{radius: p.HelpRadius}}})
+	}
+	engage = append(engage, Selector{
+		&attack{attackRange: p.AttackRange, speed: p.AttackSpeed},
+		&chase{pace: pace{ticks: p.StepTicks}},
+	})
+
+	root := Selector{&returnHome{leashRange: p.LeashRange}}
+	if p.FleeBelow > 0 {
+		root = append(root, &flee{below: p.FleeBelow, safeDistance: 2 * p.AggroRadius, pace: pace{ticks: p.StepTicks}})
+	}
+	root = append(root, engage)
+
+	switch p.Idle {
+	case Wander:
+		root = append(root, &wander{radius: p.RoamRadius, pace: pace{ticks: p.StepTicks}})
+	case Patrol:
+		root = append(root, &patrol{radius: p.RoamRadius, pace: pace{ticks: p.StepTicks}})
+	default:
+		root = append(root, stand{})
+	}
+	return root
+}
+
+// pace lets an agent step only every few ticks
+type pace struct {
+	ticks int
+	wait  int
+}
+
+func (p *pace) ready() bool {
+	if p.wait > 0 {
+		p.wait--
+		return false
+	}
+	p.wait = p.ticks
+	return true
+}
+
+// walkHome steps towards the spawn and succeeds once the agent is back
+func walkHome(agent Agent) Status {
+	pos := agent.Pos()
+	spawn := agent.SpawnPos()
+	if pos.Dist(&spawn) <= HomeRadius {
+		return Success
+	}
+
+	if !agent.MoveTowards(spawn) {
+		// there is no way back
+		agent.ResetToSpawn()
+		return Success
+	}
+	return Running
+}
+
+// returnHome takes over once the target pulled the agent beyond its leash range and walks back to the
+// spawn, the agent ignores everything on the way and is healed
+type returnHome struct {
+	leashRange int
+	returning  bool
+}
+
+func (r *returnHome) Tick(agent Agent) Status {
+	if !r.returning {
+		pos := agent.Pos()
+		spawn := agent.SpawnPos()
+		if !agent.HasTarget() || pos.Dist(&spawn) <= float64(r.leashRange) {
+			return Failure
+		}
+
+		r.returning = true
+		agent.ClearThreat()
+		agent.Heal()
+	}
+
+	if walkHome(agent) == Success {
+		r.returning = false
+	}
+	return Running
+}
+
+// flee runs away from the target at low hitpoints, a cornered agent fights on
+type flee struct {
+	below        float64
+	safeDistance int
+	pace         pace
+}
+
+func (f *flee) Tick(agent Agent) Status {
+	hitpoints := agent.Hitpoints()
+	if !agent.HasTarget() || float64(hitpoints.Current) >= f.below*float64(hitpoints.Max) {
+		return Failure
+	}
+
+	pos := agent.Pos()
+	target := agent.TargetPos()
+	if pos.Dist(&target) > float64(f.safeDistance) {
+		agent.DropTarget()
+		return Success
+	}
+
+	if !f.pace.ready() {
+		return Running
+	}
+
+	away := shared.Vector{X: 2*pos.X - target.X, Y: 2*pos.Y - target.Y}
+	if away == pos || !agent.MoveTowards(away) {
+		return Failure
+	}
+	return Running
+}
+
+// acquireTarget picks the target every tick, so the agent can switch to a more dangerous enemy
+type acquireTarget struct {
+	radius int
+}
+
+func (a *acquireTarget) Tick(agent Agent) Status {
+	if agent.FindTarget(a.radius) {
+		return Success
+	}
+	return Failure
+}
+
+type callForHelp struct {
+	radius int
+}
+
+func (c *callForHelp) Tick(agent Agent) Status {
+	agent.CallForHelp(c.radius)
+	return Success
+}
+
+// attack hits the target every few ticks while it is in range
+type attack struct {
+	attackRange int
+	speed       int
+	cooldown    int
+}
+
+func (a *attack) Tick(agent Agent) Status {
+	if a.cooldown > 0 {
+		a.cooldown--
+	}
+
+	pos := agent.Pos()
+	target := agent.TargetPos()
+	if pos.Dist(&target) > float64(a.attackRange) {
+		return Failure
+	}
+
+	if a.cooldown == 0 {
+		agent.Attack(a.attackRange > MeleeRange)
+		a.cooldown = a.speed
+	}
+	return Running
+}
+
+// chase walks towards the target, a target that can't be reached is dropped
+type chase struct {
+	pace pace
+}
+
+func (c *chase) Tick(agent Agent) Status {
+	if !c.pace.ready() {
+		return Running
+	}
+
+	if !agent.MoveTowards(agent.TargetPos()) {
+		agent.DropTarget()
+		return Failure
+	}
+	return Running
+}
+
+// stand walks back to the spawn and waits there
+type stand struct{}
+
+func (stand) Tick(agent Agent) Status {
+	return walkHome(agent)
+}
+
+// wander walks to random points around the spawn with a pause in between
+type wander struct {
+	radius      int
+	pace        pace
+	pause       int
+	destination *shared.Vector
+}
+
+func (w *wander) Tick(agent Agent) Status {
+	if w.destination == nil {
+		if w.pause > 0 {
+			w.pause--
+			return Success
+		}
+
+		spawn := agent.SpawnPos()
+		rng := agent.Rand()
+		w.destination = &shared.Vector{
+			X: spawn.X + rng.Intn(2*w.radius+1) - w.radius,
+			Y: spawn.Y + rng.Intn(2*w.radius+1) - w.radius,
+		}
+	}
+
+	if !w.pace.ready() {
+		return Running
+	}
+
+	if agent.Pos() == *w.destination || !agent.MoveTowards(*w.destination) {
+		w.destination = nil
+		w.pause = wanderPauseTicks + agent.Rand().Intn(wanderPauseTicks+1)
+		return Success
+	}
+	return Running
+}
+
+// patrol walks from corner to corner of a diamond around the spawn
+type patrol struct {
+	radius int
+	pace   pace
+	next   int
+}
+
+func (p *patrol) Tick(agent Agent) Status {
+	spawn := agent.SpawnPos()
+	corners := []shared.Vector{
+		{X: spawn.X + p.radius, Y: spawn.Y},
+		{X: spawn.X, Y: spawn.Y + p.radius},
+		{X: spawn.X - p.radius, Y: spawn.Y},
+		{X: spawn.X, Y: spawn.Y - p.radius},
+	}
+
+	if !p.pace.ready() {
+		return Running
+	}
+
+	// corners that are reached or can't be reached are skipped
+	if agent.Pos() == corners[p.next] || !agent.MoveTowards(corners[p.next]) {
+		p.next = (p.next + 1) % len(corners)
+	}
+	return Running
+}
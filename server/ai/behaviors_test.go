@@ -0,0 +1,214 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+	"ws-game/shared"
+)
+
+// fakeAgent walks 10 units per axis and step, enemies are just positions
+type fakeAgent struct {
+	pos       shared.Vector
+	spawn     shared.Vector
+	hitpoints shared.Hitpoints
+	enemies   []shared.Vector
+	target    *shared.Vector
+	blocked   bool // MoveTowards fails
+
+	attacks   []bool
+	helpCalls int
+	resets    int
+}
+
+func newFakeAgent(enemies ...shared.Vector) *fakeAgent {
+	return &fakeAgent{hitpoints: shared.Hitpoints{Current: 100, Max: 100}, enemies: enemies}
+}
+
+func (a *fakeAgent) Pos() shared.Vector          { return a.pos }
+func (a *fakeAgent) SpawnPos() shared.Vector     { return a.spawn }
+func (a *fakeAgent) Hitpoints() shared.Hitpoints { return a.hitpoints }
+func (a *fakeAgent) Rand() shared.Rand           { return rand.New(rand.NewSource(1)) }
+func (a *fakeAgent) HasTarget() bool             { return a.target != nil }
+func (a *fakeAgent) TargetPos() shared.Vector    { return *a.target }
+func (a *fakeAgent) DropTarget()                 { a.target = nil }
+func (a *fakeAgent) ClearThreat()                { a.target = nil }
+func (a *fakeAgent) ResetToSpawn()               { a.pos = a.spawn; a.resets++ }
+func (a *fakeAgent) Heal()                       { a.hitpoints.Current = a.hitpoints.Max }
+func (a *fakeAgent) Attack(ranged bool)          { a.attacks = append(a.attacks, ranged) }
+func (a *fakeAgent) CallForHelp(radius int) int  { a.helpCalls++; return 1 }
+func (a *fakeAgent) FindTarget(radius int) bool {
+	if a.target != nil {
+		return true
+	}
+	for i := range a.enemies {
+		if a.pos.Dist(&a.enemies[i]) < float64(radius) {
+			a.target = &a.enemies[i]
+			return true
+		}
+	}
+	return false
+}
+
+func (a *fakeAgent) MoveTowards(pos shared.Vector) bool {
+	if a.blocked {
+		return false
+	}
+	a.pos.X += clamp(pos.X-a.pos.X, 10)
+	a.pos.Y += clamp(pos.Y-a.pos.Y, 10)
+	return true
+}
+
+func clamp(v int, max int) int {
+	if v > max {
+		return max
+	}
+	if v < -max {
+		return -max
+	}
+	return v
+}
+
+func tick(tree Node, agent Agent, ticks int) {
+	for i := 0; i < ticks; i++ {
+		tree.Tick(agent)
+	}
+}
+
+var melee = Params{AggroRadius: 150, LeashRange: 500, AttackRange: MeleeRange, AttackSpeed: 5, StepTicks: 0}
+
+func TestChaseAndAttack(t *testing.T) {
+	agent := newFakeAgent(shared.Vector{X: 140, Y: 0})
+	tree := NewTree(melee)
+
+	tick(tree, agent, 8)
+	if agent.target == nil || agent.pos.X != 70 {
+		t.Fatalf("expected the agent to chase its target, at %+v", agent.pos)
+	}
+	if len(agent.attacks) != 1 || agent.attacks[0] {
+		t.Fatalf("expected one melee attack, got %v", agent.attacks)
+	}
+
+	// attack speed is 5 ticks
+	tick(tree, agent, 5)
+	if len(agent.attacks) != 2 {
+		t.Fatalf("expected a second attack after the cooldown, got %v", agent.attacks)
+	}
+}
+
+func TestRangedAttack(t *testing.T) {
+	params := melee
+	params.AttackRange = 200
+	agent := newFakeAgent(shared.Vector{X: 140, Y: 0})
+
+	NewTree(params).Tick(agent)
+	if agent.pos.X != 0 || len(agent.attacks) != 1 || !agent.attacks[0] {
+		t.Fatalf("expected a ranged attack without moving, got %v at %+v", agent.attacks, agent.pos)
+	}
+}
+
+func TestLeashReturnsHome(t *testing.T) {
+	agent := newFakeAgent()
+	agent.pos = shared.Vector{X: 600, Y: 0}
+	agent.target = &shared.Vector{X: 650, Y: 0}
+	agent.hitpoints.Current = 10
+	tree := NewTree(melee)
+
+	tree.Tick(agent)
+	if agent.target != nil || agent.hitpoints.Current != 100 {
+		t.Fatal("expected the agent to drop its target and heal")
+	}
+
+	// enemies on the way home are ignored
+	agent.enemies = []shared.Vector{{X: 500, Y: 0}}
+	tick(tree, agent, 60)
+	if agent.target != nil || agent.pos.X > HomeRadius {
+		t.Fatalf("expected the agent at home without target, at %+v", agent.pos)
+	}
+
+	agent.pos = shared.Vector{X: 600, Y: 0}
+	agent.target = &shared.Vector{X: 650, Y: 0}
+	agent.blocked = true
+	tick(tree, agent, 1)
+	if agent.resets != 1 || agent.pos != agent.spawn {
+		t.Fatal("expected a stuck agent to be reset to its spawn")
+	}
+}
+
+func TestFleeAtLowHitpoints(t *testing.T) {
+	params := melee
+	params.FleeBelow = 0.2
+	agent := newFakeAgent(shared.Vector{X: 50, Y: 0})
+	agent.target = &agent.enemies[0]
+	agent.hitpoints.Current = 10
+	tree := NewTree(params)
+
+	tick(tree, agent, 3)
+	if agent.pos.X >= 0 || len(agent.attacks) != 0 {
+		t.Fatalf("expected the agent to run away, at %+v with %d attacks", agent.pos, len(agent.attacks))
+	}
+
+	// cornered agents fight
+	agent.blocked = true
+	agent.pos = shared.Vector{X: 0, Y: 0}
+	tree.Tick(agent)
+	if len(agent.attacks) != 1 {
+		t.Fatal("expected a cornered agent to attack")
+	}
+}
+
+func TestCallForHelp(t *testing.T) {
+	params := melee
+	params.HelpRadius = 300
+	agent := newFakeAgent(shared.Vector{X: 50, Y: 0})
+	tree := NewTree(params)
+
+	tick(tree, agent, helpCooldownTicks+1)
+	if agent.helpCalls != 1 {
+		t.Fatalf("expected one call for help within the cooldown, got %d", agent.helpCalls)
+	}
+	tick(tree, agent, 1)
+	if agent.helpCalls != 2 {
+		t.Fatalf("expected another call after the cooldown, got %d", agent.helpCalls)
+	}
+}
+
+func TestIdleBehaviors(t *testing.T) {
+	params := melee
+	params.Idle = Wander
+	params.RoamRadius = 100
+	agent := newFakeAgent()
+	tree := NewTree(params)
+
+	moved := false
+	for i := 0; i < 500; i++ {
+		tree.Tick(agent)
+		moved = moved || agent.pos != agent.spawn
+		if abs(agent.pos.X) > 100 || abs(agent.pos.Y) > 100 {
+			t.Fatalf("wandered too far to %+v", agent.pos)
+		}
+	}
+	if !moved {
+		t.Error("agent didn't wander")
+	}
+
+	params.Idle = Patrol
+	agent = newFakeAgent()
+	tree = NewTree(params)
+	visited := map[shared.Vector]bool{}
+	for i := 0; i < 200; i++ {
+		tree.Tick(agent)
+		visited[agent.pos] = true
+	}
+	for _, corner := range []shared.Vector{{X: 100}, {Y: 100}, {X: -100}, {Y: -100}} {
+		if !visited[corner] {
+			t.Errorf("patrol didn't reach %+v", corner)
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
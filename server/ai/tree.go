@@ -0,0 +1,71 @@
+package ai
+
+// Npcs are controlled by behavior trees. Every tick the root node gets ticked with the agent it controls
+// and passes the tick down to its children. Nodes keep their own state (cooldowns, the next patrol
+// point, ...), so every agent needs its own tree, see NewTree.
+
+type Status int
+
+const (
+	Success Status = 0
+	Failure Status = 1
+	Running Status = 2 // the node isn't done and wants to be ticked again
+)
+
+type Node interface {
+	Tick(agent Agent) Status
+}
+
+// Sequence ticks its children in order until one of them doesn't succeed
+type Sequence []Node
+
+func (s Sequence) Tick(agent Agent) Status {
+	for _, child := range s {
+		if status := child.Tick(agent); status != Success {
+			return status
+		}
+	}
+	return Success
+}
+
+// Selector ticks its children in order until one of them doesn't fail
+type Selector []Node
+
+func (s Selector) Tick(agent Agent) Status {
+	for _, child := range s {
+		if status := child.Tick(agent); status != Failure {
+			return status
+		}
+	}
+	return Failure
+}
+
+// Succeed runs its child and succeeds no matter how the child did, used for optional steps of a sequence
+type Succeed struct {
+	Child Node
+}
+
+func (s Succeed) Tick(agent Agent) Status {
+	s.Child.Tick(agent)
+	return Success
+}
+
+// Cooldown fails for Ticks ticks after its child succeeded
+type Cooldown struct {
+	Ticks     int
+	Child     Node
+	remaining int
+}
+
+func (c *Cooldown) Tick(agent Agent) Status {
+	if c.remaining > 0 {
+		c.remaining--
+		return Failure
+	}
+
+	status := c.Child.Tick(agent)
+	if status == Success {
+		c.remaining = c.Ticks
+	}
+	return status
+}
@@ -0,0 +1,243 @@
+package bestiary
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"ws-game/ai"
+	"ws-game/loot"
+	"ws-game/shared"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The bestiary describes the kinds of npcs (archetypes) and where they live. Spawn tables are
+// matched against the biome and zone level of a sub cell, the first matching table decides how
+// many npcs live there and picks their archetypes by weight.
+
+//go:embed npcs.yaml
+var defaultBestiary []byte
+
+// Archetype contains the stats of a kind of npc, zero values fall back to the npc config of the server
+type Archetype struct {
+	Hitpoints   int           `yaml:"hitpoints"`
+	MinDamage   int           `yaml:"minDamage"`
+	MaxDamage   int           `yaml:"maxDamage"`
+	CritChance  float64       `yaml:"critChance"`
+	AttackSpeed int           `yaml:"attackSpeed"` // cell ticks between attacks
+	StepSize    int           `yaml:"stepSize"`    // max distance per axis an npc walks per step
+	AggroRadius int           `yaml:"aggroRadius"` // players closer than this get attacked
+	LeashRange  int           `yaml:"leashRange"`  // chasing a player further away from the spawn makes the npc return
+	Respawn     time.Duration `yaml:"respawn"`     // cooldown before a killed npc comes back
+	Loot        string        `yaml:"loot"`        // loot table rolled on death
+	Sprite      string        `yaml:"sprite"`      // what the clients render
+
+	// behavior, see ai.Params
+	AttackRange int     `yaml:"attackRange"`
+	FleeBelow   float64 `yaml:"fleeBelow"`
+	HelpRadius  int     `yaml:"helpRadius"`
+	Idle        ai.Idle `yaml:"idle"`
+	RoamRadius  int     `yaml:"roamRadius"`
+}
+
+type SpawnEntry struct {
+	Npc    string `yaml:"npc"`
+	Weight int    `yaml:"weight"` // default 1
+}
+
+// SpawnTable applies to sub cells of one of its biomes with a zone level in [MinLevel, MaxLevel]
+type SpawnTable struct {
+	Biomes   []string `yaml:"biomes"` // empty matches every biome
+	MinLevel int      `yaml:"minLevel"`
+	MaxLevel int      `yaml:"maxLevel"` // 0 means no upper limit
+
+	// npcs in a cell that consists only of matching sub cells
+	Density float64      `yaml:"density"`
+	Entries []SpawnEntry `yaml:"entries"`
+}
+
+type Bestiary struct {
+	Archetypes map[string]Archetype `yaml:"archetypes"`
+	Spawns     []SpawnTable         `yaml:"spawns"`
+}
+
+func Default() Bestiary {
+	b, err := Parse(defaultBestiary)
+	if err != nil {
+		panic(fmt.Sprintf("built-in bestiary is invalid: %s", err))
+	}
+	return b
+}
+
+// Load reads the bestiary from a yaml file, an empty path returns the built-in one
+func Load(path string) (Bestiary, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Bestiary{}, err
+	}
+
+	b, err := Parse(data)
+	if err != nil {
+		return Bestiary{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+func Parse(data []byte) (Bestiary, error) {
+	b := Bestiary{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&b); err != nil && !errors.Is(err, io.EOF) {
+		return Bestiary{}, err
+	}
+
+	for s := range b.Spawns {
+		for e := range b.Spawns[s].Entries {
+			entry := &b.Spawns[s].Entries[e]
+			if entry.Weight == 0 {
+				entry.Weight = 1
+			}
+		}
+	}
+
+	if err := b.Validate(); err != nil {
+		return Bestiary{}, err
+	}
+	return b, nil
+}
+
+func (b Bestiary) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	for name, a := range b.Archetypes {
+		check(a.Hitpoints >= 0, "%s.hitpoints must not be negative", name)
+		check(a.MinDamage >= 0 && (a.MaxDamage == 0 || a.MinDamage <= a.MaxDamage), "%s.minDamage must be between 0 and maxDamage", name)
+		check(a.CritChance >= 0 && a.CritChance <= 1, "%s.critChance must be between 0 and 1", name)
+		check(a.AttackSpeed >= 0, "%s.attackSpeed must not be negative", name)
+		check(a.StepSize >= 0, "%s.stepSize must not be negative", name)
+		check(a.AggroRadius >= 0, "%s.aggroRadius must not be negative", name)
+		check(a.LeashRange >= 0, "%s.leashRange must not be negative", name)
+		check(a.Respawn >= 0, "%s.respawn must not be negative", name)
+		check(a.AttackRange == 0 || a.AttackRange >= ai.MeleeRange, "%s.attackRange must be at least %d", name, ai.MeleeRange)
+		check(a.FleeBelow >= 0 && a.FleeBelow < 1, "%s.fleeBelow must be between 0 and 1", name)
+		check(a.HelpRadius >= 0, "%s.helpRadius must not be negative", name)
+		check(a.Idle == "" || a.Idle == ai.Stand || a.Idle == ai.Wander || a.Idle == ai.Patrol, "%s.idle must be stand, wander or patrol", name)
+		check(a.RoamRadius >= 0, "%s.roamRadius must not be negative", name)
+	}
+
+	for s, table := range b.Spawns {
+		where := fmt.Sprintf("spawns[%d]", s)
+		check(table.MinLevel >= 0 && (table.MaxLevel == 0 || table.MinLevel <= table.MaxLevel), "%s.minLevel must be between 0 and maxLevel", where)
+		check(table.Density >= 0, "%s.density must not be negative", where)
+
+		for e, entry := range table.Entries {
+			where := fmt.Sprintf("%s.entries[%d]", where, e)
+			check(entry.Weight > 0, "%s.weight must be positive", where)
+			_, ok := b.Archetypes[entry.Npc]
+			check(ok, "%s.npc %s does not exist", where, entry.Npc)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid bestiary: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// CheckLoot makes sure every loot table an archetype refers to exists
+func (b Bestiary) CheckLoot(tables loot.Tables) error {
+	missing := []string{}
+	for name, a := range b.Archetypes {
+		if _, ok := tables[a.Loot]; a.Loot != "" && !ok {
+			missing = append(missing, fmt.Sprintf("%s.loot %s", name, a.Loot))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("unknown loot tables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// CheckDamage makes sure the damage range of every archetype is valid once the zero values
+// fell back to the damage of the npc config
+func (b Bestiary) CheckDamage(minDamage int, maxDamage int) error {
+	invalid := []string{}
+	for name, a := range b.Archetypes {
+		min, max := minDamage, maxDamage
+		if a.MinDamage > 0 {
+			min = a.MinDamage
+		}
+		if a.MaxDamage > 0 {
+			max = a.MaxDamage
+		}
+		if min > max {
+			invalid = append(invalid, fmt.Sprintf("%s hits for %d to %d", name, min, max))
+		}
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("minDamage above maxDamage: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// SpawnTable returns the first table matching biome and level
+func (b Bestiary) SpawnTable(biome string, level int) (SpawnTable, bool) {
+	for _, table := range b.Spawns {
+		if len(table.Biomes) > 0 && !contains(table.Biomes, biome) {
+			continue
+		}
+		if level < table.MinLevel || (table.MaxLevel != 0 && level > table.MaxLevel) {
+			continue
+		}
+		return table, true
+	}
+	return SpawnTable{}, false
+}
+
+// Pick rolls the archetype of a new npc by weight
+func (table SpawnTable) Pick(rng shared.Rand) (string, bool) {
+	total := 0
+	for _, entry := range table.Entries {
+		total += entry.Weight
+	}
+	if total == 0 {
+		return "", false
+	}
+
+	roll := rng.Intn(total)
+	for _, entry := range table.Entries {
+		if roll < entry.Weight {
+			return entry.Npc, true
+		}
+		roll -= entry.Weight
+	}
+	return "", false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package bestiary
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"ws-game/loot"
+)
+
+func TestDefaultBestiaryIsValid(t *testing.T) {
+	b := Default()
+	if err := b.CheckLoot(loot.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, biome := range []string{"Forest", "Desert", "Mountain", "Swamp", "Snow"} {
+		if _, ok := b.SpawnTable(biome, 1); !ok {
+			t.Errorf("no npcs in %s", biome)
+		}
+	}
+}
+
+func TestSpawnTableMatchesLevel(t *testing.T) {
+	b, err := Parse([]byte(`
+archetypes:
+  rat: {}
+  wolf: {}
+spawns:
+  - biomes: [Forest]
+    maxLevel: 2
+    entries: [{npc: rat}]
+  - minLevel: 3
+    entries: [{npc: wolf}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	if table, ok := b.SpawnTable("Forest", 2); !ok || table.Entries[0].Npc != "rat" {
+		t.Errorf("expected rats in low forests, got %+v", table)
+	}
+	if table, ok := b.SpawnTable("Desert", 5); !ok || table.Entries[0].Npc != "wolf" {
+		t.Errorf("expected wolves everywhere else, got %+v", table)
+	}
+	if _, ok := b.SpawnTable("Desert", 1); ok {
+		t.Error("expected no table for low deserts")
+	}
+
+	table, _ := b.SpawnTable("Forest", 1)
+	if npc, ok := table.Pick(rng); !ok || npc != "rat" {
+		t.Errorf("picked %s", npc)
+	}
+}
+
+func TestParseRejectsUnknownNpcs(t *testing.T) {
+	_, err := Parse([]byte(`
+archetypes:
+  rat: {}
+spawns:
+  - entries: [{npc: dragon}]
+`))
+	if err == nil {
+		t.Fatal("expected an error for an unknown archetype")
+	}
+
+	b, err := Parse([]byte(`
+archetypes:
+  rat: {loot: cheese}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.CheckLoot(loot.Default()) == nil {
+		t.Error("expected an error for an unknown loot table")
+	}
+}
+
+func TestCheckDamageUsesTheFallbacks(t *testing.T) {
+	b, err := Parse([]byte(`
+archetypes:
+  rat: {}
+  ogre: {minDamage: 50}
+  troll: {minDamage: 50, maxDamage: 80}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ogre falls back to a max of 35
+	err = b.CheckDamage(20, 35)
+	if err == nil || !strings.Contains(err.Error(), "ogre") || strings.Contains(err.Error(), "troll") {
+		t.Fatalf("expected only the ogre to be invalid, got %v", err)
+	}
+	if err := b.CheckDamage(20, 60); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,7 +3,8 @@ module ws-game
 go 1.18
 
 require (
-	github.com/aquilax/go-perlin v1.1.0 // indirect
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/gorilla/websocket v1.5.0
+	golang.org/x/crypto v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
 )
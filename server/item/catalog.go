@@ -0,0 +1,211 @@
+package item
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"ws-game/shared"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The catalog describes what random items can be. An item gets a base (sword, helmet, ...) and a
+// rarity, the rarity decides the quality of the base stats and how many affixes are rolled on top.
+// The zone level the item is found in scales the base stats and unlocks higher affix tiers.
+
+//go:embed items.yaml
+var defaultCatalog []byte
+
+// Range is inclusive, a single number in yaml means min = max
+type Range struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+func (r *Range) UnmarshalYAML(value *yaml.Node) error {
+	var n int
+	if value.Decode(&n) == nil {
+		r.Min, r.Max = n, n
+		return nil
+	}
+
+	type plain Range
+	return value.Decode((*plain)(r))
+}
+
+func (r Range) roll(rng shared.Rand) int {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + rng.Intn(r.Max-r.Min+1)
+}
+
+type Base struct {
+	Type        ItemType              `yaml:"type"`
+	Weight      int                   `yaml:"weight"` // default 1
+	Damage      Range                 `yaml:"damage"`
+	Absorb      int                   `yaml:"absorb"`
+	AttackSpeed int                   `yaml:"attackSpeed"`
+	Requires    map[BoniAttribute]int `yaml:"requires"`
+}
+
+type AffixStat string
+
+// besides the attributes of Boni
+const (
+	DamageStat      AffixStat = "damage"
+	AbsorbStat      AffixStat = "absorb"
+	AttackSpeedStat AffixStat = "attackSpeed"
+)
+
+type AffixTier struct {
+	MinLevel int   `yaml:"minLevel"`
+	Value    Range `yaml:"value"`
+}
+
+type Affix struct {
+	Stat  AffixStat   `yaml:"stat"`
+	Types []ItemType  `yaml:"types"` // empty fits every item
+	Tiers []AffixTier `yaml:"tiers"`
+}
+
+type RarityRule struct {
+	Weight  int   `yaml:"weight"`
+	Affixes Range `yaml:"affixes"`
+	Quality Range `yaml:"quality"` // percent of the base stats
+}
+
+type Catalog struct {
+	Bases               map[ItemSubType]Base  `yaml:"bases"`
+	Affixes             map[string]Affix      `yaml:"affixes"`
+	Rarities            map[Rarity]RarityRule `yaml:"rarities"`
+	PowerPerLevel       float64               `yaml:"powerPerLevel"`       // base stats grow by this part every zone level
+	RequirementPerLevel int                   `yaml:"requirementPerLevel"` // added to every requirement every zone level
+}
+
+func Default() Catalog {
+	c, err := Parse(defaultCatalog)
+	if err != nil {
+		panic(fmt.Sprintf("built-in item catalog is invalid: %s", err))
+	}
+	return c
+}
+
+// Load reads the catalog from a yaml file, an empty path returns the built-in one
+func Load(path string) (Catalog, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return Catalog{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func Parse(data []byte) (Catalog, error) {
+	c := Catalog{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Catalog{}, err
+	}
+
+	for subType, base := range c.Bases {
+		if base.Weight == 0 {
+			base.Weight = 1
+			c.Bases[subType] = base
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return Catalog{}, err
+	}
+	return c, nil
+}
+
+func (c Catalog) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(len(c.Bases) > 0, "bases must not be empty")
+	check(c.PowerPerLevel >= 0, "powerPerLevel must not be negative")
+	check(c.RequirementPerLevel >= 0, "requirementPerLevel must not be negative")
+
+	for subType, base := range c.Bases {
+		_, ok := slots[subType]
+		check(ok, "bases.%s can't be equipped", subType)
+		check(base.Type == Weapon || base.Type == Armour, "bases.%s.type must be %s or %s", subType, Weapon, Armour)
+		check(base.Weight > 0, "bases.%s.weight must be positive", subType)
+		check(base.Damage.Min >= 0 && base.Damage.Min <= base.Damage.Max, "bases.%s.damage must be a range >= 0", subType)
+		check(base.Absorb >= 0, "bases.%s.absorb must not be negative", subType)
+		for attribute, value := range base.Requires {
+			check(isAttribute(attribute), "bases.%s.requires.%s is no attribute", subType, attribute)
+			check(value >= 0, "bases.%s.requires.%s must not be negative", subType, attribute)
+		}
+	}
+
+	for name, affix := range c.Affixes {
+		check(isAttribute(BoniAttribute(affix.Stat)) || affix.Stat == DamageStat || affix.Stat == AbsorbStat || affix.Stat == AttackSpeedStat,
+			"affixes.%s.stat %s is unknown", name, affix.Stat)
+		check(len(affix.Tiers) > 0, "affixes.%s.tiers must not be empty", name)
+		for t, tier := range affix.Tiers {
+			check(tier.MinLevel >= 0, "affixes.%s.tiers[%d].minLevel must not be negative", name, t)
+			check(tier.Value.Min <= tier.Value.Max, "affixes.%s.tiers[%d].value must be a range", name, t)
+		}
+	}
+
+	for _, rarity := range []Rarity{NormalRarity, MagicRarity, UniqueRarity, UltraRarity} {
+		_, ok := c.Rarities[rarity]
+		check(ok, "rarities.%s is missing", rarity)
+	}
+	for rarity, rule := range c.Rarities {
+		check(rule.Weight >= 0, "rarities.%s.weight must not be negative", rarity)
+		check(rule.Affixes.Min >= 0 && rule.Affixes.Min <= rule.Affixes.Max, "rarities.%s.affixes must be a range >= 0", rarity)
+		check(rule.Quality.Min > 0 && rule.Quality.Min <= rule.Quality.Max, "rarities.%s.quality must be a range > 0", rarity)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid item catalog: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func isAttribute(attribute BoniAttribute) bool {
+	return attribute == Strength || attribute == Agility || attribute == Vitality
+}
+
+// sorted keys, rolls must not depend on the order of map iteration
+func (c Catalog) baseTypes() []ItemSubType {
+	subTypes := make([]ItemSubType, 0, len(c.Bases))
+	for subType := range c.Bases {
+		subTypes = append(subTypes, subType)
+	}
+	sort.Slice(subTypes, func(i, j int) bool { return subTypes[i] < subTypes[j] })
+	return subTypes
+}
+
+func (c Catalog) affixNames() []string {
+	names := make([]string, 0, len(c.Affixes))
+	for name := range c.Affixes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
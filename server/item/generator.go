@@ -0,0 +1,175 @@
+package item
+
+import (
+	"math"
+	"ws-game/shared"
+
+	"github.com/google/uuid"
+)
+
+// rarities in the order their weights are rolled
+var rarities = []Rarity{NormalRarity, MagicRarity, UniqueRarity, UltraRarity}
+
+// attributes in the order requirements are listed
+var attributes = []BoniAttribute{Strength, Agility, Vitality}
+
+func (c Catalog) NewItem(gridCellPos shared.Vector, zoneLevel int, pos shared.Vector) Item {
+	return c.NewItemFrom(shared.GlobalRand, gridCellPos, zoneLevel, pos)
+}
+
+// NewItemFrom rolls a random item of the catalog with r, a seeded r always creates the same item
+func (c Catalog) NewItemFrom(r shared.Rand, gridCellPos shared.Vector, zoneLevel int, pos shared.Vector) Item {
+	item, _ := c.NewItemOf(r, c.rollBase(r), gridCellPos, zoneLevel, pos)
+	return item
+}
+
+// NewItemOf rolls an item of subType, ok is false if the catalog has no base for it
+func (c Catalog) NewItemOf(r shared.Rand, subType ItemSubType, gridCellPos shared.Vector, zoneLevel int, pos shared.Vector) (Item, bool) {
+	base, ok := c.Bases[subType]
+	if !ok {
+		return Item{}, false
+	}
+	level := zoneLevel
+	if level < 1 {
+		level = 1
+	}
+
+	rarity := c.rollRarity(r)
+	rule := c.Rarities[rarity]
+	quality := rule.Quality.roll(r)
+	power := (1 + c.PowerPerLevel*float64(level-1)) * float64(quality) / 100
+
+	item := Item{
+		GridCellPos:  gridCellPos,
+		ItemType:     base.Type,
+		ItemSubType:  subType,
+		Pos:          pos,
+		UUID:         uuid.Must(uuid.NewRandomFromReader(r)).String(),
+		Quantity:     1,
+		Rarity:       rarity,
+		Quality:      quality,
+		MinDamage:    scale(base.Damage.Min, power),
+		MaxDamage:    scale(base.Damage.Max, power),
+		Absorb:       scale(base.Absorb, power),
+		AttackSpeed:  base.AttackSpeed,
+		Boni:         []Boni{},
+		Requirements: []Boni{},
+	}
+
+	for _, attribute := range attributes {
+		if value, ok := base.Requires[attribute]; ok && value > 0 {
+			item.Requirements = append(item.Requirements, Boni{
+				Attribute: attribute,
+				Value:     value + c.RequirementPerLevel*(level-1),
+			})
+		}
+	}
+
+	// every affix at most once, the count is capped by the affixes that fit
+	candidates := c.fittingAffixes(base.Type, level)
+	count := rule.Affixes.roll(r)
+	for i := 0; i < count && len(candidates) > 0; i++ {
+		picked := r.Intn(len(candidates))
+		affix := c.Affixes[candidates[picked]]
+		candidates = append(candidates[:picked], candidates[picked+1:]...)
+
+		item.addAffix(affix.Stat, affix.rollTier(r, level).Value.roll(r))
+	}
+
+	return item, true
+}
+
+func (i *Item) addAffix(stat AffixStat, value int) {
+	switch stat {
+	case DamageStat:
+		i.MinDamage += value
+		i.MaxDamage += value
+	case AbsorbStat:
+		i.Absorb += value
+	case AttackSpeedStat:
+		i.AttackSpeed += value
+	default:
+		i.Boni = append(i.Boni, Boni{Attribute: BoniAttribute(stat), Value: value})
+	}
+}
+
+func scale(value int, power float64) int {
+	return int(math.Round(float64(value) * power))
+}
+
+func (c Catalog) rollRarity(r shared.Rand) Rarity {
+	total := 0
+	for _, rarity := range rarities {
+		total += c.Rarities[rarity].Weight
+	}
+	if total <= 0 {
+		return NormalRarity
+	}
+
+	roll := r.Intn(total)
+	for _, rarity := range rarities {
+		roll -= c.Rarities[rarity].Weight
+		if roll < 0 {
+			return rarity
+		}
+	}
+	return NormalRarity
+}
+
+func (c Catalog) rollBase(r shared.Rand) ItemSubType {
+	subTypes := c.baseTypes()
+	total := 0
+	for _, subType := range subTypes {
+		total += c.Bases[subType].Weight
+	}
+
+	roll := r.Intn(total)
+	for _, subType := range subTypes {
+		roll -= c.Bases[subType].Weight
+		if roll < 0 {
+			return subType
+		}
+	}
+	return subTypes[len(subTypes)-1]
+}
+
+// fittingAffixes are the names of the affixes that can roll on itemType with a tier unlocked at level
+func (c Catalog) fittingAffixes(itemType ItemType, level int) []string {
+	names := []string{}
+	for _, name := range c.affixNames() {
+		affix := c.Affixes[name]
+		if !affix.fits(itemType) || len(affix.unlockedTiers(level)) == 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (a Affix) fits(itemType ItemType) bool {
+	if len(a.Types) == 0 {
+		return true
+	}
+	for _, t := range a.Types {
+		if t == itemType {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Affix) unlockedTiers(level int) []AffixTier {
+	tiers := []AffixTier{}
+	for _, tier := range a.Tiers {
+		if tier.MinLevel <= level {
+			tiers = append(tiers, tier)
+		}
+	}
+	return tiers
+}
+
+// rollTier picks one of the tiers level has unlocked, there has to be at least one
+func (a Affix) rollTier(r shared.Rand, level int) AffixTier {
+	tiers := a.unlockedTiers(level)
+	return tiers[r.Intn(len(tiers))]
+}
@@ -0,0 +1,111 @@
+package item
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"ws-game/shared"
+)
+
+func TestSeededItemsAreReproducible(t *testing.T) {
+	a := Default().NewItemFrom(rand.New(rand.NewSource(7)), shared.Vector{}, 3, shared.Vector{})
+	b := Default().NewItemFrom(rand.New(rand.NewSource(7)), shared.Vector{}, 3, shared.Vector{})
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("same seed created different items:\n%+v\n%+v", a, b)
+	}
+}
+
+func averageDamage(zoneLevel int) float64 {
+	catalog := Default()
+	rng := rand.New(rand.NewSource(1))
+	n := 2000
+	total := 0
+	for i := 0; i < n; i++ {
+		sword, _ := catalog.NewItemOf(rng, Sword, shared.Vector{}, zoneLevel, shared.Vector{})
+		total += sword.MinDamage + sword.MaxDamage
+	}
+	return float64(total) / float64(n)
+}
+
+func TestZoneLevelScalesItems(t *testing.T) {
+	if low, high := averageDamage(1), averageDamage(6); high < low*2 {
+		t.Fatalf("expected zone level 6 swords to hit at least twice as hard, got %f and %f", low, high)
+	}
+
+	catalog := Default()
+	sword, _ := catalog.NewItemOf(rand.New(rand.NewSource(1)), Sword, shared.Vector{}, 6, shared.Vector{})
+	for _, requirement := range sword.Requirements {
+		if requirement.Value != 3+5*catalog.RequirementPerLevel {
+			t.Fatalf("expected requirements to grow with the zone level, got %+v", sword.Requirements)
+		}
+	}
+}
+
+func TestRarityDecidesAffixes(t *testing.T) {
+	c, err := Parse([]byte(`
+bases:
+  ring:
+    type: armourItem
+affixes:
+  mighty:
+    stat: strength
+    tiers:
+      - minLevel: 1
+        value: 1
+      - minLevel: 5
+        value: 10
+  nimble:
+    stat: agility
+    tiers: [{minLevel: 1, value: 1}]
+  sharp:
+    stat: damage
+    types: [weaponItem]
+    tiers: [{minLevel: 1, value: 1}]
+rarities:
+  normal: {weight: 0, affixes: 0, quality: 80}
+  magic: {weight: 0, affixes: 1, quality: 90}
+  unique: {weight: 0, affixes: 2, quality: 95}
+  ultra: {weight: 1, affixes: 3, quality: 100}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := c.NewItem(shared.Vector{}, 1, shared.Vector{})
+	if ring.ItemSubType != Ring || ring.Rarity != UltraRarity || ring.Quality != 100 {
+		t.Fatalf("expected an ultra ring, got %+v", ring)
+	}
+	// sharp doesn't fit rings, so only two of the three affixes can roll
+	if len(ring.Boni) != 2 || ring.MinDamage != 0 {
+		t.Fatalf("expected the two attribute affixes, got %+v", ring)
+	}
+
+	ring = c.NewItem(shared.Vector{}, 5, shared.Vector{})
+	for _, boni := range ring.Boni {
+		if boni.Attribute == Strength && boni.Value != 1 && boni.Value != 10 {
+			t.Fatalf("expected a tier of mighty, got %+v", boni)
+		}
+	}
+}
+
+func TestInvalidCatalog(t *testing.T) {
+	_, err := Parse([]byte(`
+bases:
+  healthPotion:
+    type: consumableItem
+affixes:
+  lucky:
+    stat: luck
+    tiers: []
+rarities:
+  normal: {affixes: 0, quality: 80}
+`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, problem := range []string{"bases.healthPotion can't be equipped", "affixes.lucky.stat luck is unknown", "rarities.magic is missing"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Fatalf("expected %q in %q", problem, err)
+		}
+	}
+}
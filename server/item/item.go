@@ -1,7 +1,6 @@
 package item
 
 import (
-	"fmt"
 	"ws-game/shared"
 
 	"github.com/google/uuid"
@@ -31,6 +30,53 @@ const (
 	Hammer ItemSubType = "hammer"
 )
 
+// armour
+const (
+	Shield     ItemSubType = "shield"
+	Helmet     ItemSubType = "helmet"
+	Chestplate ItemSubType = "chestplate"
+	Greaves    ItemSubType = "greaves"
+	Ring       ItemSubType = "ring"
+)
+
+// consumables
+const (
+	HealthPotion ItemSubType = "healthPotion"
+	CookedMeat   ItemSubType = "cookedMeat"
+)
+
+// hitpoints restored by using a consumable
+var consumableHeal = map[ItemSubType]int{
+	HealthPotion: 400,
+	CookedMeat:   150,
+}
+
+// Slot is where an equipped item is worn, every slot holds one item
+type Slot string
+
+const (
+	MainHandSlot Slot = "mainHand"
+	OffHandSlot  Slot = "offHand"
+	HeadSlot     Slot = "head"
+	ChestSlot    Slot = "chest"
+	LegsSlot     Slot = "legs"
+	RingSlot     Slot = "ring"
+)
+
+// Slots in the order they are shown and checked
+var Slots = []Slot{MainHandSlot, OffHandSlot, HeadSlot, ChestSlot, LegsSlot, RingSlot}
+
+var slots = map[ItemSubType]Slot{
+	Axe:        MainHandSlot,
+	Sword:      MainHandSlot,
+	Hammer:     MainHandSlot,
+	Shield:     OffHandSlot,
+	Helmet:     HeadSlot,
+	Chestplate: ChestSlot,
+	Greaves:    LegsSlot,
+	Ring:       RingSlot,
+}
+
 type BoniAttribute string
 
 const (
@@ -48,9 +94,9 @@ type Boni struct {
 type Rarity string
 
 const (
-	NormalRarity Rarity = "normal" // 50%
-	MagicRarity  Rarity = "magic"  // 80%
-	UniqueRarity Rarity = "unique" // 9%
+	NormalRarity Rarity = "normal" // 50% with the built-in catalog
+	MagicRarity  Rarity = "magic"  // 45%
+	UniqueRarity Rarity = "unique" // 4%
 	UltraRarity  Rarity = "ultra"  // 1%
 )
 
@@ -59,64 +105,56 @@ type Item struct {
 	ItemType    ItemType      `json:"itemType"`
 	ItemSubType ItemSubType   `json:"itemSubType"`
 	Pos         shared.Vector `json:"pos"`
-	UUID        string        `json:"uuid"`
+	UUID        string        `json:"uuid" wire:"uuid"`
 	Quantity    int           `json:"quantity"` // for potions or throwables
 
-	Rarity      Rarity `json:"rarity"`    // influences min/max damage etc.
-	Quality     int    `json:"quality"`   // 80-100
+	Rarity      Rarity `json:"rarity"`    // decides the quality and the number of affixes
+	Quality     int    `json:"quality"`   // percent of the base stats, 80-100
 	MinDamage   int    `json:"minDamage"` // roll
 	MaxDamage   int    `json:"maxDamage"` // roll
 	Absorb      int    `json:"absorb"`    // all items can have this stat -> only show in ui if >= 0
 	AttackSpeed int    `json:"attackSpeed"`
+	Heal        int    `json:"heal"` // consumables only
 
 	Boni []Boni `json:"boni"`
 	// bonis the items provides +20 vita etc.
 	// calulcate players stats on equipped item changes
+
+	Requirements []Boni `json:"requirements"` // min attributes of the player to equip it
 }
 
-func rollRarity() Rarity {
-	rarityRoll := shared.RandIntInRange(0, 101)
-	if rarityRoll <= 50 {
-		return NormalRarity
-	} else if rarityRoll > 50 && rarityRoll <= 95 {
-		return MagicRarity
-	} else if rarityRoll > 95 && rarityRoll <= 99 {
-		return UniqueRarity
+// Slot returns where the item is equipped, ok is false for items that can't be equipped
+func (i Item) Slot() (Slot, bool) {
+	if i.ItemType == Consumable {
+		return "", false
 	}
-	fmt.Println("UNIQUE ITEM OHH BOY!")
-	return UltraRarity
+	slot, ok := slots[i.ItemSubType]
+	return slot, ok
 }
 
-func NewItem(gridCellPos shared.Vector, zoneLevel int, pos shared.Vector) Item {
-
-	item := Item{
-		GridCellPos: gridCellPos,
-		ItemType:    Weapon,
-		ItemSubType: Sword,
-		Pos:         pos,
-		UUID:        uuid.New().String(),
-		Quantity:    1,
-		Rarity:      rollRarity(),
-		Quality:     100,
-		MinDamage:   2000,
-		MaxDamage:   5000,
-		Absorb:      10,
-		AttackSpeed: 10,
-		Boni: []Boni{
-			{
-				Attribute: Vitality,
-				Value:     10,
-			},
-		},
-	}
+// TwoHanded weapons need the off hand to be free
+func (i Item) TwoHanded() bool {
+	return i.ItemSubType == Hammer
+}
 
-	for i := 0; i < shared.RandIntInRange(2, 5); i++ {
-		//Todo random initialze items; rarity in considerations
-		item.Boni = append(item.Boni, Boni{
-			Attribute: Vitality,
-			Value:     10,
-		})
+// NewConsumable creates a stack of quantity consumables, ok is false if subType isn't a consumable
+func NewConsumable(subType ItemSubType, gridCellPos shared.Vector, pos shared.Vector, quantity int) (Item, bool) {
+	heal, ok := consumableHeal[subType]
+	if !ok {
+		return Item{}, false
 	}
 
-	return item
+	return Item{
+		GridCellPos:  gridCellPos,
+		ItemType:     Consumable,
+		ItemSubType:  subType,
+		Pos:          pos,
+		UUID:         uuid.New().String(),
+		Quantity:     quantity,
+		Rarity:       NormalRarity,
+		Quality:      100,
+		Heal:         heal,
+		Boni:         []Boni{},
+		Requirements: []Boni{},
+	}, true
 }
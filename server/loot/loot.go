@@ -0,0 +1,287 @@
+package loot
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"ws-game/resource"
+	"ws-game/shared"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Loot tables describe what resources, npcs (and anything else that drops loot) leave behind.
+// A table consists of pools, every pool is rolled independently. Each roll picks one of the
+// entries whose conditions match by weight, the entry then drops with its chance. An entry drops
+// a resource, random items, the loot of another table or nothing at all.
+
+//go:embed tables.yaml
+var defaultTables []byte
+
+// nested tables deeper than this are not rolled, Parse already rejects cycles
+const maxDepth = 8
+
+// Range is inclusive, a single number in yaml means min = max
+type Range struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+func (r *Range) UnmarshalYAML(value *yaml.Node) error {
+	var n int
+	if value.Decode(&n) == nil {
+		r.Min, r.Max = n, n
+		return nil
+	}
+
+	type plain Range
+	return value.Decode((*plain)(r))
+}
+
+func (r Range) roll(rng shared.Rand) int {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + rng.Intn(r.Max-r.Min+1)
+}
+
+// Condition restricts an entry to the places it can drop at, empty fields match everything
+type Condition struct {
+	Biomes    []string `yaml:"biomes"`
+	NotBiomes []string `yaml:"notBiomes"`
+}
+
+func (c Condition) matches(ctx Context) bool {
+	if len(c.Biomes) > 0 && !contains(c.Biomes, ctx.Biome) {
+		return false
+	}
+	return !contains(c.NotBiomes, ctx.Biome)
+}
+
+type Entry struct {
+	Weight   int       `yaml:"weight"` // default 1
+	Chance   *float64  `yaml:"chance"` // default 1
+	Quantity Range     `yaml:"quantity"`
+	When     Condition `yaml:"when"`
+
+	// at most one of them is set, none drops nothing
+	Resource resource.ResourceType `yaml:"resource"`
+	Item     bool                  `yaml:"item"` // Quantity random items
+	Table    string                `yaml:"table"`
+}
+
+type Pool struct {
+	Rolls   Range   `yaml:"rolls"` // default 1
+	Entries []Entry `yaml:"entries"`
+}
+
+type Table struct {
+	Pools []Pool `yaml:"pools"`
+}
+
+type Tables map[string]Table
+
+// Context is what conditions of a drop are checked against
+type Context struct {
+	Biome string
+}
+
+// Drop is either a stack of a resource or a number of random items
+type Drop struct {
+	Resource resource.ResourceType
+	Item     bool
+	Quantity int
+}
+
+func Default() Tables {
+	tables, err := Parse(defaultTables)
+	if err != nil {
+		panic(fmt.Sprintf("built-in loot tables are invalid: %s", err))
+	}
+	return tables
+}
+
+// Load reads loot tables from a yaml file, an empty path returns the built-in tables
+func Load(path string) (Tables, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tables, nil
+}
+
+func Parse(data []byte) (Tables, error) {
+	tables := Tables{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&tables); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for name, table := range tables {
+		for p := range table.Pools {
+			pool := &table.Pools[p]
+			if pool.Rolls == (Range{}) {
+				pool.Rolls = Range{Min: 1, Max: 1}
+			}
+			for e := range pool.Entries {
+				entry := &pool.Entries[e]
+				if entry.Weight == 0 {
+					entry.Weight = 1
+				}
+				if entry.Quantity == (Range{}) {
+					entry.Quantity = Range{Min: 1, Max: 1}
+				}
+			}
+		}
+		tables[name] = table
+	}
+
+	if err := tables.Validate(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func (tables Tables) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	for name, table := range tables {
+		for p, pool := range table.Pools {
+			where := fmt.Sprintf("%s.pools[%d]", name, p)
+			check(pool.Rolls.Min >= 0 && pool.Rolls.Min <= pool.Rolls.Max, "%s.rolls must be a range >= 0", where)
+
+			for e, entry := range pool.Entries {
+				where := fmt.Sprintf("%s.entries[%d]", where, e)
+				check(entry.Weight > 0, "%s.weight must be positive", where)
+				check(entry.Chance == nil || (*entry.Chance >= 0 && *entry.Chance <= 1), "%s.chance must be between 0 and 1", where)
+				check(entry.Quantity.Min >= 0 && entry.Quantity.Min <= entry.Quantity.Max, "%s.quantity must be a range >= 0", where)
+
+				kinds := 0
+				if entry.Resource != "" {
+					kinds++
+				}
+				if entry.Item {
+					kinds++
+				}
+				if entry.Table != "" {
+					kinds++
+					_, ok := tables[entry.Table]
+					check(ok, "%s.table %s does not exist", where, entry.Table)
+				}
+				check(kinds <= 1, "%s can only drop one of resource, item or table", where)
+			}
+		}
+
+		check(!tables.hasCycle(name, map[string]bool{}), "%s contains itself", name)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid loot tables: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func (tables Tables) hasCycle(name string, visiting map[string]bool) bool {
+	if visiting[name] {
+		return true
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	for _, pool := range tables[name].Pools {
+		for _, entry := range pool.Entries {
+			if entry.Table != "" && tables.hasCycle(entry.Table, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Roll returns the drops of a table, unknown tables drop nothing. rng is passed in so tests can use a seeded one.
+func (tables Tables) Roll(name string, rng shared.Rand, ctx Context) []Drop {
+	drops := []Drop{}
+	tables.roll(name, rng, ctx, 0, &drops)
+	return drops
+}
+
+func (tables Tables) roll(name string, rng shared.Rand, ctx Context, depth int, drops *[]Drop) {
+	table, ok := tables[name]
+	if !ok || depth > maxDepth {
+		return
+	}
+
+	for _, pool := range table.Pools {
+		for i := pool.Rolls.roll(rng); i > 0; i-- {
+			entry, ok := pool.pick(rng, ctx)
+			if !ok {
+				continue
+			}
+			if entry.Chance != nil && rng.Float64() >= *entry.Chance {
+				continue
+			}
+
+			switch {
+			case entry.Table != "":
+				tables.roll(entry.Table, rng, ctx, depth+1, drops)
+			case entry.Resource != "" || entry.Item:
+				if quantity := entry.Quantity.roll(rng); quantity > 0 {
+					*drops = append(*drops, Drop{Resource: entry.Resource, Item: entry.Item, Quantity: quantity})
+				}
+			}
+		}
+	}
+}
+
+// pick selects one of the matching entries by weight
+func (pool Pool) pick(rng shared.Rand, ctx Context) (Entry, bool) {
+	matching := []Entry{}
+	total := 0
+	for _, entry := range pool.Entries {
+		if entry.When.matches(ctx) {
+			matching = append(matching, entry)
+			total += entry.Weight
+		}
+	}
+	if total == 0 {
+		return Entry{}, false
+	}
+
+	roll := rng.Intn(total)
+	for _, entry := range matching {
+		if roll < entry.Weight {
+			return entry, true
+		}
+		roll -= entry.Weight
+	}
+	return Entry{}, false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package loot
+
+import (
+	"math/rand"
+	"testing"
+	"ws-game/resource"
+)
+
+func TestDefaultTablesAreValid(t *testing.T) {
+	tables := Default()
+	for _, name := range []string{"stone", "tree", "blockade", "woodBlockade", "npc"} {
+		if _, ok := tables[name]; !ok {
+			t.Fatalf("missing loot table %s", name)
+		}
+	}
+}
+
+func ironOreRate(tables Tables, biome string) float64 {
+	rng := rand.New(rand.NewSource(1))
+	n := 10000
+	withIron := 0
+	for i := 0; i < n; i++ {
+		for _, drop := range tables.Roll("stone", rng, Context{Biome: biome}) {
+			if drop.Resource == resource.IronOre {
+				withIron++
+			}
+		}
+	}
+	return float64(withIron) / float64(n)
+}
+
+func TestStoneDropsMoreIronInMountains(t *testing.T) {
+	tables := Default()
+
+	if rate := ironOreRate(tables, "Mountain"); rate < 0.77 || rate > 0.83 {
+		t.Fatalf("expected 80%% iron ore in the mountains, got %f", rate)
+	}
+	if rate := ironOreRate(tables, "Forest"); rate < 0.27 || rate > 0.33 {
+		t.Fatalf("expected 30%% iron ore in the forest, got %f", rate)
+	}
+}
+
+func TestWeightsAndNestedTables(t *testing.T) {
+	tables, err := Parse([]byte(`
+chest:
+  pools:
+    - rolls: {min: 2, max: 2}
+      entries:
+        - weight: 3
+          table: gems
+        - {}
+gems:
+  pools:
+    - entries:
+        - resource: gold
+          quantity: {min: 1, max: 3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	n := 10000
+	gold := 0
+	for i := 0; i < n; i++ {
+		for _, drop := range tables.Roll("chest", rng, Context{}) {
+			if drop.Resource != resource.Gold || drop.Quantity < 1 || drop.Quantity > 3 {
+				t.Fatalf("unexpected drop %+v", drop)
+			}
+			gold++
+		}
+	}
+
+	// two rolls with 3/4 chance each
+	if rate := float64(gold) / float64(n); rate < 1.45 || rate > 1.55 {
+		t.Fatalf("expected 1.5 gold drops per chest, got %f", rate)
+	}
+}
+
+func TestInvalidTablesAreRejected(t *testing.T) {
+	for _, data := range []string{
+		"a: {pools: [{entries: [{table: b}]}]}",
+		"a: {pools: [{entries: [{table: a}]}]}",
+		"a: {pools: [{entries: [{resource: log, item: true}]}]}",
+		"a: {pools: [{entries: [{resource: log, chance: 2}]}]}",
+		"a: {pools: [{entries: [{resource: log, quantity: {min: 3, max: 1}}]}]}",
+		"a: {pools: [{entries: [{resorce: log}]}]}",
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Fatalf("expected error for %s", data)
+		}
+	}
+}
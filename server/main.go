@@ -1,22 +1,97 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"sync"
+	"syscall"
+	"time"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/loot"
 	"ws-game/root"
 )
 
 var addr = flag.String("addr", ":6060", "http service address")
+var dataDir = flag.String("data", "data", "directory for persisted game data")
+var configPath = flag.String("config", "", "yaml file with game settings, WS_GAME_* variables override it")
+var lootPath = flag.String("loot", "", "yaml file with loot tables, uses the built-in tables if empty")
+var npcsPath = flag.String("npcs", "", "yaml file with npc archetypes and spawn tables, uses the built-in bestiary if empty")
+var itemsPath = flag.String("items", "", "yaml file with item bases, affixes and rarities, uses the built-in catalog if empty")
 
 func main() {
 	runtime.SetMutexProfileFraction(-1)
 	runtime.SetBlockProfileRate(1)
 	flag.Parse()
-	hub := root.NewHub()
+
+	config, err := root.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal("LoadConfig: ", err)
+	}
+
+	lootTables, err := loot.Load(*lootPath)
+	if err != nil {
+		log.Fatal("loot.Load: ", err)
+	}
+
+	npcTypes, err := bestiary.Load(*npcsPath)
+	if err != nil {
+		log.Fatal("bestiary.Load: ", err)
+	}
+	if err := npcTypes.CheckLoot(lootTables); err != nil {
+		log.Fatal("bestiary.CheckLoot: ", err)
+	}
+	if err := npcTypes.CheckDamage(config.Npc.MinDamage, config.Npc.MaxDamage); err != nil {
+		log.Fatal("bestiary.CheckDamage: ", err)
+	}
+
+	itemCatalog, err := item.Load(*itemsPath)
+	if err != nil {
+		log.Fatal("item.Load: ", err)
+	}
+	if err := root.CheckRecipes(itemCatalog); err != nil {
+		log.Fatal("CheckRecipes: ", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	playerStore, err := root.NewFilePlayerStore(filepath.Join(*dataDir, "players"))
+	if err != nil {
+		log.Fatal("NewFilePlayerStore: ", err)
+	}
+
+	worldStore, err := root.NewFileWorldStore(filepath.Join(*dataDir, "world.json"))
+	if err != nil {
+		log.Fatal("NewFileWorldStore: ", err)
+	}
+
+	accountStore, err := root.NewFileAccountStore(filepath.Join(*dataDir, "accounts.json"))
+	if err != nil {
+		log.Fatal("NewFileAccountStore: ", err)
+	}
+
+	// a fixed secret can be provided if multiple instances have to accept the same tokens
+	sessionSecret := []byte(os.Getenv("WS_GAME_SESSION_SECRET"))
+	if len(sessionSecret) == 0 {
+		sessionSecret, err = root.LoadOrCreateSessionSecret(filepath.Join(*dataDir, "session.key"))
+		if err != nil {
+			log.Fatal("LoadOrCreateSessionSecret: ", err)
+		}
+	}
+	authenticator := root.NewAuthenticator(accountStore, sessionSecret)
+
+	hub := root.NewHub(config, lootTables, npcTypes, itemCatalog, playerStore, worldStore, authenticator)
+	if err := hub.LoadWorld(); err != nil {
+		log.Fatal("LoadWorld: ", err)
+	}
 	go hub.Run()
 
 	var m sync.Mutex
@@ -25,6 +100,9 @@ func main() {
 		w.Write([]byte("hello"))
 	})
 
+	http.HandleFunc("/register", authenticator.HandleRegister)
+	http.HandleFunc("/login", authenticator.HandleLogin)
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		root.ServeWs(hub, w, r, &m)
 	})
@@ -37,8 +115,24 @@ func main() {
 		w.Write([]byte(hub.GridManager.ActiveCells()))
 	})
 
-	err := http.ListenAndServe(*addr, nil)
-	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
+	server := &http.Server{Addr: *addr}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("ListenAndServe: ", err)
+		}
+	}()
+
+	// wait for a deploy or ctrl+c
+	<-ctx.Done()
+	log.Println("shutting down")
+
+	// stop accepting new connections, websocket connections are closed by the hub
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("Shutdown: ", err)
 	}
+
+	hub.Shutdown("server is restarting")
+	log.Println("shutdown complete")
 }
@@ -18,6 +18,9 @@ const (
 	IronIngot    ResourceType = "ironIngot"
 	Gold         ResourceType = "gold"
 	Cooper       ResourceType = "cooper"
+	Furnace      ResourceType = "furnace" // crafting station for smelting ore and cooking
+	Meat         ResourceType = "meat"
+	Herb         ResourceType = "herb"
 )
 
 type ResourceMin struct {
@@ -34,6 +37,9 @@ type Resource struct {
 	IsSolid      bool             `json:"isSolid"`
 	IsLootable   bool             `json:"isLootable"`
 	GridCellKey  string           `json:"gridCellKey"`
+	Owner        string           `json:"owner"`    // uuid of the player that built it, empty for world resources
+	Team         string           `json:"team"`     // team of the owner when it was built
+	DecaysAt     int64            `json:"decaysAt"` // unix time of the next decay step, 0 if it doesn't decay
 	remove       bool
 }
 
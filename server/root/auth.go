@@ -0,0 +1,395 @@
+package root
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// how long a session token issued by /login is valid
+	SessionTokenTTL = 7 * 24 * time.Hour
+
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores everything after 72 bytes
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountExists      = errors.New("username is already taken")
+	ErrInvalidUsername    = errors.New("username must be 3-20 characters: letters, digits, _ or -")
+	ErrInvalidPassword    = fmt.Errorf("password must be %d-%d characters", minPasswordLength, maxPasswordLength)
+	ErrInvalidToken       = errors.New("invalid or expired session token")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,20}$`)
+
+// Account links login credentials to the character of a player
+type Account struct {
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"passwordHash"`
+	PlayerUUID   string    `json:"playerUUID"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// AccountStore is the backend for accounts, usernames are compared case insensitive.
+// Create returns ErrAccountExists if the username is taken.
+type AccountStore interface {
+	Get(username string) (Account, bool, error)
+	Create(account Account) error
+}
+
+// MemoryAccountStore keeps accounts in memory, they are lost on restart
+type MemoryAccountStore struct {
+	accounts      map[string]Account
+	accountsMutex sync.Mutex
+}
+
+func NewMemoryAccountStore() *MemoryAccountStore {
+	return &MemoryAccountStore{
+		accounts:      make(map[string]Account),
+		accountsMutex: sync.Mutex{},
+	}
+}
+
+func (s *MemoryAccountStore) Get(username string) (Account, bool, error) {
+	s.accountsMutex.Lock()
+	defer s.accountsMutex.Unlock()
+
+	account, ok := s.accounts[strings.ToLower(username)]
+	return account, ok, nil
+}
+
+func (s *MemoryAccountStore) Create(account Account) error {
+	s.accountsMutex.Lock()
+	defer s.accountsMutex.Unlock()
+
+	key := strings.ToLower(account.Username)
+	if _, ok := s.accounts[key]; ok {
+		return ErrAccountExists
+	}
+	s.accounts[key] = account
+	return nil
+}
+
+// FileAccountStore keeps all accounts in a single json file
+type FileAccountStore struct {
+	path     string
+	accounts map[string]Account
+	mutex    sync.Mutex
+}
+
+func NewFileAccountStore(path string) (*FileAccountStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	s := &FileAccountStore{
+		path:     path,
+		accounts: make(map[string]Account),
+		mutex:    sync.Mutex{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.accounts); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileAccountStore) Get(username string) (Account, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account, ok := s.accounts[strings.ToLower(username)]
+	return account, ok, nil
+}
+
+func (s *FileAccountStore) Create(account Account) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := strings.ToLower(account.Username)
+	if _, ok := s.accounts[key]; ok {
+		return ErrAccountExists
+	}
+
+	s.accounts[key] = account
+
+	data, err := json.Marshal(s.accounts)
+	if err == nil {
+		err = writeFileAtomic(s.path, data)
+	}
+	if err != nil {
+		delete(s.accounts, key)
+		return err
+	}
+
+	return nil
+}
+
+// sessionClaims are signed into the token a client sends with PLAYER_LOGIN_EVENT
+type sessionClaims struct {
+	Username   string `json:"username"`
+	PlayerUUID string `json:"playerUUID"`
+	Expires    int64  `json:"expires"`
+}
+
+// Authenticator registers accounts and issues/validates session tokens
+type Authenticator struct {
+	accounts AccountStore
+	secret   []byte
+}
+
+func NewAuthenticator(accounts AccountStore, secret []byte) *Authenticator {
+	return &Authenticator{
+		accounts: accounts,
+		secret:   secret,
+	}
+}
+
+// LoadOrCreateSessionSecret reads the token signing key from path or creates a new one,
+// keeping it on disk lets sessions survive restarts
+func LoadOrCreateSessionSecret(path string) ([]byte, error) {
+	secret, err := os.ReadFile(path)
+	if err == nil && len(secret) >= 32 {
+		return secret, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+func (a *Authenticator) Register(username string, password string) (Account, error) {
+	if !usernamePattern.MatchString(username) {
+		return Account{}, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return Account{}, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return Account{}, err
+	}
+
+	account := Account{
+		Username:     username,
+		PasswordHash: hash,
+		PlayerUUID:   uuid.New().String(),
+		CreatedAt:    time.Now(),
+	}
+
+	if err := a.accounts.Create(account); err != nil {
+		return Account{}, err
+	}
+
+	return account, nil
+}
+
+// Login checks the credentials and returns a signed session token
+func (a *Authenticator) Login(username string, password string) (string, Account, error) {
+	account, ok, err := a.accounts.Get(username)
+	if err != nil {
+		return "", Account{}, err
+	}
+
+	if !ok {
+		// compare against a dummy hash anyway so the response time doesn't reveal existing usernames
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return "", Account{}, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password)); err != nil {
+		return "", Account{}, ErrInvalidCredentials
+	}
+
+	token, err := a.issueToken(account, time.Now().Add(SessionTokenTTL))
+	if err != nil {
+		return "", Account{}, err
+	}
+
+	return token, account, nil
+}
+
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func (a *Authenticator) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tokens have the form base64(claims).base64(hmac of the first part)
+func (a *Authenticator) issueToken(account Account, expires time.Time) (string, error) {
+	claims := sessionClaims{
+		Username:   account.Username,
+		PlayerUUID: account.PlayerUUID,
+		Expires:    expires.Unix(),
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + a.sign(payload), nil
+}
+
+func (a *Authenticator) ValidateToken(token string) (sessionClaims, error) {
+	claims := sessionClaims{}
+
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return claims, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(a.sign(payload))) {
+		return claims, ErrInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+
+	if time.Now().Unix() > claims.Expires {
+		return claims, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+type credentialsRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type sessionResponse struct {
+	Token    string `json:"token"`
+	Username string `json:"username"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func readCredentials(w http.ResponseWriter, r *http.Request) (credentialsRequest, bool) {
+	credentials := credentialsRequest{}
+
+	// the frontend is served from a different origin than the api during development,
+	// no cookies are involved so allowing every origin is fine
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return credentials, false
+	}
+
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return credentials, false
+	}
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&credentials); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return credentials, false
+	}
+
+	return credentials, true
+}
+
+// HandleRegister creates an account and directly returns a session token for it
+func (a *Authenticator) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	credentials, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := a.Register(credentials.Username, credentials.Password)
+	switch {
+	case errors.Is(err, ErrAccountExists):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, ErrInvalidUsername), errors.Is(err, ErrInvalidPassword):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		fmt.Printf("Error: failed to register account: %s\n", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "registration failed"})
+		return
+	}
+
+	a.writeSession(w, credentials)
+}
+
+func (a *Authenticator) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	credentials, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	a.writeSession(w, credentials)
+}
+
+func (a *Authenticator) writeSession(w http.ResponseWriter, credentials credentialsRequest) {
+	token, account, err := a.Login(credentials.Username, credentials.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: failed to login: %s\n", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "login failed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessionResponse{Token: token, Username: account.Username})
+}
@@ -0,0 +1,59 @@
+package root
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	account, err := auth.Register("bob", "secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := auth.Register("BOB", "other-password"); !errors.Is(err, ErrAccountExists) {
+		t.Errorf("expected ErrAccountExists got %v", err)
+	}
+
+	if _, _, err := auth.Login("bob", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials got %v", err)
+	}
+
+	token, _, err := auth.Login("bob", "secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := auth.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.PlayerUUID != account.PlayerUUID {
+		t.Errorf("expected player %s got %s", account.PlayerUUID, claims.PlayerUUID)
+	}
+}
+
+func TestValidateTokenRejectsForgedAndExpiredTokens(t *testing.T) {
+	auth := newTestAuthenticator()
+	account := Account{Username: "eve", PlayerUUID: "some-uuid"}
+
+	expired, err := auth.issueToken(account, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ValidateToken(expired); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected expired token to be rejected got %v", err)
+	}
+
+	other := NewAuthenticator(NewMemoryAccountStore(), []byte("another-secret-another-secret-xx"))
+	forged, err := other.issueToken(account, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ValidateToken(forged); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected token signed with another secret to be rejected got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package root
+
+import (
+	"ws-game/shared"
+)
+
+type Biome string
+
+const (
+	ForestBiome   Biome = "Forest"
+	DesertBiome   Biome = "Desert"
+	MountainBiome Biome = "Mountain"
+	SwampBiome    Biome = "Swamp"
+	SnowBiome     Biome = "Snow"
+)
+
+// moisture and temperature noise is sampled at this fraction of the elevation coordinates
+const biomeScale = 0.25
+
+type biomeDefinition struct {
+	terrain TerrainType // land away from the shore
+
+	// multiplier of the resources config, 2 -> twice as many trees as configured
+	treeDensity  float64
+	stoneDensity float64
+}
+
+var biomes = map[Biome]biomeDefinition{
+	ForestBiome: {
+		terrain:      Grass,
+		treeDensity:  2,
+		stoneDensity: 0.5,
+	},
+	DesertBiome: {
+		terrain:      Sand,
+		treeDensity:  0.1,
+		stoneDensity: 1,
+	},
+	MountainBiome: {
+		terrain:      Rock,
+		treeDensity:  0.3,
+		stoneDensity: 4,
+	},
+	SwampBiome: {
+		terrain:      Mud,
+		treeDensity:  1,
+		stoneDensity: 0.2,
+	},
+	SnowBiome: {
+		terrain:      Snow,
+		treeDensity:  0.5,
+		stoneDensity: 1,
+	},
+}
+
+// getBiome picks the biome from the noise layers, moisture and temperature are roughly in [-0.7, 0.7]
+func getBiome(depth float64, moisture float64, temperature float64) Biome {
+	switch {
+	case temperature < -0.25:
+		return SnowBiome
+	case depth < 0.05:
+		// elevation noise close to 0 forms ridges far from the water
+		return MountainBiome
+	case temperature > 0.15 && moisture < 0:
+		return DesertBiome
+	case moisture > 0.2 && depth > 0.2:
+		// wet lowlands close to the water
+		return SwampBiome
+	default:
+		return ForestBiome
+	}
+}
+
+// BiomeAt returns the biome at a world position, see TerrainAt
+func (w *World) BiomeAt(pos shared.Vector) Biome {
+	subX := floorDiv(pos.X, w.SubCellSize)
+	subY := floorDiv(pos.Y, w.SubCellSize)
+	cellX := floorDiv(subX, w.SubCells)
+	cellY := floorDiv(subY, w.SubCells)
+
+	_, biome := w.subCellTerrain(cellX, cellY, subX-cellX*w.SubCells, subY-cellY*w.SubCells)
+	return biome
+}
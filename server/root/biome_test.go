@@ -0,0 +1,57 @@
+package root
+
+import (
+	"testing"
+	"ws-game/bestiary"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func biomeSubCells(w *World, biome Biome) []SubCell {
+	subCells := []SubCell{}
+	for x := 0; x < w.SubCells; x++ {
+		for y := 0; y < w.SubCells; y++ {
+			subCells = append(subCells, SubCell{Pos: shared.Vector{X: x, Y: y}, TerrainType: biomes[biome].terrain, Biome: biome})
+		}
+	}
+	return subCells
+}
+
+func countResources(spawns []resourceSpawn, resourceType resource.ResourceType) int {
+	n := 0
+	for _, spawn := range spawns {
+		if spawn.resourceType == resourceType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBiomeResourceDistribution(t *testing.T) {
+	config := DefaultConfig()
+	w := NewWorld(config.World)
+	forest := w.generateResources(shared.Vector{X: 0, Y: 0}, biomeSubCells(w, ForestBiome), config.Resources)
+	mountain := w.generateResources(shared.Vector{X: 0, Y: 0}, biomeSubCells(w, MountainBiome), config.Resources)
+
+	if countResources(forest, resource.Tree) <= countResources(mountain, resource.Tree) {
+		t.Fatal("expected more trees in the forest than in the mountains")
+	}
+	if countResources(mountain, resource.Stone) <= countResources(forest, resource.Stone) {
+		t.Fatal("expected more stones in the mountains than in the forest")
+	}
+}
+
+func TestBiomesAreComplete(t *testing.T) {
+	for _, biome := range []Biome{ForestBiome, DesertBiome, MountainBiome, SwampBiome, SnowBiome} {
+		definition, ok := biomes[biome]
+		if !ok {
+			t.Fatalf("biome %s is not defined", biome)
+		}
+		if !definition.terrain.Passable() {
+			t.Fatalf("land of biome %s is not passable", biome)
+		}
+		if _, ok := bestiary.Default().SpawnTable(string(biome), 1); !ok {
+			t.Fatalf("biome %s has no npcs", biome)
+		}
+	}
+}
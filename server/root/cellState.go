@@ -0,0 +1,281 @@
+package root
+
+import (
+	"fmt"
+	"strconv"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// Npcs and resources of a cell are not broadcasted as events anymore. Each tick the cell builds a
+// snapshot of their state and sends every subscriber only what changed compared to the last
+// snapshot the subscriber acknowledged. Subscribers without a baseline get a keyframe with the
+// full state. Every StateKeyframeInterval ticks all subscribers get a keyframe to resync, it
+// contains position and hitpoints of every entity but spawn data only for unknown ones.
+
+const (
+	// every n-th cell tick all subscribers get the full state of the cell
+	StateKeyframeInterval = 100
+
+	// a subscriber that has this many snapshots unacknowledged loses its baseline and gets a keyframe
+	maxPendingSnapshots = 32
+)
+
+type EntityKind int
+
+const (
+	NpcEntity      EntityKind = 0
+	ResourceEntity EntityKind = 1
+)
+
+// entityState contains the fields that are compared between snapshots
+type entityState struct {
+	kind      EntityKind
+	pos       shared.Vector
+	hitpoints shared.Hitpoints
+	npc       *Npc
+	resource  *resource.Resource
+}
+
+// stateSnapshot maps entity ids (npc uuid or resource id) to their state
+type stateSnapshot map[string]entityState
+
+// subscriberState is what the cell knows about the state of one subscriber
+type subscriberState struct {
+	ackedSeq int
+	acked    stateSnapshot         // nil until the subscriber acked a snapshot
+	pending  map[int]stateSnapshot // sent but not acked yet
+
+	// last keyframe sent, used as baseline until the first ack arrives
+	keyframeSeq int
+}
+
+type stateAck struct {
+	clientId int
+	seq      int
+}
+
+func newSubscriberState() *subscriberState {
+	return &subscriberState{
+		pending: make(map[int]stateSnapshot),
+	}
+}
+
+// EntityDelta contains only the fields that changed, npc or resource is set if the entity is new to the client
+type EntityDelta struct {
+	Id        string             `json:"id" wire:"uuid"`
+	Kind      EntityKind         `json:"kind"`
+	Pos       *shared.Vector     `json:"pos,omitempty"`
+	Hitpoints *shared.Hitpoints  `json:"hitpoints,omitempty"`
+	Npc       *Npc               `json:"npc,omitempty"`
+	Resource  *resource.Resource `json:"resource,omitempty"`
+}
+
+type CellStateEvent struct {
+	EventType   EventType     `json:"eventType"`
+	GridCellKey string        `json:"gridCellKey"`
+	Seq         int           `json:"seq"`
+	BaseSeq     int           `json:"baseSeq"` // snapshot the deltas apply to, 0 for keyframes
+	Keyframe    bool          `json:"keyframe"`
+	Entities    []EntityDelta `json:"entities"`
+	Removed     []string      `json:"removed" wire:"uuid"`
+}
+
+func resourceEntityId(id int) string {
+	return strconv.Itoa(id)
+}
+
+// currentState builds the snapshot of all npcs and resources in the cell
+func (cell *GridCell) currentState() stateSnapshot {
+	snapshot := make(stateSnapshot)
+
+	for _, npc := range cell.GetNpcList() {
+		if npc.remove {
+			continue
+		}
+		npc := npc
+		npc.targetedPlayer = nil
+		snapshot[npc.UUID] = entityState{kind: NpcEntity, pos: npc.Pos, hitpoints: npc.Hitpoints, npc: &npc}
+	}
+
+	for id, r := range cell.GetResources() {
+		r := r
+		snapshot[resourceEntityId(id)] = entityState{kind: ResourceEntity, pos: r.Pos, hitpoints: r.Hitpoints, resource: &r}
+	}
+
+	return snapshot
+}
+
+// diffSnapshots returns the changes from base to current, a nil base results in the full state.
+// if all is set every entity is included with position and hitpoints
+func diffSnapshots(base stateSnapshot, current stateSnapshot, all bool) ([]EntityDelta, []string) {
+	entities := []EntityDelta{}
+	removed := []string{}
+
+	for id, state := range current {
+		old, known := base[id]
+		if !known {
+			entities = append(entities, EntityDelta{Id: id, Kind: state.kind, Npc: state.npc, Resource: state.resource})
+			continue
+		}
+
+		delta := EntityDelta{Id: id, Kind: state.kind}
+		changed := false
+		if all || old.pos != state.pos {
+			pos := state.pos
+			delta.Pos = &pos
+			changed = true
+		}
+		if all || old.hitpoints != state.hitpoints {
+			hitpoints := state.hitpoints
+			delta.Hitpoints = &hitpoints
+			changed = true
+		}
+		if changed {
+			entities = append(entities, delta)
+		}
+	}
+
+	for id := range base {
+		if _, ok := current[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	return entities, removed
+}
+
+// nextStateEvent returns the event for a subscriber or nil if nothing changed since its baseline
+func (state *subscriberState) nextStateEvent(gridCellKey string, seq int, current stateSnapshot, keyframe bool) *CellStateEvent {
+	if len(state.pending) >= maxPendingSnapshots {
+		// client doesn't ack (anymore), start over with a keyframe
+		state.acked = nil
+		state.ackedSeq = 0
+		state.keyframeSeq = 0
+		state.pending = make(map[int]stateSnapshot)
+	}
+
+	base := state.acked
+	baseSeq := state.ackedSeq
+	if base == nil && state.keyframeSeq != 0 {
+		// the websocket delivers in order, so the client has the keyframe before this delta
+		base = state.pending[state.keyframeSeq]
+		baseSeq = state.keyframeSeq
+	}
+
+	var entities []EntityDelta
+	var removed []string
+	if keyframe || base == nil {
+		// the keyframe replaces the state of the client, known entities don't need spawn data
+		entities, _ = diffSnapshots(base, current, true)
+		removed = []string{}
+		keyframe = true
+		baseSeq = 0
+	} else {
+		entities, removed = diffSnapshots(base, current, false)
+		if len(entities) == 0 && len(removed) == 0 {
+			return nil
+		}
+	}
+
+	state.pending[seq] = current
+	if keyframe {
+		state.keyframeSeq = seq
+	}
+
+	return &CellStateEvent{
+		EventType:   CELL_STATE_EVENT,
+		GridCellKey: gridCellKey,
+		Seq:         seq,
+		BaseSeq:     baseSeq,
+		Keyframe:    keyframe,
+		Entities:    entities,
+		Removed:     removed,
+	}
+}
+
+// ack makes an acknowledged snapshot the new baseline
+func (state *subscriberState) ack(seq int) {
+	snapshot, ok := state.pending[seq]
+	if !ok {
+		// already replaced by a newer ack or dropped
+		return
+	}
+
+	state.acked = snapshot
+	state.ackedSeq = seq
+	for pendingSeq := range state.pending {
+		if pendingSeq <= seq {
+			delete(state.pending, pendingSeq)
+		}
+	}
+}
+
+// QueueStateAck is called by the hub, the ack is processed with the next cell tick
+func (cell *GridCell) QueueStateAck(clientId int, seq int) {
+	cell.stateAcksMutex.Lock()
+	cell.stateAcks = append(cell.stateAcks, stateAck{clientId: clientId, seq: seq})
+	cell.stateAcksMutex.Unlock()
+}
+
+// resetSubscriberState makes sure a new subscription starts with a keyframe
+func (cell *GridCell) resetSubscriberState(clientId int) {
+	delete(cell.subscriberStates, clientId)
+}
+
+// sendStateUpdates is called from the cell coroutine after the npcs got updated
+func (cell *GridCell) sendStateUpdates() {
+	cell.stateAcksMutex.Lock()
+	acks := cell.stateAcks
+	cell.stateAcks = []stateAck{}
+	cell.stateAcksMutex.Unlock()
+
+	for _, a := range acks {
+		if state, ok := cell.subscriberStates[a.clientId]; ok {
+			state.ack(a.seq)
+		}
+	}
+
+	cell.stateSeq++
+	keyframe := cell.stateSeq%StateKeyframeInterval == 0
+	current := cell.currentState()
+
+	subscribed := make(map[int]bool)
+	for _, sub := range cell.GetSubscriptions() {
+		if !sub.Player.getConnected() {
+			continue
+		}
+		subscribed[sub.Player.Id] = true
+
+		state, ok := cell.subscriberStates[sub.Player.Id]
+		if !ok {
+			state = newSubscriberState()
+			cell.subscriberStates[sub.Player.Id] = state
+		}
+
+		if event := state.nextStateEvent(cell.GridCellKey, cell.stateSeq, current, keyframe); event != nil {
+			sub.Player.send <- event
+		}
+	}
+
+	// forget about clients that unsubscribed
+	for clientId := range cell.subscriberStates {
+		if !subscribed[clientId] {
+			delete(cell.subscriberStates, clientId)
+		}
+	}
+}
+
+// handleStateAck passes the ack of a client to the cell the snapshot belongs to
+func (h *Hub) handleStateAck(event StateAckEvent, c *Client) {
+	for _, a := range event.Acks {
+		var x, y int
+		if _, err := fmt.Sscanf(a.GridCellKey, "%d#%d", &x, &y); err != nil {
+			continue
+		}
+
+		if cell, ok := h.GridManager.findCell(x, y); ok {
+			cell.QueueStateAck(c.Id, a.Seq)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package root
+
+import (
+	"testing"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func testSnapshot(npcPos shared.Vector, treeHp int) stateSnapshot {
+	npc := NewNpc(npcPos, DefaultConfig().Npc)
+	npc.UUID = "c0ffee00-0000-4000-8000-000000000001"
+	tree := resource.NewResource(resource.Tree, shared.Vector{X: 10, Y: 20}, 7, 100, true, treeHp, false, "0#0")
+
+	return stateSnapshot{
+		npc.UUID:                  {kind: NpcEntity, pos: npc.Pos, hitpoints: npc.Hitpoints, npc: &npc},
+		resourceEntityId(tree.Id): {kind: ResourceEntity, pos: tree.Pos, hitpoints: tree.Hitpoints, resource: tree},
+	}
+}
+
+func TestCellStateSendsOnlyChanges(t *testing.T) {
+	state := newSubscriberState()
+
+	first := state.nextStateEvent("0#0", 1, testSnapshot(shared.Vector{X: 1, Y: 1}, 100), false)
+	if first == nil || !first.Keyframe || len(first.Entities) != 2 {
+		t.Fatalf("expected keyframe with all entities, got %+v", first)
+	}
+	if _, err := EncodeBinaryEvent(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing changed since the keyframe
+	if event := state.nextStateEvent("0#0", 2, testSnapshot(shared.Vector{X: 1, Y: 1}, 100), false); event != nil {
+		t.Fatalf("expected no event, got %+v", event)
+	}
+
+	state.ack(1)
+
+	moved := testSnapshot(shared.Vector{X: 5, Y: 1}, 100)
+	event := state.nextStateEvent("0#0", 3, moved, false)
+	if event == nil || event.Keyframe || event.BaseSeq != 1 || len(event.Entities) != 1 {
+		t.Fatalf("expected delta with the npc only, got %+v", event)
+	}
+	delta := event.Entities[0]
+	if delta.Kind != NpcEntity || delta.Pos == nil || delta.Pos.X != 5 || delta.Hitpoints != nil || delta.Npc != nil {
+		t.Fatalf("unexpected npc delta %+v", delta)
+	}
+
+	// not acked yet -> the next delta is still relative to seq 1
+	delete(moved, resourceEntityId(7))
+	event = state.nextStateEvent("0#0", 4, moved, false)
+	if event == nil || event.BaseSeq != 1 || len(event.Removed) != 1 || event.Removed[0] != "7" {
+		t.Fatalf("expected removed tree relative to seq 1, got %+v", event)
+	}
+
+	// the client knows the npc already, the keyframe doesn't repeat its spawn data
+	event = state.nextStateEvent("0#0", 5, moved, true)
+	if event == nil || !event.Keyframe || len(event.Entities) != 1 {
+		t.Fatalf("expected forced keyframe, got %+v", event)
+	}
+	if delta := event.Entities[0]; delta.Npc != nil || delta.Pos == nil || delta.Hitpoints == nil {
+		t.Fatalf("unexpected keyframe entry %+v", delta)
+	}
+}
+
+func TestCellStateKeyframeWithoutAcks(t *testing.T) {
+	state := newSubscriberState()
+
+	for seq := 1; seq <= maxPendingSnapshots+1; seq++ {
+		event := state.nextStateEvent("0#0", seq, testSnapshot(shared.Vector{X: seq, Y: 1}, 100), false)
+		if event == nil {
+			t.Fatalf("expected event for seq %d", seq)
+		}
+		if seq == maxPendingSnapshots+1 && !event.Keyframe {
+			t.Fatal("expected keyframe after too many unacked snapshots")
+		}
+	}
+}
@@ -39,6 +39,14 @@ var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin:     x,
+	// preferred first, clients that don't ask for a subprotocol get json
+	Subprotocols: []string{BinarySubprotocol, JSONSubprotocol},
+}
+
+// closeConnection is queued on the send channel to close the connection after all pending messages
+type closeConnection struct {
+	code   int
+	reason string
 }
 
 // Client is a middleman between the websocket connection and the hub.
@@ -49,37 +57,49 @@ type Client struct {
 	conn *websocket.Conn
 
 	// Buffered channel of outbound messages.
-	send                chan interface{}
-	Id                  int
-	UUID                string
-	Pos                 shared.Vector
-	Hitpoints           shared.Hitpoints
-	PosMutex            sync.Mutex
-	ResourceInventory   map[resource.ResourceType]resource.Resource
-	ItemInventory       []item.Item
-	ItemInventoryMutex  sync.Mutex
-	ZoneChangeTick      int
-	ZoneChangeTickMutex sync.Mutex
-	GridCell            *GridCell
-	GridCellMutex       sync.Mutex
-	Connected           bool
-	ConnectedMutex      sync.Mutex
-	NeedsInit           bool
-	EquippedItemsMutex  sync.Mutex
-	EquippedItems       []string
-	minDamage           int
-	maxDamage           int
-	critChance          int
+	send                   chan interface{}
+	Id                     int
+	UUID                   string
+	Team                   string // players of the same team share their structures, empty if not in a team
+	Pos                    shared.Vector
+	Hitpoints              shared.Hitpoints
+	HitpointsMutex         sync.Mutex
+	lastCombat             time.Time // regeneration starts a while after it
+	PosMutex               sync.Mutex
+	ResourceInventory      map[resource.ResourceType]resource.Resource
+	ResourceInventoryMutex sync.Mutex
+	ItemInventory          []item.Item
+	ItemInventoryMutex     sync.Mutex
+	ZoneChangeTick         int
+	ZoneChangeTickMutex    sync.Mutex
+	GridCell               *GridCell
+	GridCellMutex          sync.Mutex
+	Connected              bool
+	ConnectedMutex         sync.Mutex
+	loggedIn               bool
+	NeedsInit              bool
+	EquipmentMutex         sync.Mutex
+	Equipment              Equipment
+	stats                  Stats
+	lastAction             time.Time // hits and loot wait for the attack speed, see tryAction
+	statsMutex             sync.Mutex
+	input                  movementInput
+	InputMutex             sync.Mutex
+	binaryProtocol         bool // outbound events are written in the binary wire format instead of json
+	dead                   bool
+	respawnAt              time.Time
+	respawnPos             *shared.Vector // set by the player, nil uses the spawn of the server
+	lifeMutex              sync.Mutex
 }
 
 func NewClient(hub *Hub, conn *websocket.Conn, id int) *Client {
-	clientPostion := shared.Vector{X: GridCellSize / 2, Y: GridCellSize / 2}
+	clientPostion := shared.Vector{X: hub.config.Player.SpawnX, Y: hub.config.Player.SpawnY}
 
 	sendChan := make(chan interface{}, 1024)
 
 	hitpoints := shared.Hitpoints{
-		Current: 500000,
-		Max:     500000,
+		Current: hub.config.Player.Hitpoints,
+		Max:     hub.config.Player.Hitpoints,
 	}
 
 	gridCell := hub.GridManager.GetCellFromPos(clientPostion)
@@ -99,106 +119,100 @@ func NewClient(hub *Hub, conn *websocket.Conn, id int) *Client {
 		Hitpoints:           hitpoints,
 		ItemInventory:       []item.Item{},
 		ItemInventoryMutex:  sync.Mutex{},
-		EquippedItemsMutex:  sync.Mutex{},
-		EquippedItems:       []string{},
-		minDamage:           10,
-		maxDamage:           20,
-		critChance:          50,
+		EquipmentMutex:      sync.Mutex{},
+		Equipment:           Equipment{},
+		input:               newMovementInput(),
+		InputMutex:          sync.Mutex{},
 		// NeedsInit gets set to false after first cell data is provided to the client
 	}
+	// vitality of the base attributes adds to the hitpoints
+	client.updateStats()
+	client.Hitpoints.Current = client.Hitpoints.Max
 
 	return client
 }
 
-func (c *Client) DamageRoll() (int, bool) {
-	damage := shared.RandIntInRange(c.minDamage, c.maxDamage)
-	isCrit := shared.RandIntInRange(0, 101) >= c.critChance
-	if isCrit {
-		damage *= 2
-	}
-	return damage, isCrit
+func (c *Client) getConnected() bool {
+	c.ConnectedMutex.Lock()
+	isConn := c.Connected
+	c.ConnectedMutex.Unlock()
+	return isConn
 }
 
-func (c *Client) handleInventoryItemClick(uuid string) {
-	c.EquippedItemsMutex.Lock()
-	defer c.EquippedItemsMutex.Unlock()
-
-	deselect := false
-	for i, itemUUID := range c.EquippedItems {
-		if itemUUID == uuid {
-			// deselect
-			c.EquippedItems = append(c.EquippedItems[:i], c.EquippedItems[i+1:]...)
-			deselect = true
-		}
-	}
+func (c *Client) setConnected(v bool) {
+	c.ConnectedMutex.Lock()
+	c.Connected = v
+	c.ConnectedMutex.Unlock()
+}
 
-	if !deselect {
-		c.EquippedItems = append(c.EquippedItems, uuid)
+// Close sends a close frame with the code and reason after all queued messages were written
+func (c *Client) Close(code int, reason string) {
+	select {
+	case c.send <- closeConnection{code: code, reason: reason}:
+	default:
+		// send buffer is full, the client would not receive the pending messages in time anyway
+		c.conn.Close()
 	}
-
-	// update clients inventory status
-	c.send <- NewUpdateEquippedInventoryItemEvent(uuid, !deselect)
 }
 
-func (c *Client) updateStats() {
-	strength := 0
-	vitality := 0
-
-	minDamage := 10
-	maxDamage := 20
-
-	c.EquippedItemsMutex.Lock()
-	c.ItemInventoryMutex.Lock()
-	defer func() {
-		c.EquippedItemsMutex.Unlock()
-		c.ItemInventoryMutex.Unlock()
-	}()
-
-	for _, inventoryItem := range c.ItemInventory {
-		for _, itemUUID := range c.EquippedItems {
-			if inventoryItem.UUID == itemUUID {
-				// equipped item
-
-				if inventoryItem.MinDamage > 0 {
-					minDamage += inventoryItem.MinDamage
-				}
-
-				if inventoryItem.MaxDamage > 0 {
-					maxDamage += inventoryItem.MaxDamage
-				}
+// trySend queues an event without blocking, the event is dropped if the client disconnected
+// or can't keep up
+func (c *Client) trySend(event interface{}) bool {
+	c.ConnectedMutex.Lock()
+	defer c.ConnectedMutex.Unlock()
 
-				for _, boni := range inventoryItem.Boni {
-					if boni.Attribute == "strength" {
-						strength += boni.Value
-					}
-					if boni.Attribute == "vitality" {
-						vitality += boni.Value
-					}
-				}
-			}
-		}
+	if !c.Connected {
+		return false
 	}
 
-	minDamage += strength
-	maxDamage += strength
-
-	c.minDamage = minDamage
-	c.maxDamage = maxDamage
+	select {
+	case c.send <- event:
+		return true
+	default:
+		return false
+	}
 }
 
-func (c *Client) getConnected() bool {
+func (c *Client) isLoggedIn() bool {
 	c.ConnectedMutex.Lock()
-	isConn := c.Connected
+	loggedIn := c.loggedIn
 	c.ConnectedMutex.Unlock()
-	return isConn
+	return loggedIn
 }
 
-func (c *Client) setConnected(v bool) {
+func (c *Client) setLoggedIn(v bool) {
 	c.ConnectedMutex.Lock()
-	c.Connected = v
+	c.loggedIn = v
 	c.ConnectedMutex.Unlock()
 }
 
+// getPersistance copies everything that should survive a disconnect
+func (c *Client) getPersistance() ClientPersistance {
+	c.ResourceInventoryMutex.Lock()
+	inventory := make(map[resource.ResourceType]resource.Resource, len(c.ResourceInventory))
+	for resourceType, r := range c.ResourceInventory {
+		inventory[resourceType] = r
+	}
+	c.ResourceInventoryMutex.Unlock()
+
+	c.ItemInventoryMutex.Lock()
+	items := append([]item.Item{}, c.ItemInventory...)
+	c.ItemInventoryMutex.Unlock()
+
+	persistance := ClientPersistance{
+		Pos:           c.GetPos(),
+		Inventory:     inventory,
+		ItemInventory: items,
+		Hitpoints:     c.getHitpoints(),
+		Equipment:     c.getEquipment(),
+		Team:          c.Team,
+	}
+	if respawnPos, ok := c.getRespawnPos(); ok {
+		persistance.RespawnPos = &respawnPos
+	}
+	return persistance
+}
+
 func (c *Client) GetPos() shared.Vector {
 	c.PosMutex.Lock()
 	pos := c.Pos
@@ -295,9 +309,23 @@ func (client *Client) writePump() {
 			if !client.getConnected() || !ok {
 				return
 			}
-			// json
+			if closeMessage, isClose := message.(closeConnection); isClose {
+				closeFrame := websocket.FormatCloseMessage(closeMessage.code, closeMessage.reason)
+				client.conn.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(writeWait))
+				return
+			}
 			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			client.conn.WriteJSON(message)
+			if client.binaryProtocol {
+				data, err := EncodeBinaryEvent(message)
+				if err != nil {
+					fmt.Printf("Error: failed to encode %T: %s\n", message, err)
+					continue
+				}
+				client.conn.WriteMessage(websocket.BinaryMessage, data)
+			} else {
+				// json
+				client.conn.WriteJSON(message)
+			}
 		case <-ticker.C:
 			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -320,6 +348,18 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, m *sync.Mutex) {
 	m.Lock()
 	client := NewClient(hub, conn, hub.getClientId())
 
+	if conn.Subprotocol() == BinarySubprotocol {
+		// the schema is needed to decode everything else, so it is written before the pumps start
+		client.binaryProtocol = true
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := conn.WriteJSON(NewWireSchemaEvent()); err != nil {
+			log.Println(err)
+			conn.Close()
+			m.Unlock()
+			return
+		}
+	}
+
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
@@ -334,44 +374,58 @@ func UnmarshalClientEvents(event_data BaseEvent, h *Hub, c *Client) {
 	case PLAYER_LOGIN_EVENT:
 		// first event the client sends after establishing a websocket connection
 		loginPlayerEvent := &LoginPlayerEvent{}
-		if err := json.Unmarshal(event_data.Payload, &loginPlayerEvent); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &loginPlayerEvent, c) {
+			return
 		}
-		h.LoginPlayer(loginPlayerEvent.UUID, c)
+		h.LoginPlayer(loginPlayerEvent.Token, c)
+		return
+	}
+
+	// everything else requires an authenticated player
+	if !c.isLoggedIn() {
+		return
+	}
+
+	// dead players only receive
+	if c.isDead() && event_data.EventType != STATE_ACK_EVENT {
+		return
+	}
+
+	switch event_data.EventType {
 	case KEYBOARD_EVENT:
 		keyboardEvent := &KeyBoardEvent{}
-		if err := json.Unmarshal(event_data.Payload, &keyboardEvent); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &keyboardEvent, c) {
+			return
 		}
 
-		h.handleMovementEvent(*keyboardEvent, c)
+		h.handleKeyboardEvent(*keyboardEvent, c)
 
 	case HIT_RESOURCE_EVENT:
 		event := &HitResourceEvent{}
-		if err := json.Unmarshal(event_data.Payload, &event); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &event, c) {
+			return
 		}
 		h.HandleResourceHit(*event, c)
 
 	case HIT_NPC_EVENT:
 		event := &HitNpcEvent{}
-		if err := json.Unmarshal(event_data.Payload, &event); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &event, c) {
+			return
 		}
 		h.HandleNpcHit(*event, c)
 
 	case LOOT_RESOURCE_EVENT:
 		event := &LootResourceEvent{}
-		if err := json.Unmarshal(event_data.Payload, &event); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &event, c) {
+			return
 		}
 		h.HandleLootResource(*event, c)
 
 	case PLAYER_PLACED_RESOURCE_EVENT:
 		event := &PlayerPlacedResourceEvent{}
 
-		if err := json.Unmarshal(event_data.Payload, &event); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &event, c) {
+			return
 		}
 
 		h.HandlePlayerPlacedResource(*event, c)
@@ -379,8 +433,8 @@ func UnmarshalClientEvents(event_data BaseEvent, h *Hub, c *Client) {
 	case PLAYER_CLICKED_GROUND_ITEM_EVENT:
 		event := &PlayerClickedItemEvent{}
 
-		if err := json.Unmarshal(event_data.Payload, &event); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &event, c) {
+			return
 		}
 
 		//h.HandlePlayerPlacedResource(*event, c)
@@ -390,12 +444,57 @@ func UnmarshalClientEvents(event_data BaseEvent, h *Hub, c *Client) {
 
 		event := &PlayerClickedInventoryItemEvent{}
 
-		if err := json.Unmarshal(event_data.Payload, &event); err != nil {
-			panic(err)
+		if !unmarshalPayload(event_data, &event, c) {
+			return
+		}
+
+		h.HandleInventoryItemClick(event.UUID, c)
+
+	case USE_ITEM_EVENT:
+		event := &UseItemEvent{}
+		if !unmarshalPayload(event_data, &event, c) {
+			return
+		}
+		h.HandleUseItem(*event, c)
+
+	case STATE_ACK_EVENT:
+		event := &StateAckEvent{}
+
+		if !unmarshalPayload(event_data, &event, c) {
+			return
+		}
+
+		h.handleStateAck(*event, c)
+
+	case CRAFT_EVENT:
+		event := &CraftEvent{}
+
+		if !unmarshalPayload(event_data, &event, c) {
+			return
+		}
+
+		h.HandleCraft(*event, c)
+
+	case REPAIR_EVENT:
+		event := &RepairEvent{}
+
+		if !unmarshalPayload(event_data, &event, c) {
+			return
 		}
 
-		c.handleInventoryItemClick(event.UUID)
-		c.updateStats()
+		h.HandleRepair(*event, c)
+
+	case SET_RESPAWN_EVENT:
+		h.HandleSetRespawn(c)
 	}
 
 }
+
+// unmarshalPayload decodes the payload of a client event into v, malformed payloads are logged and dropped
+func unmarshalPayload(event BaseEvent, v interface{}, c *Client) bool {
+	if err := json.Unmarshal(event.Payload, v); err != nil {
+		fmt.Printf("Error: client %d sent a malformed payload for event %d: %s\n", c.Id, event.EventType, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,329 @@
+package root
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"ws-game/ai"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config contains the gameplay settings of the server. It is loaded from a yaml file, every value
+// can be overwritten by an environment variable named after its yaml path, e.g. world.gridCellSize
+// -> WS_GAME_WORLD_GRID_CELL_SIZE. Values missing in the file keep their defaults.
+type Config struct {
+	World      WorldConfig      `yaml:"world"`
+	Player     PlayerConfig     `yaml:"player"`
+	Npc        NpcConfig        `yaml:"npc"`
+	Resources  ResourcesConfig  `yaml:"resources"`
+	Structures StructuresConfig `yaml:"structures"`
+}
+
+type WorldConfig struct {
+	GridCellSize int   `yaml:"gridCellSize"`
+	SubCells     int   `yaml:"subCells"` // sub cells per row of a grid cell, has to divide gridCellSize
+	Seed         int64 `yaml:"seed"`     // terrain, resources, npcs and starter items of a cell only depend on it
+
+	CellUpdateRate time.Duration `yaml:"cellUpdateRate"`
+
+	// players are subscribed to the cells in this distance (in cells) around their own
+	SubscriptionRadius int `yaml:"subscriptionRadius"`
+}
+
+type PlayerConfig struct {
+	Hitpoints  int `yaml:"hitpoints"` // of new players
+	Speed      int `yaml:"speed"`     // max distance a player moves per movement tick
+	LootRange  int `yaml:"lootRange"`
+	BuildRange int `yaml:"buildRange"` // max distance between a player and the structures they place
+
+	// new players and dead players without an own respawn point appear here
+	SpawnX       int           `yaml:"spawnX"`
+	SpawnY       int           `yaml:"spawnY"`
+	RespawnDelay time.Duration `yaml:"respawnDelay"` // time dead players wait before they respawn
+	DeathDrop    float64       `yaml:"deathDrop"`    // part of every resource stack dropped on death, 0 keeps everything
+
+	RegenDelay time.Duration `yaml:"regenDelay"` // time after the last hit before hitpoints regenerate
+	RegenRate  float64       `yaml:"regenRate"`  // part of the max hitpoints regenerated per second, 0 disables it
+
+	Attributes Attributes `yaml:"attributes"` // of every player, equipment adds to them (see stats.go)
+}
+
+// NpcConfig contains the stats of npcs whose archetype doesn't set them (see the bestiary) and the population limits
+type NpcConfig struct {
+	StepSize    int     `yaml:"stepSize"` // max distance per axis an npc walks per step
+	Hitpoints   int     `yaml:"hitpoints"`
+	AttackSpeed int     `yaml:"attackSpeed"` // cell ticks between attacks
+	MinDamage   int     `yaml:"minDamage"`
+	MaxDamage   int     `yaml:"maxDamage"`
+	CritChance  float64 `yaml:"critChance"`
+	AggroRadius int     `yaml:"aggroRadius"` // players closer than this get attacked
+	LeashRange  int     `yaml:"leashRange"`  // chasing a player further away from the spawn makes the npc return
+	AttackRange int     `yaml:"attackRange"` // npcs attack players closer than this
+	StepTicks   int     `yaml:"stepTicks"`   // cell ticks between two steps of a chasing or roaming npc
+	RoamRadius  int     `yaml:"roamRadius"`  // how far wandering and patrolling npcs walk from their spawn
+
+	ProximityThreat float64 `yaml:"proximityThreat"` // threat per second of a player standing right next to a npc
+	ThreatDecay     float64 `yaml:"threatDecay"`     // part of the threat lost per second
+
+	MaxPerCell      int           `yaml:"maxPerCell"`      // cells don't spawn or respawn more npcs than this
+	RespawnCooldown time.Duration `yaml:"respawnCooldown"` // killed npcs come back at their spawn point after it
+
+	PathBudget int `yaml:"pathBudget"` // sub cells the npcs of a cell may search per tick, more searches wait for the next tick
+}
+
+// ResourcesConfig is the number of resources spawned in a new cell that is all land,
+// the biomes of the cell scale it up or down
+type ResourcesConfig struct {
+	MinTrees  int `yaml:"minTrees"`
+	MaxTrees  int `yaml:"maxTrees"`
+	MinStones int `yaml:"minStones"`
+	MaxStones int `yaml:"maxStones"`
+
+	RespawnDelay time.Duration `yaml:"respawnDelay"` // harvested trees and stones regrow after this delay
+}
+
+// StructuresConfig controls the decay of player built structures and who may damage them
+type StructuresConfig struct {
+	DecayDelay    time.Duration `yaml:"decayDelay"`    // time after building or repairing before a structure decays
+	DecayInterval time.Duration `yaml:"decayInterval"` // time between two decay steps
+	DecayDamage   float64       `yaml:"decayDamage"`   // part of the max hitpoints lost per decay step
+	Raidable      bool          `yaml:"raidable"`      // players can damage structures of other players and teams
+}
+
+const configEnvPrefix = "WS_GAME"
+
+func DefaultConfig() Config {
+	return Config{
+		World: WorldConfig{
+			GridCellSize:       1000,
+			SubCells:           20,
+			Seed:               54000,
+			CellUpdateRate:     50 * time.Millisecond,
+			SubscriptionRadius: 2,
+		},
+		Player: PlayerConfig{
+			Hitpoints:  1000,
+			Speed:      18,
+			LootRange:  150,
+			BuildRange: 250,
+
+			SpawnX:       500,
+			SpawnY:       500,
+			RespawnDelay: 10 * time.Second,
+			DeathDrop:    0.5,
+
+			RegenDelay: 5 * time.Second,
+			RegenRate:  0.02,
+
+			Attributes: Attributes{Strength: 5, Agility: 5, Vitality: 5},
+		},
+		Npc: NpcConfig{
+			StepSize:    35,
+			Hitpoints:   25000,
+			AttackSpeed: 15,
+			MinDamage:   20,
+			MaxDamage:   35,
+			CritChance:  0.5,
+			AggroRadius: 150,
+			LeashRange:  500,
+			AttackRange: ai.MeleeRange,
+			StepTicks:   2,
+			RoamRadius:  150,
+
+			ProximityThreat: 10,
+			ThreatDecay:     0.1,
+
+			MaxPerCell:      6,
+			RespawnCooldown: 5 * time.Minute,
+
+			PathBudget: 2000,
+		},
+		Resources: ResourcesConfig{
+			MinTrees:  20,
+			MaxTrees:  50,
+			MinStones: 5,
+			MaxStones: 20,
+
+			RespawnDelay: 5 * time.Minute,
+		},
+		Structures: StructuresConfig{
+			DecayDelay:    24 * time.Hour,
+			DecayInterval: time.Hour,
+			DecayDamage:   0.05,
+			Raidable:      true,
+		},
+	}
+}
+
+// LoadConfig reads the config file at path (optional if empty), applies the environment and validates the result
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, err
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true) // typos should not silently fall back to defaults
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return config, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := applyEnvOverrides(&config, os.LookupEnv); err != nil {
+		return config, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+func applyEnvOverrides(config *Config, lookup func(string) (string, bool)) error {
+	return applyEnv(reflect.ValueOf(config).Elem(), configEnvPrefix, lookup)
+}
+
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		name := prefix + "_" + envName(t.Field(i).Tag.Get("yaml"))
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setConfigValue(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setConfigValue(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config type %s", field.Type())
+	}
+	return nil
+}
+
+// envName turns a yaml key like gridCellSize into GRID_CELL_SIZE
+func envName(key string) string {
+	name := strings.Builder{}
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			name.WriteRune('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+func (c Config) Validate() error {
+	problems := []string{}
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	w := c.World
+	check(w.GridCellSize > 0, "world.gridCellSize must be positive")
+	check(w.SubCells > 0, "world.subCells must be positive")
+	check(w.SubCells > 0 && w.GridCellSize%w.SubCells == 0, "world.subCells has to divide world.gridCellSize")
+	check(w.CellUpdateRate > 0, "world.cellUpdateRate must be positive")
+	check(w.SubscriptionRadius >= 1, "world.subscriptionRadius must be at least 1")
+
+	p := c.Player
+	check(p.Hitpoints > 0, "player.hitpoints must be positive")
+	check(p.Speed > 0, "player.speed must be positive")
+	check(p.LootRange > 0, "player.lootRange must be positive")
+	check(p.BuildRange > 0, "player.buildRange must be positive")
+	check(p.RespawnDelay >= 0, "player.respawnDelay must not be negative")
+	check(p.DeathDrop >= 0 && p.DeathDrop <= 1, "player.deathDrop must be between 0 and 1")
+	check(p.RegenDelay >= 0, "player.regenDelay must not be negative")
+	check(p.RegenRate >= 0 && p.RegenRate <= 1, "player.regenRate must be between 0 and 1")
+	check(p.Attributes.Strength >= 0 && p.Attributes.Agility >= 0 && p.Attributes.Vitality >= 0, "player.attributes must not be negative")
+
+	n := c.Npc
+	check(n.StepSize > 0, "npc.stepSize must be positive")
+	check(n.Hitpoints > 0, "npc.hitpoints must be positive")
+	check(n.AttackSpeed > 0, "npc.attackSpeed must be positive")
+	check(n.MinDamage >= 0 && n.MinDamage <= n.MaxDamage, "npc.minDamage must be between 0 and npc.maxDamage")
+	check(n.CritChance >= 0 && n.CritChance <= 1, "npc.critChance must be between 0 and 1")
+	check(n.AggroRadius > 0, "npc.aggroRadius must be positive")
+	check(n.LeashRange > 0, "npc.leashRange must be positive")
+	check(n.AttackRange >= ai.MeleeRange, "npc.attackRange must be at least %d", ai.MeleeRange)
+	check(n.StepTicks >= 0, "npc.stepTicks must not be negative")
+	check(n.RoamRadius >= 0, "npc.roamRadius must not be negative")
+	check(n.ProximityThreat >= 0, "npc.proximityThreat must not be negative")
+	check(n.ThreatDecay >= 0 && n.ThreatDecay < 1, "npc.threatDecay must be between 0 and 1")
+	check(n.MaxPerCell >= 0, "npc.maxPerCell must not be negative")
+	check(n.RespawnCooldown > 0, "npc.respawnCooldown must be positive")
+	check(n.PathBudget > 0, "npc.pathBudget must be positive")
+
+	r := c.Resources
+	check(r.MinTrees >= 0 && r.MinTrees <= r.MaxTrees, "resources.minTrees must be between 0 and resources.maxTrees")
+	check(r.MinStones >= 0 && r.MinStones <= r.MaxStones, "resources.minStones must be between 0 and resources.maxStones")
+	check(r.RespawnDelay > 0, "resources.respawnDelay must be positive")
+
+	s := c.Structures
+	check(s.DecayDelay >= 0, "structures.decayDelay must not be negative")
+	check(s.DecayInterval >= time.Second, "structures.decayInterval must be at least 1s")
+	check(s.DecayDamage > 0 && s.DecayDamage <= 1, "structures.decayDamage must be between 0 and 1")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
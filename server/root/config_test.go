@@ -0,0 +1,60 @@
+package root
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExampleConfigMatchesDefaults(t *testing.T) {
+	config, err := LoadConfig(filepath.Join("..", "config.example.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, DefaultConfig()) {
+		t.Fatalf("config.example.yaml differs from the defaults:\n%+v\n%+v", config, DefaultConfig())
+	}
+}
+
+func TestConfigFileAndEnvOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("world:\n  cellUpdateRate: 100ms\nnpc:\n  stepSize: 40\n"), 0644)
+	t.Setenv("WS_GAME_NPC_STEP_SIZE", "45")
+	t.Setenv("WS_GAME_NPC_CRIT_CHANCE", "0.25")
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.World.CellUpdateRate != 100*time.Millisecond {
+		t.Errorf("expected cell update rate from file, got %s", config.World.CellUpdateRate)
+	}
+	if config.Npc.StepSize != 45 || config.Npc.CritChance != 0.25 {
+		t.Errorf("expected env to override the file, got %+v", config.Npc)
+	}
+	if config.Player != DefaultConfig().Player {
+		t.Errorf("expected defaults for missing values, got %+v", config.Player)
+	}
+}
+
+func TestInvalidConfigIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	os.WriteFile(path, []byte("world:\n  subCells: 30\n"), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for sub cells that don't divide the cell size")
+	}
+
+	os.WriteFile(path, []byte("world:\n  gridCelSize: 500\n"), 0644)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for unknown keys")
+	}
+
+	t.Setenv("WS_GAME_PLAYER_HITPOINTS", "lots")
+	if _, err := LoadConfig(""); err == nil {
+		t.Error("expected error for invalid env value")
+	}
+}
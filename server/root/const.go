@@ -1,9 +0,0 @@
-package root
-
-const (
-	// Todo: sync these values with frontend
-	StepSize     = 35
-	GridCellSize = 1000
-	SubCells     = 20
-	SubCellSize  = GridCellSize / SubCells
-)
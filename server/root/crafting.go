@@ -0,0 +1,190 @@
+package root
+
+import (
+	"fmt"
+	"strings"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// Recipe turns resources of the players inventory into a resource or an item
+type Recipe struct {
+	Id          string                 `json:"id"`
+	Ingredients []resource.ResourceMin `json:"ingredients"`
+
+	// has to be in loot range of the player, empty if the recipe can be crafted everywhere
+	Station resource.ResourceType `json:"station"`
+
+	// output is either a resource or an item
+	Resource resource.ResourceMin `json:"resource"`
+	Item     item.ItemSubType     `json:"item"`
+}
+
+// recipes are sent to the clients with the game config, the order is the order of the crafting menu
+var recipes = []Recipe{
+	{
+		Id:          "ironIngot",
+		Ingredients: []resource.ResourceMin{{ResourceType: resource.IronOre, Quantity: 2}},
+		Station:     resource.Furnace,
+		Resource:    resource.ResourceMin{ResourceType: resource.IronIngot, Quantity: 1},
+	},
+	{
+		Id:          "axe",
+		Ingredients: []resource.ResourceMin{{ResourceType: resource.Log, Quantity: 3}, {ResourceType: resource.IronIngot, Quantity: 2}},
+		Item:        item.Axe,
+	},
+	{
+		Id:          "hammer",
+		Ingredients: []resource.ResourceMin{{ResourceType: resource.Log, Quantity: 2}, {ResourceType: resource.IronIngot, Quantity: 4}},
+		Item:        item.Hammer,
+	},
+	{
+		Id:          "sword",
+		Ingredients: []resource.ResourceMin{{ResourceType: resource.Log, Quantity: 1}, {ResourceType: resource.IronIngot, Quantity: 5}},
+		Item:        item.Sword,
+	},
+	{
+		Id:          "cookedMeat",
+		Ingredients: []resource.ResourceMin{{ResourceType: resource.Meat, Quantity: 2}},
+		Station:     resource.Furnace,
+		Item:        item.CookedMeat,
+	},
+	{
+		Id:          "healthPotion",
+		Ingredients: []resource.ResourceMin{{ResourceType: resource.Herb, Quantity: 3}},
+		Item:        item.HealthPotion,
+	},
+}
+
+func findRecipe(id string) (Recipe, bool) {
+	for _, recipe := range recipes {
+		if recipe.Id == id {
+			return recipe, true
+		}
+	}
+	return Recipe{}, false
+}
+
+func (h *Hub) HandleCraft(event CraftEvent, c *Client) {
+	recipe, ok := findRecipe(event.RecipeId)
+	if !ok {
+		return
+	}
+
+	if recipe.Station != "" && !h.stationInRange(c.GetPos(), recipe.Station) {
+		return
+	}
+
+	// resolve the item first, a broken recipe must not cost the ingredients
+	var crafted item.Item
+	if recipe.Item != "" {
+		cellPos := h.GridManager.GetCellFromPos(c.GetPos()).Pos
+		crafted, ok = h.craftItem(recipe.Item, cellPos, c.GetPos())
+		if !ok {
+			fmt.Printf("Error: recipe %s crafts item %s the catalog doesn't have\n", recipe.Id, recipe.Item)
+			return
+		}
+	}
+
+	if !c.takeResources(recipe.Ingredients) {
+		return
+	}
+	for _, ingredient := range recipe.Ingredients {
+		c.send <- NewUpdateInventoryEvent(ingredient, true)
+	}
+
+	if recipe.Item == "" {
+		c.addResource(recipe.Resource)
+		c.send <- NewUpdateInventoryEvent(recipe.Resource, false)
+		return
+	}
+
+	if crafted.ItemType == item.Consumable {
+		c.send <- NewUpdateInventoryItemEvent(c.addConsumable(crafted), false)
+		return
+	}
+
+	c.ItemInventoryMutex.Lock()
+	c.ItemInventory = append(c.ItemInventory, crafted)
+	c.ItemInventoryMutex.Unlock()
+
+	c.send <- NewUpdateInventoryItemEvent(crafted, false)
+}
+
+// craftItem creates a consumable or rolls an item of the catalog, ok is false if neither knows subType
+func (h *Hub) craftItem(subType item.ItemSubType, cellPos shared.Vector, pos shared.Vector) (item.Item, bool) {
+	if consumable, ok := item.NewConsumable(subType, cellPos, pos, 1); ok {
+		return consumable, true
+	}
+	return h.items.NewItemOf(shared.GlobalRand, subType, cellPos, ZoneLevel(cellPos.X, cellPos.Y), pos)
+}
+
+// CheckRecipes makes sure every item a recipe crafts is a consumable or has a base in the catalog
+func CheckRecipes(items item.Catalog) error {
+	missing := []string{}
+	for _, recipe := range recipes {
+		if recipe.Item == "" {
+			continue
+		}
+		if _, ok := item.NewConsumable(recipe.Item, shared.Vector{}, shared.Vector{}, 1); ok {
+			continue
+		}
+		if _, ok := items.Bases[recipe.Item]; !ok {
+			missing = append(missing, fmt.Sprintf("%s.item %s", recipe.Id, recipe.Item))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("unknown recipe items: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// stationInRange checks the cell of pos and its neighbours for a resource of type station in loot range
+func (h *Hub) stationInRange(pos shared.Vector, station resource.ResourceType) bool {
+	cellX := floorDiv(pos.X, h.world.GridCellSize)
+	cellY := floorDiv(pos.Y, h.world.GridCellSize)
+
+	for x := cellX - 1; x <= cellX+1; x++ {
+		for y := cellY - 1; y <= cellY+1; y++ {
+			for _, r := range h.GridManager.GetCell(x, y).GetResources() {
+				if r.ResourceType == station && r.Pos.Dist(&pos) < float64(h.config.Player.LootRange) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// takeResources removes the resources from the inventory, nothing is removed if one of them is missing
+func (c *Client) takeResources(resources []resource.ResourceMin) bool {
+	c.ResourceInventoryMutex.Lock()
+	defer c.ResourceInventoryMutex.Unlock()
+
+	for _, r := range resources {
+		if c.ResourceInventory[r.ResourceType].Quantity < r.Quantity {
+			return false
+		}
+	}
+
+	for _, r := range resources {
+		invRes := c.ResourceInventory[r.ResourceType]
+		invRes.Quantity -= r.Quantity
+		c.ResourceInventory[r.ResourceType] = invRes
+	}
+	return true
+}
+
+func (c *Client) addResource(r resource.ResourceMin) {
+	c.ResourceInventoryMutex.Lock()
+	defer c.ResourceInventoryMutex.Unlock()
+
+	invRes, ok := c.ResourceInventory[r.ResourceType]
+	if !ok {
+		invRes = resource.Resource{ResourceType: r.ResourceType}
+	}
+	invRes.Quantity += r.Quantity
+	c.ResourceInventory[r.ResourceType] = invRes
+}
@@ -0,0 +1,77 @@
+package root
+
+import (
+	"strings"
+	"testing"
+	"ws-game/item"
+	"ws-game/resource"
+)
+
+func TestCraftingNeedsIngredientsAndStation(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.IronOre, Quantity: 3})
+
+	// no furnace around
+	hub.HandleCraft(CraftEvent{RecipeId: "ironIngot"}, client)
+	if client.ResourceInventory[resource.IronOre].Quantity != 3 {
+		t.Fatal("crafted without the furnace")
+	}
+
+	cell := hub.GridManager.GetCellFromPos(client.GetPos())
+	furnace := resource.NewResource(resource.Furnace, client.GetPos(), hub.ResourceManager.GetResourceId(), 1, true, 1000, false, cell.GridCellKey)
+	cell.ResourcesMutex.Lock()
+	cell.Resources[furnace.Id] = furnace
+	cell.ResourcesMutex.Unlock()
+
+	hub.HandleCraft(CraftEvent{RecipeId: "ironIngot"}, client)
+	if client.ResourceInventory[resource.IronOre].Quantity != 1 || client.ResourceInventory[resource.IronIngot].Quantity != 1 {
+		t.Fatalf("expected 1 ore and 1 ingot, got %+v", client.ResourceInventory)
+	}
+
+	// only one ore left
+	hub.HandleCraft(CraftEvent{RecipeId: "ironIngot"}, client)
+	if client.ResourceInventory[resource.IronOre].Quantity != 1 || client.ResourceInventory[resource.IronIngot].Quantity != 1 {
+		t.Fatalf("crafted with missing ingredients %+v", client.ResourceInventory)
+	}
+}
+
+func TestCraftingItems(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.Log, Quantity: 3})
+	client.addResource(resource.ResourceMin{ResourceType: resource.IronIngot, Quantity: 2})
+
+	hub.HandleCraft(CraftEvent{RecipeId: "axe"}, client)
+	if len(client.ItemInventory) != 1 || client.ItemInventory[0].ItemSubType != item.Axe {
+		t.Fatalf("expected an axe, got %+v", client.ItemInventory)
+	}
+	if client.ResourceInventory[resource.Log].Quantity != 0 || client.ResourceInventory[resource.IronIngot].Quantity != 0 {
+		t.Fatalf("ingredients were not used %+v", client.ResourceInventory)
+	}
+}
+
+func TestCraftingUnknownItemKeepsIngredients(t *testing.T) {
+	items := item.Default()
+	delete(items.Bases, item.Axe)
+	if err := CheckRecipes(items); err == nil || !strings.Contains(err.Error(), "axe.item axe") {
+		t.Fatalf("expected the axe recipe to be reported, got %v", err)
+	}
+	if err := CheckRecipes(item.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := newTestHub(t)
+	hub.items = items
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.Log, Quantity: 3})
+	client.addResource(resource.ResourceMin{ResourceType: resource.IronIngot, Quantity: 2})
+
+	hub.HandleCraft(CraftEvent{RecipeId: "axe"}, client)
+	if len(client.ItemInventory) != 0 || client.ResourceInventory[resource.Log].Quantity != 3 || client.ResourceInventory[resource.IronIngot].Quantity != 2 {
+		t.Fatalf("expected nothing crafted and the ingredients kept, got %+v and %+v", client.ItemInventory, client.ResourceInventory)
+	}
+}
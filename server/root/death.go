@@ -0,0 +1,172 @@
+package root
+
+import (
+	"time"
+	"ws-game/loot"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// deaths are handed from the cells to the hub, which drops the inventory of the dead player
+const playerDeathsBuffer = 64
+
+func (c *Client) isDead() bool {
+	c.lifeMutex.Lock()
+	defer c.lifeMutex.Unlock()
+	return c.dead
+}
+
+// die marks the client dead until respawnAt, returns false if it already was dead
+func (c *Client) die(respawnAt time.Time) bool {
+	c.lifeMutex.Lock()
+	defer c.lifeMutex.Unlock()
+
+	if c.dead {
+		return false
+	}
+	c.dead = true
+	c.respawnAt = respawnAt
+	return true
+}
+
+func (c *Client) respawnDue(now time.Time) bool {
+	c.lifeMutex.Lock()
+	defer c.lifeMutex.Unlock()
+	return c.dead && !now.Before(c.respawnAt)
+}
+
+func (c *Client) revive() {
+	c.lifeMutex.Lock()
+	c.dead = false
+	c.lifeMutex.Unlock()
+}
+
+// getRespawnPos returns the respawn point the player has set, if any
+func (c *Client) getRespawnPos() (shared.Vector, bool) {
+	c.lifeMutex.Lock()
+	defer c.lifeMutex.Unlock()
+
+	if c.respawnPos == nil {
+		return shared.Vector{}, false
+	}
+	return *c.respawnPos, true
+}
+
+func (c *Client) setRespawnPos(pos shared.Vector) {
+	c.lifeMutex.Lock()
+	c.respawnPos = &pos
+	c.lifeMutex.Unlock()
+}
+
+// killPlayer is called by the cell whose npc dealt the deadly blow
+func (cell *GridCell) killPlayer(c *Client) {
+	delay := c.hub.config.Player.RespawnDelay
+	if !c.die(time.Now().Add(delay)) {
+		return
+	}
+
+	cell.AddEventToBroadcast(NewPlayerDiedEvent(c.Id, c.GetPos(), delay))
+
+	cell.pendingDeathsMutex.Lock()
+	cell.pendingDeaths = append(cell.pendingDeaths, c)
+	cell.pendingDeathsMutex.Unlock()
+	cell.reportDeaths()
+}
+
+// reportDeaths hands the pending deaths to the hub. The cell must never wait for the hub, it might be
+// saving the world and wait for the npcs of this cell, deaths that don't fit are retried next tick.
+func (cell *GridCell) reportDeaths() {
+	cell.pendingDeathsMutex.Lock()
+	defer cell.pendingDeathsMutex.Unlock()
+
+	for len(cell.pendingDeaths) > 0 {
+		c := cell.pendingDeaths[0]
+		select {
+		case c.hub.playerDeaths <- c:
+			cell.pendingDeaths = cell.pendingDeaths[1:]
+		default:
+			return
+		}
+	}
+}
+
+// dropOnDeath drops the configured part of every resource stack of a dead player as loot
+func (h *Hub) dropOnDeath(c *Client) {
+	if h.config.Player.DeathDrop <= 0 {
+		return
+	}
+
+	drops := []loot.Drop{}
+	c.ResourceInventoryMutex.Lock()
+	for resourceType, r := range c.ResourceInventory {
+		quantity := int(float64(r.Quantity) * h.config.Player.DeathDrop)
+		if quantity <= 0 {
+			continue
+		}
+
+		r.Quantity -= quantity
+		c.ResourceInventory[resourceType] = r
+		drops = append(drops, loot.Drop{Resource: resourceType, Quantity: quantity})
+	}
+	c.ResourceInventoryMutex.Unlock()
+
+	for _, drop := range drops {
+		c.trySend(NewUpdateInventoryEvent(resource.ResourceMin{ResourceType: drop.Resource, Quantity: drop.Quantity}, true))
+	}
+
+	pos := c.GetPos()
+	h.spawnDrops(drops, pos, h.GridManager.GetCellFromPos(pos).GridCellKey)
+}
+
+// respawnPlayers brings back the dead players whose respawn delay is over
+func (h *Hub) respawnPlayers(now time.Time) {
+	for _, c := range h.clientList() {
+		if c.isLoggedIn() && c.respawnDue(now) {
+			h.respawnPlayer(c)
+		}
+	}
+}
+
+// respawnPos is the respawn point of the player or the spawn of the server
+func (h *Hub) respawnPos(c *Client) shared.Vector {
+	pos, ok := c.getRespawnPos()
+	if !ok {
+		pos = shared.Vector{X: h.config.Player.SpawnX, Y: h.config.Player.SpawnY}
+	}
+	return h.world.nearestPassable(pos)
+}
+
+func (h *Hub) respawnPlayer(c *Client) {
+	pos := h.respawnPos(c)
+
+	// keys held down when the player died are forgotten
+	c.InputMutex.Lock()
+	c.input = newMovementInput()
+	c.InputMutex.Unlock()
+
+	c.HitpointsMutex.Lock()
+	c.Hitpoints.Current = c.Hitpoints.Max
+	hitpoints := c.Hitpoints
+	c.HitpointsMutex.Unlock()
+
+	c.SetPos(pos)
+	c.revive()
+
+	// the old cell tells everyone who saw the player die, the grid manager moves the player to the new cell
+	c.getGridCell().AddEventToBroadcast(NewPlayerRespawnedEvent(c.Id, pos, hitpoints))
+	c.trySend(NewPlayerTargetPositionEvent(pos, c.Id, true))
+
+	select {
+	case h.GridManager.UpdateClientPosition <- c:
+	case <-h.ctx.Done():
+	}
+}
+
+// HandleSetRespawn makes the current position of the player their respawn point
+func (h *Hub) HandleSetRespawn(c *Client) {
+	pos := c.GetPos()
+	if !h.world.terrainPassable(pos) {
+		return
+	}
+	c.setRespawnPos(pos)
+}
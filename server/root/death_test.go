@@ -0,0 +1,101 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func TestPlayerDeathAndRespawn(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.setLoggedIn(true)
+	client.ResourceInventory = map[resource.ResourceType]resource.Resource{
+		resource.Brick: {ResourceType: resource.Brick, Quantity: 10},
+	}
+	hub.SetClient(client)
+
+	cell := client.getGridCell()
+	npc := NewNpc(client.GetPos(), hub.config.Npc)
+	npc.targetedPlayer = client
+	client.Hitpoints.Current = 1
+	(&npcAgent{cell: cell, npc: &npc}).Attack(false)
+
+	if !client.isDead() || client.Hitpoints.Current != 0 {
+		t.Fatalf("expected the player to die, hitpoints %d", client.Hitpoints.Current)
+	}
+	died := false
+	for _, event := range cell.eventsToBroadcast {
+		if _, ok := event.(*PlayerDiedEvent); ok {
+			died = true
+		}
+	}
+	if !died {
+		t.Error("expected PLAYER_DIED to be broadcast")
+	}
+
+	hub.dropOnDeath(<-hub.playerDeaths)
+	if client.ResourceInventory[resource.Brick].Quantity != 5 {
+		t.Errorf("expected half of the bricks to drop, %d left", client.ResourceInventory[resource.Brick].Quantity)
+	}
+
+	// dead players can't act
+	client.SetPos(shared.Vector{X: 1, Y: 1})
+	UnmarshalClientEvents(BaseEvent{EventType: SET_RESPAWN_EVENT}, hub, client)
+	if _, ok := client.getRespawnPos(); ok {
+		t.Error("dead player set a respawn point")
+	}
+
+	hub.respawnPlayers(time.Now())
+	if !client.isDead() {
+		t.Fatal("respawned before the delay")
+	}
+
+	hub.respawnPlayers(time.Now().Add(hub.config.Player.RespawnDelay))
+	spawn := hub.world.nearestPassable(shared.Vector{X: hub.config.Player.SpawnX, Y: hub.config.Player.SpawnY})
+	if client.isDead() || client.Hitpoints.Current != client.Hitpoints.Max || client.GetPos() != spawn {
+		t.Fatalf("expected the player alive at the spawn, at %+v with %d hitpoints", client.GetPos(), client.Hitpoints.Current)
+	}
+}
+
+func TestNpcAttackWithFixedDamage(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.setLoggedIn(true)
+	hub.SetClient(client)
+
+	// min == max used to panic in the cell coroutine
+	npc := NewNpc(client.GetPos(), hub.config.Npc)
+	npc.minDamage, npc.maxDamage, npc.critChance = 30, 30, 0
+	npc.targetedPlayer = client
+
+	hp := client.Hitpoints.Current
+	(&npcAgent{cell: client.getGridCell(), npc: &npc}).Attack(false)
+	if client.Hitpoints.Current != hp-30 {
+		t.Fatalf("expected 30 damage, got %d", hp-client.Hitpoints.Current)
+	}
+}
+
+func TestDeathsWaitForTheHub(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.setLoggedIn(true)
+	hub.SetClient(client)
+
+	for i := 0; i < playerDeathsBuffer; i++ {
+		hub.playerDeaths <- NewClient(hub, nil, hub.getClientId())
+	}
+
+	// the cell must not block on a full buffer, the hub might wait for its npcs
+	cell := client.getGridCell()
+	cell.killPlayer(client)
+
+	for i := 0; i < playerDeathsBuffer; i++ {
+		<-hub.playerDeaths
+	}
+	cell.reportDeaths()
+	if dead := <-hub.playerDeaths; dead != client {
+		t.Fatalf("expected the death of client %d, got %d", client.Id, dead.Id)
+	}
+}
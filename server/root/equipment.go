@@ -0,0 +1,217 @@
+package root
+
+import (
+	"ws-game/item"
+)
+
+// Equipment maps the slots to the uuids of the equipped items, the items stay in the item inventory
+type Equipment map[item.Slot]string
+
+// equipmentChange is sent to the client for every item that got equipped or unequipped
+type equipmentChange struct {
+	uuid     string
+	slot     item.Slot
+	equipped bool
+}
+
+// HandleInventoryItemClick equips the item or unequips it if it already is equipped
+func (h *Hub) HandleInventoryItemClick(uuid string, c *Client) {
+	changes := c.toggleEquipment(uuid)
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, change := range changes {
+		c.send <- NewUpdateEquippedInventoryItemEvent(change.uuid, change.slot, change.equipped)
+	}
+	h.equipmentChanged(c)
+}
+
+func (c *Client) toggleEquipment(uuid string) []equipmentChange {
+	c.EquipmentMutex.Lock()
+	defer c.EquipmentMutex.Unlock()
+	c.ItemInventoryMutex.Lock()
+	defer c.ItemInventoryMutex.Unlock()
+
+	inventory := c.itemsByUUID()
+
+	for slot, equipped := range c.Equipment {
+		if equipped == uuid {
+			delete(c.Equipment, slot)
+			changes := []equipmentChange{{uuid: uuid, slot: slot, equipped: false}}
+			return append(changes, c.dropUnmetRequirements(inventory)...)
+		}
+	}
+
+	newItem, ok := inventory[uuid]
+	if !ok {
+		return nil
+	}
+	slot, ok := newItem.Slot()
+	if !ok {
+		return nil
+	}
+
+	// the items that make room, two handed weapons and off hand items push each other out
+	replaced := []item.Slot{slot}
+	if newItem.TwoHanded() {
+		replaced = append(replaced, item.OffHandSlot)
+	}
+	if mainHand, ok := inventory[c.Equipment[item.MainHandSlot]]; ok && slot == item.OffHandSlot && mainHand.TwoHanded() {
+		replaced = append(replaced, item.MainHandSlot)
+	}
+
+	remaining := Equipment{}
+	for s, equipped := range c.Equipment {
+		remaining[s] = equipped
+	}
+	for _, s := range replaced {
+		delete(remaining, s)
+	}
+	if !requirementsMet(newItem, c.attributesWith(remaining, inventory)) {
+		return nil
+	}
+
+	changes := []equipmentChange{}
+	for _, s := range replaced {
+		if equipped, ok := c.Equipment[s]; ok {
+			delete(c.Equipment, s)
+			changes = append(changes, equipmentChange{uuid: equipped, slot: s, equipped: false})
+		}
+	}
+	c.Equipment[slot] = uuid
+	changes = append(changes, equipmentChange{uuid: uuid, slot: slot, equipped: true})
+
+	return append(changes, c.dropUnmetRequirements(inventory)...)
+}
+
+// dropUnmetRequirements unequips items whose requirements were only met by the boni of items that are gone now
+func (c *Client) dropUnmetRequirements(inventory map[string]item.Item) []equipmentChange {
+	changes := []equipmentChange{}
+
+	for dropped := true; dropped; {
+		dropped = false
+		for _, slot := range item.Slots {
+			uuid, ok := c.Equipment[slot]
+			if !ok {
+				continue
+			}
+
+			others := Equipment{}
+			for s, equipped := range c.Equipment {
+				if s != slot {
+					others[s] = equipped
+				}
+			}
+			if !requirementsMet(inventory[uuid], c.attributesWith(others, inventory)) {
+				delete(c.Equipment, slot)
+				changes = append(changes, equipmentChange{uuid: uuid, slot: slot, equipped: false})
+				dropped = true
+			}
+		}
+	}
+	return changes
+}
+
+// validateEquipment removes slots with items that aren't in the inventory or don't fit, e.g. from old saves
+func (c *Client) validateEquipment() {
+	c.EquipmentMutex.Lock()
+	defer c.EquipmentMutex.Unlock()
+	c.ItemInventoryMutex.Lock()
+	defer c.ItemInventoryMutex.Unlock()
+
+	if c.Equipment == nil {
+		c.Equipment = Equipment{}
+	}
+	inventory := c.itemsByUUID()
+
+	for slot, uuid := range c.Equipment {
+		equipped, ok := inventory[uuid]
+		if !ok {
+			delete(c.Equipment, slot)
+			continue
+		}
+		if itemSlot, ok := equipped.Slot(); !ok || itemSlot != slot {
+			delete(c.Equipment, slot)
+		}
+	}
+	if mainHand, ok := inventory[c.Equipment[item.MainHandSlot]]; ok && mainHand.TwoHanded() {
+		delete(c.Equipment, item.OffHandSlot)
+	}
+
+	c.dropUnmetRequirements(inventory)
+}
+
+// equipmentFromList puts the items of the free-form list saves had before slots existed into their slots,
+// the first item of a slot wins
+func equipmentFromList(uuids []string, items []item.Item) Equipment {
+	equipment := Equipment{}
+	for _, uuid := range uuids {
+		for _, i := range items {
+			if i.UUID != uuid {
+				continue
+			}
+			if slot, ok := i.Slot(); ok && equipment[slot] == "" {
+				equipment[slot] = uuid
+			}
+		}
+	}
+	return equipment
+}
+
+func (c *Client) getEquipment() Equipment {
+	c.EquipmentMutex.Lock()
+	defer c.EquipmentMutex.Unlock()
+
+	equipment := make(Equipment, len(c.Equipment))
+	for slot, uuid := range c.Equipment {
+		equipment[slot] = uuid
+	}
+	return equipment
+}
+
+// equippedItems returns the items of equipment in slot order, the item inventory has to be locked
+func (c *Client) equippedItems(equipment Equipment, inventory map[string]item.Item) []item.Item {
+	items := []item.Item{}
+	for _, slot := range item.Slots {
+		if equipped, ok := inventory[equipment[slot]]; ok {
+			items = append(items, equipped)
+		}
+	}
+	return items
+}
+
+// attributesWith are the attributes of the player wearing equipment
+func (c *Client) attributesWith(equipment Equipment, inventory map[string]item.Item) Attributes {
+	player := c.hub.config.Player
+	return calculateStats(player.Attributes, player.Hitpoints, c.equippedItems(equipment, inventory)).Attributes
+}
+
+// itemsByUUID indexes the item inventory, the item inventory has to be locked
+func (c *Client) itemsByUUID() map[string]item.Item {
+	items := make(map[string]item.Item, len(c.ItemInventory))
+	for _, i := range c.ItemInventory {
+		items[i.UUID] = i
+	}
+	return items
+}
+
+func requirementsMet(i item.Item, attributes Attributes) bool {
+	for _, requirement := range i.Requirements {
+		switch requirement.Attribute {
+		case item.Strength:
+			if attributes.Strength < requirement.Value {
+				return false
+			}
+		case item.Agility:
+			if attributes.Agility < requirement.Value {
+				return false
+			}
+		case item.Vitality:
+			if attributes.Vitality < requirement.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
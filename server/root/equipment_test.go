@@ -0,0 +1,111 @@
+package root
+
+import (
+	"testing"
+	"ws-game/item"
+	"ws-game/shared"
+)
+
+func newEquipmentTestClient(t *testing.T, items ...item.Item) *Client {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ItemInventory = items
+	return client
+}
+
+func newTestItem(t *testing.T, subType item.ItemSubType, boni ...item.Boni) item.Item {
+	i, ok := item.Default().NewItemOf(shared.GlobalRand, subType, shared.Vector{}, 1, shared.Vector{})
+	if !ok {
+		t.Fatalf("the item catalog has no %s", subType)
+	}
+	i.Boni = boni
+	// the tests set the requirements they check
+	i.Requirements = []item.Boni{}
+	return i
+}
+
+func TestEquipmentSlots(t *testing.T) {
+	sword := newTestItem(t, item.Sword)
+	axe := newTestItem(t, item.Axe)
+	hammer := newTestItem(t, item.Hammer)
+	shield := newTestItem(t, item.Shield)
+	potion, _ := item.NewConsumable(item.HealthPotion, shared.Vector{}, shared.Vector{}, 1)
+	client := newEquipmentTestClient(t, sword, axe, hammer, shield, potion)
+
+	client.toggleEquipment(sword.UUID)
+	changes := client.toggleEquipment(axe.UUID)
+	if client.Equipment[item.MainHandSlot] != axe.UUID || len(changes) != 2 || changes[0].uuid != sword.UUID || changes[0].equipped {
+		t.Fatalf("expected the axe to replace the sword, got %+v and %+v", client.Equipment, changes)
+	}
+	if stats := client.updateStats(); stats.MinDamage != calculateStats(client.hub.config.Player.Attributes, 0, []item.Item{axe}).MinDamage {
+		t.Errorf("damage of the swapped out sword still counts %+v", stats)
+	}
+
+	// a two handed weapon pushes out the shield and the other way round
+	client.toggleEquipment(shield.UUID)
+	client.toggleEquipment(hammer.UUID)
+	if client.Equipment[item.MainHandSlot] != hammer.UUID || client.Equipment[item.OffHandSlot] != "" {
+		t.Fatalf("expected only the hammer, got %+v", client.Equipment)
+	}
+	client.toggleEquipment(shield.UUID)
+	if client.Equipment[item.MainHandSlot] != "" || client.Equipment[item.OffHandSlot] != shield.UUID {
+		t.Fatalf("expected only the shield, got %+v", client.Equipment)
+	}
+
+	// clicking an equipped item unequips it
+	client.toggleEquipment(shield.UUID)
+	if len(client.Equipment) != 0 {
+		t.Fatalf("expected nothing equipped, got %+v", client.Equipment)
+	}
+
+	if changes := client.toggleEquipment(potion.UUID); changes != nil {
+		t.Error("equipped a potion")
+	}
+	if changes := client.toggleEquipment("not-in-the-inventory"); changes != nil {
+		t.Error("equipped an item that isn't in the inventory")
+	}
+}
+
+func TestEquipmentRequirements(t *testing.T) {
+	base := DefaultConfig().Player.Attributes.Strength
+
+	sword := newTestItem(t, item.Sword)
+	sword.Requirements = []item.Boni{{Attribute: item.Strength, Value: base + 10}}
+	ring := newTestItem(t, item.Ring, item.Boni{Attribute: item.Strength, Value: 10})
+	client := newEquipmentTestClient(t, sword, ring)
+
+	if changes := client.toggleEquipment(sword.UUID); changes != nil {
+		t.Fatal("equipped the sword without the strength")
+	}
+
+	client.toggleEquipment(ring.UUID)
+	client.toggleEquipment(sword.UUID)
+	if client.Equipment[item.MainHandSlot] != sword.UUID {
+		t.Fatalf("expected the ring to enable the sword, got %+v", client.Equipment)
+	}
+
+	// the sword needs the ring
+	changes := client.toggleEquipment(ring.UUID)
+	if len(client.Equipment) != 0 || len(changes) != 2 {
+		t.Fatalf("expected both items to be unequipped, got %+v and %+v", client.Equipment, changes)
+	}
+}
+
+func TestEquipmentFromOldSaves(t *testing.T) {
+	sword := newTestItem(t, item.Sword)
+	axe := newTestItem(t, item.Axe)
+	helmet := newTestItem(t, item.Helmet)
+
+	// the first item of a slot wins, gone items are ignored
+	equipment := equipmentFromList([]string{sword.UUID, axe.UUID, helmet.UUID, "gone"}, []item.Item{sword, axe, helmet})
+	if len(equipment) != 2 || equipment[item.MainHandSlot] != sword.UUID || equipment[item.HeadSlot] != helmet.UUID {
+		t.Fatalf("unexpected equipment %+v", equipment)
+	}
+
+	client := newEquipmentTestClient(t, sword)
+	client.Equipment = Equipment{item.MainHandSlot: sword.UUID, item.HeadSlot: helmet.UUID, item.RingSlot: sword.UUID}
+	client.validateEquipment()
+	if len(client.Equipment) != 1 || client.Equipment[item.MainHandSlot] != sword.UUID {
+		t.Fatalf("expected only the sword to stay, got %+v", client.Equipment)
+	}
+}
@@ -2,6 +2,7 @@ package root
 
 import (
 	"encoding/json"
+	"time"
 	"ws-game/item"
 	"ws-game/resource"
 	"ws-game/shared"
@@ -39,8 +40,47 @@ const (
 	UPDATE_INVENTORY_ITEM_EVENT          EventType = 26
 	PLAYER_CLICKED_INEVNTORY_ITEM_EVENT  EventType = 27
 	UPDATE_EQUIPPED_INVENTORY_ITEM_EVENT EventType = 28
+	LOGIN_FAILED_EVENT                   EventType = 29
+	WIRE_SCHEMA_EVENT                    EventType = 30
+	CELL_STATE_EVENT                     EventType = 31
+	STATE_ACK_EVENT                      EventType = 32
+	CRAFT_EVENT                          EventType = 33
+	REPAIR_EVENT                         EventType = 34
+	PLAYER_DIED_EVENT                    EventType = 35
+	PLAYER_RESPAWNED_EVENT               EventType = 36
+	SET_RESPAWN_EVENT                    EventType = 37
+	USE_ITEM_EVENT                       EventType = 38
+	PLAYER_STATS_EVENT                   EventType = 39
 )
 
+// events the server sends, the binary wire format is generated from these structs (see wire.go)
+var outboundEvents = map[EventType]interface{}{
+	NEW_USER_EVENT:                       NewPlayerEvent{},
+	USER_INIT_EVENT:                      UserInitEvent{},
+	PLAYER_TARGET_POSITION_EVENT:         PlayerTargetPositionEvent{},
+	RESOURCE_POSITIONS_EVENT:             ResourcePositionsEvent{},
+	REMOVE_PLAYER_EVENT:                  RemovePlayerEvent{},
+	UPDATE_RESOURCE_EVENT:                UpdateResourceEvent{},
+	UPDATE_INVENTORY_EVENT:               UpdateInventoryEvent{},
+	REMOVE_GRID_CELL:                     RemoveGridCellEvent{},
+	MULTIPLE_EVENTS:                      MultipleEvents{},
+	CELL_DATA_EVENT:                      CellDataEvent{},
+	NPC_LIST_EVENT:                       NpcListEvent{},
+	NPC_TARGET_POSITION_EVENT:            NpcTagetPositionEvent{},
+	UPDATE_NPC_EVENT:                     UpdateNpcEvent{},
+	UPDATE_PLAYER_EVENT:                  UpdatePlayerEvent{},
+	NPC_ATTACK_ANIM_EVENT:                NpcAttackAnimEvent{},
+	ITEM_POSITIONS_EVENT:                 ItemPositionsEvent{},
+	REMOVE_ITEM_EVENT:                    RemoveItemEvent{},
+	UPDATE_INVENTORY_ITEM_EVENT:          UpdateInventoryItemEvent{},
+	UPDATE_EQUIPPED_INVENTORY_ITEM_EVENT: UpdateEquippedInventoryItemEvent{},
+	LOGIN_FAILED_EVENT:                   LoginFailedEvent{},
+	CELL_STATE_EVENT:                     CellStateEvent{},
+	PLAYER_DIED_EVENT:                    PlayerDiedEvent{},
+	PLAYER_RESPAWNED_EVENT:               PlayerRespawnedEvent{},
+	PLAYER_STATS_EVENT:                   PlayerStatsEvent{},
+}
+
 const (
 	PLAYER_LOGIN_EVENT EventType = 15
 )
@@ -57,44 +97,60 @@ func GetNewPlayerEvent(id int, pos shared.Vector, hitpoints shared.Hitpoints) in
 }
 
 type GameConfig struct {
-	GridCellSize   int `json:"gridCellSize"`
-	SubCells       int `json:"subCells"`
-	PlayerStepSize int `json:"playerStepSize"`
-	SubCellSize    int `json:"subCellSize"`
+	GridCellSize int `json:"gridCellSize"`
+	SubCells     int `json:"subCells"`
+	SubCellSize  int `json:"subCellSize"`
+	// distance a player moves per movement tick, used by the client to predict its own movement
+	PlayerSpeed  int `json:"playerSpeed"`
+	MovementTick int `json:"movementTick"` // in ms
+	// speed multiplier per terrain, 0 is impassable
+	TerrainSpeed map[TerrainType]float64 `json:"terrainSpeed"`
+	// max distance to loot and hit resources
+	LootRange  int         `json:"lootRange"`
+	Recipes    []Recipe    `json:"recipes"`
+	Buildables []Buildable `json:"buildables"`
 }
 
 type UserInitEvent struct {
-	EventType     EventType              `json:"eventType"`
-	Id            int                    `json:"id"`
-	Pos           shared.Vector          `json:"pos"`
-	Hitpoints     shared.Hitpoints       `json:"hitpoints"`
-	UUID          string                 `json:"uuid"`
-	GameConfig    GameConfig             `json:"gameConfig"`
-	Resources     []resource.ResourceMin `json:"resources"`
-	Items         []item.Item            `json:"items"`
-	EquippedItems []string               `json:"equippedItems"`
+	EventType  EventType              `json:"eventType"`
+	Id         int                    `json:"id"`
+	Pos        shared.Vector          `json:"pos"`
+	Hitpoints  shared.Hitpoints       `json:"hitpoints"`
+	UUID       string                 `json:"uuid" wire:"uuid"`
+	GameConfig GameConfig             `json:"gameConfig"`
+	Resources  []resource.ResourceMin `json:"resources"`
+	Items      []item.Item            `json:"items"`
+	Equipment  Equipment              `json:"equipment" wire:"uuid"`
+	Stats      Stats                  `json:"stats"`
 }
 
 func NewUserInitEvent(client *Client, config GameConfig) interface{} {
 	resources := []resource.ResourceMin{}
 
+	client.ResourceInventoryMutex.Lock()
 	for _, entry := range client.ResourceInventory {
 		resources = append(resources, resource.ResourceMin{
 			Quantity:     entry.Quantity,
 			ResourceType: entry.ResourceType,
 		})
 	}
+	client.ResourceInventoryMutex.Unlock()
+
+	client.ItemInventoryMutex.Lock()
+	items := append([]item.Item{}, client.ItemInventory...)
+	client.ItemInventoryMutex.Unlock()
 
 	return &UserInitEvent{
-		EventType:     USER_INIT_EVENT,
-		Id:            client.Id,
-		Pos:           client.Pos,
-		Hitpoints:     client.Hitpoints,
-		UUID:          client.UUID,
-		GameConfig:    config,
-		Resources:     resources,
-		Items:         client.ItemInventory,
-		EquippedItems: client.EquippedItems,
+		EventType:  USER_INIT_EVENT,
+		Id:         client.Id,
+		Pos:        client.GetPos(),
+		Hitpoints:  client.getHitpoints(),
+		UUID:       client.UUID,
+		GameConfig: config,
+		Resources:  resources,
+		Items:      items,
+		Equipment:  client.getEquipment(),
+		Stats:      client.getStats(),
 	}
 }
 
@@ -142,7 +198,7 @@ func NewItemPositionsEvent(items []item.Item, gridCellKey string) interface{} {
 
 type RemoveItemEvent struct {
 	EventType EventType `json:"eventType"`
-	UUID      string    `json:"uuid"`
+	UUID      string    `json:"uuid" wire:"uuid"`
 }
 
 func NewRemoveItemEvent(uuid string) RemoveItemEvent {
@@ -254,7 +310,7 @@ func NewMultipleEvents(events []interface{}) interface{} {
 type NpcTagetPositionEvent struct {
 	EventType   EventType     `json:"eventType"`
 	GridCellKey string        `json:"gridCellKey"`
-	NpcUUID     string        `json:"npcUUID"`
+	NpcUUID     string        `json:"npcUUID" wire:"uuid"`
 	Pos         shared.Vector `json:"pos"`
 }
 
@@ -270,7 +326,7 @@ func NewNpcTargetPositionEvent(gridCellKey string, npcUUID string, pos shared.Ve
 type UpdateNpcEvent struct {
 	EventType   EventType        `json:"eventType"`
 	GridCellKey string           `json:"gridCellKey"`
-	NpcUUID     string           `json:"npcUUID"`
+	NpcUUID     string           `json:"npcUUID" wire:"uuid"`
 	Hitpoints   shared.Hitpoints `json:"hitpoints"`
 	Remove      bool             `json:"remove"`
 	Damage      int              `json:"damage"`
@@ -297,7 +353,7 @@ func NewUpdatePlayerEvent(playerId int, hitpoints shared.Hitpoints, damage int,
 
 type NpcAttackAnimEvent struct {
 	EventType EventType `json:"eventType"`
-	NpcUUID   string    `json:"npcUUID"`
+	NpcUUID   string    `json:"npcUUID" wire:"uuid"`
 	AttackID  int       `json:"attackID"`
 }
 
@@ -305,14 +361,55 @@ func NewNpcAttackAnimEvent(npcUUID string, attackID int) interface{} {
 	return &NpcAttackAnimEvent{EventType: NPC_ATTACK_ANIM_EVENT, NpcUUID: npcUUID, AttackID: attackID}
 }
 
+type PlayerDiedEvent struct {
+	EventType EventType     `json:"eventType"`
+	PlayerId  int           `json:"playerId"`
+	Pos       shared.Vector `json:"pos"`
+	RespawnIn int           `json:"respawnIn"` // milliseconds until the player respawns
+}
+
+func NewPlayerDiedEvent(playerId int, pos shared.Vector, respawnIn time.Duration) interface{} {
+	return &PlayerDiedEvent{EventType: PLAYER_DIED_EVENT, PlayerId: playerId, Pos: pos, RespawnIn: int(respawnIn / time.Millisecond)}
+}
+
+type PlayerRespawnedEvent struct {
+	EventType EventType        `json:"eventType"`
+	PlayerId  int              `json:"playerId"`
+	Pos       shared.Vector    `json:"pos"`
+	Hitpoints shared.Hitpoints `json:"hitpoints"`
+}
+
+func NewPlayerRespawnedEvent(playerId int, pos shared.Vector, hitpoints shared.Hitpoints) interface{} {
+	return &PlayerRespawnedEvent{EventType: PLAYER_RESPAWNED_EVENT, PlayerId: playerId, Pos: pos, Hitpoints: hitpoints}
+}
+
+type PlayerStatsEvent struct {
+	EventType EventType `json:"eventType"`
+	Stats     Stats     `json:"stats"`
+}
+
+func NewPlayerStatsEvent(stats Stats) interface{} {
+	return &PlayerStatsEvent{EventType: PLAYER_STATS_EVENT, Stats: stats}
+}
+
 type UpdateEquippedInventoryItemEvent struct {
 	EventType  EventType `json:"eventType"`
-	UUID       string    `json:"uuid"`
+	UUID       string    `json:"uuid" wire:"uuid"`
+	Slot       item.Slot `json:"slot"`
 	IsEquipped bool      `json:"isEquipped"`
 }
 
-func NewUpdateEquippedInventoryItemEvent(uuid string, isEquipped bool) interface{} {
-	return &UpdateEquippedInventoryItemEvent{EventType: UPDATE_EQUIPPED_INVENTORY_ITEM_EVENT, UUID: uuid, IsEquipped: isEquipped}
+func NewUpdateEquippedInventoryItemEvent(uuid string, slot item.Slot, isEquipped bool) interface{} {
+	return &UpdateEquippedInventoryItemEvent{EventType: UPDATE_EQUIPPED_INVENTORY_ITEM_EVENT, UUID: uuid, Slot: slot, IsEquipped: isEquipped}
+}
+
+type LoginFailedEvent struct {
+	EventType EventType `json:"eventType"`
+	Reason    string    `json:"reason"`
+}
+
+func NewLoginFailedEvent(reason string) interface{} {
+	return &LoginFailedEvent{EventType: LOGIN_FAILED_EVENT, Reason: reason}
 }
 
 // Events send from client
@@ -345,9 +442,21 @@ type PlayerPlacedResourceEvent struct {
 	Pos          shared.Vector `json:"pos"`
 }
 
+type CraftEvent struct {
+	RecipeId string `json:"recipeId"`
+}
+
+type RepairEvent struct {
+	Id int `json:"id"`
+}
+
+type UseItemEvent struct {
+	UUID string `json:"uuid"`
+}
+
 type LoginPlayerEvent struct {
 	ResourceType string `json:"resourceType"`
-	UUID         string `json:"uuid"`
+	Token        string `json:"token"` // session token issued by /login
 }
 
 type PlayerClickedItemEvent struct {
@@ -358,3 +467,13 @@ type PlayerClickedItemEvent struct {
 type PlayerClickedInventoryItemEvent struct {
 	UUID string `json:"uuid"`
 }
+
+type StateAck struct {
+	GridCellKey string `json:"gridCellKey"`
+	Seq         int    `json:"seq"`
+}
+
+// the client acks the cell states it applied, batched per frame
+type StateAckEvent struct {
+	Acks []StateAck `json:"acks"`
+}
@@ -2,13 +2,15 @@ package root
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
-	"math"
 	"sync"
 	"time"
+	"ws-game/ai"
+	"ws-game/bestiary"
 	"ws-game/item"
 	"ws-game/resource"
 	"ws-game/shared"
@@ -16,10 +18,6 @@ import (
 	"encoding/base64"
 )
 
-const (
-	CellUpdateRate = time.Millisecond * 50
-)
-
 type GridCell struct {
 	Pos                  shared.Vector
 	GridCellKey          string
@@ -35,6 +33,8 @@ type GridCell struct {
 	Players              map[int]*Client            // players inside this cell atm
 	Resources            map[int]*resource.Resource // resources located in this cell
 	ResourcesMutex       sync.Mutex
+	resourcesSpawned     bool // set by the resource manager after trees/stones got spawned
+	removedResources     chan<- int
 	Items                map[string]*item.Item
 	ItemsMutex           sync.Mutex
 	Broadcast            chan interface{}
@@ -54,12 +54,28 @@ type GridCell struct {
 	ItemsToRemoveMutex     sync.Mutex
 	Active                 bool
 	ActiveMutex            sync.Mutex
+	ctx                    context.Context // cancelled on shutdown
+	coros                  *sync.WaitGroup
+	config                 Config
+	world                  *World
+	bestiary               bestiary.Bestiary
+	items                  item.Catalog
+	pathfinder             *Pathfinder // nil for cells without a grid manager, npcs walk straight then
+	pathBudget             int         // sub cells the npcs of this cell may still search in this tick
+	npcRespawns            []NpcRespawn // killed npcs waiting to come back
+	npcRespawnsMutex       sync.Mutex
+	pendingDeaths          []*Client // players killed by the npcs of this cell the hub didn't take yet
+	pendingDeathsMutex     sync.Mutex
+	stateSeq               int                      // seq of the last cell state, see cellState.go
+	subscriberStates       map[int]*subscriberState // only used by the cell coroutine
+	stateAcks              []stateAck
+	stateAcksMutex         sync.Mutex
 }
 
-func NewCell(x int, y int) *GridCell {
+func NewCell(x int, y int, config Config, world *World, b bestiary.Bestiary, items item.Catalog) *GridCell {
 
-	subCells := getSubCells(x, y)
-	subCellsBase64 := getCellMiniMapPng(subCells)
+	subCells := world.getSubCells(x, y)
+	subCellsBase64 := getCellMiniMapPng(subCells, world.SubCells)
 
 	cell := &GridCell{
 		Pos:                  shared.Vector{X: x, Y: y},
@@ -95,30 +111,26 @@ func NewCell(x int, y int) *GridCell {
 		ItemsToRemoveMutex:     sync.Mutex{},
 		Active:                 false,
 		ActiveMutex:            sync.Mutex{},
+		ctx:                    context.Background(),
+		coros:                  &sync.WaitGroup{},
+		config:                 config,
+		world:                  world,
+		bestiary:               b,
+		items:                  items,
+		npcRespawns:            []NpcRespawn{},
+		npcRespawnsMutex:       sync.Mutex{},
+		pendingDeaths:          []*Client{},
+		pendingDeathsMutex:     sync.Mutex{},
+		subscriberStates:       make(map[int]*subscriberState),
+		stateAcks:              []stateAck{},
+		stateAcksMutex:         sync.Mutex{},
 	}
 
-	// test npc -> only one per cell atm
-	for i := 0; i < 1; i++ {
-		npcPos := shared.Vector{X: (x * GridCellSize), Y: (y * GridCellSize)}
-		npc := NewNpc(npcPos)
-		cell.NpcList = append(cell.NpcList, npc)
-	}
+	cell.NpcList = append(cell.NpcList, world.generateNpcs(x, y, subCells, config.Npc, b)...)
 
-	// spawn some items for testing
-	if cell.Pos.X == 0 && cell.Pos.Y == 0 {
-		cell.ItemsMutex.Lock()
-		cellCenter := shared.Vector{X: (x * GridCellSize) + GridCellSize/2, Y: (y * GridCellSize) + GridCellSize/2}
-		for i := 0; i < 5; i++ {
-			// start with center
-			spawnPos := cellCenter.Copy()
-			spawnPos.X += shared.RandIntInRange(-GridCellSize/2, GridCellSize/2)
-			spawnPos.Y += shared.RandIntInRange(-GridCellSize/2, GridCellSize/2)
-
-			item := item.NewItem(cell.Pos, 0, spawnPos)
-			item.Rarity = "unique"
-			cell.Items[item.UUID] = &item
-		}
-		cell.ItemsMutex.Unlock()
+	for _, i := range world.generateItems(x, y, items) {
+		i := i
+		cell.Items[i.UUID] = &i
 	}
 
 	return cell
@@ -132,33 +144,54 @@ func (c *GridCell) SpawnItem(pos shared.Vector) {
 	pos.X += shared.RandIntInRange(-r, r)
 	pos.Y += shared.RandIntInRange(-r, r)
 
-	c.ItemsToAdd = append(c.ItemsToAdd, item.NewItem(c.Pos, 0, pos))
+	c.ItemsToAdd = append(c.ItemsToAdd, c.items.NewItem(c.Pos, ZoneLevel(c.Pos.X, c.Pos.Y), pos))
+}
+
+// hitNpc applies the damage of client to the npc with uuid, found is false if the npc isn't in this cell
+func (cell *GridCell) hitNpc(uuid string, client *Client) (Npc, bool) {
+	cell.NpcListMutex.Lock()
+	defer cell.NpcListMutex.Unlock()
+
+	for npcIndex, npc := range cell.NpcList {
+		if npc.UUID != uuid {
+			continue
+		}
+
+		damage, isCrit := client.DamageRoll()
+
+		npc.Hitpoints.Current -= damage
+		npc.threat.add(client, float64(damage))
+		remove := npc.Hitpoints.Current <= 0
+		if remove {
+			npc.SetRemove(true)
+		}
+
+		cell.NpcList[npcIndex] = npc
+		cell.broadcast(NewUpdateNpcEvent(npc.UUID, npc.Hitpoints.Current, npc.Hitpoints.Max, remove, cell.GridCellKey, damage, isCrit))
+		return npc, true
+	}
+
+	return Npc{}, false
 }
 
-func getCellMiniMapPng(subCells []SubCell) string {
+func getCellMiniMapPng(subCells []SubCell, size int) string {
 
-	var img = image.NewRGBA(image.Rect(0, 0, SubCells, SubCells))
+	var img = image.NewRGBA(image.Rect(0, 0, size, size))
 
 	hue := uint8(255)
 
-	water := color.RGBA{0, 98, 168, hue}
-	shallowWater := color.RGBA{67, 199, 247, hue}
-	grass := color.RGBA{99, 171, 63, hue}
-	sand := color.RGBA{255, 255, 0, hue}
+	colors := map[TerrainType]color.RGBA{
+		Water:        {0, 98, 168, hue},
+		ShallowWater: {67, 199, 247, hue},
+		Grass:        {99, 171, 63, hue},
+		Sand:         {255, 255, 0, hue},
+		Rock:         {128, 118, 110, hue},
+		Mud:          {94, 84, 46, hue},
+		Snow:         {240, 245, 250, hue},
+	}
 
 	for _, subCell := range subCells {
-		if subCell.TerrainType == "Water" {
-			img.Set(subCell.Pos.X, subCell.Pos.Y, water)
-		}
-		if subCell.TerrainType == "Grass" {
-			img.Set(subCell.Pos.X, subCell.Pos.Y, grass)
-		}
-		if subCell.TerrainType == "ShallowWater" {
-			img.Set(subCell.Pos.X, subCell.Pos.Y, shallowWater)
-		}
-		if subCell.TerrainType == "Sand" {
-			img.Set(subCell.Pos.X, subCell.Pos.Y, sand)
-		}
+		img.Set(subCell.Pos.X, subCell.Pos.Y, colors[subCell.TerrainType])
 	}
 
 	buff := new(bytes.Buffer)
@@ -272,183 +305,25 @@ func (c *GridCell) AddQueuedItems() {
 
 }
 
+// UpdateNpc ticks the behavior tree of the npc, see npcAgent for what the npc can do
 func (cell *GridCell) UpdateNpc(index int, npc Npc) {
 	defer func() {
 		cell.NpcList[index] = npc
 	}()
 
-	var player *Client = npc.targetedPlayer
-	minDistToAttack := 75.0
-
-	if player != nil && !player.getConnected() {
-		npc.targetedPlayer = nil
-		return
-	}
-
-	// Example: Player disconncts while npc has him targeted
-	if npc.State != Idle && npc.State != Returning && player == nil {
-		npc.State = Returning
-		return
-	}
-
-	if npc.State == Idle {
-		// either we are in idle with or without a target player
-
-		// see if a player is nearby
-		var smallestDist = math.Inf(1)
-		if player == nil {
-			for _, sub := range cell.playerSubscriptions {
-				subbedPlayerPos := sub.Player.GetPos()
-				dist := subbedPlayerPos.Dist(&npc.Pos)
-				if dist < smallestDist && dist < 150 {
-					player = sub.Player
-					npc.targetedPlayer = player
-					smallestDist = dist
-				}
-			}
-
-			// return early no matter if we found one or not
-			return
-		}
-
-		// we have a target player but still in idle state
-		// check if we are close enough to attack
-
-		playerPos := player.GetPos()
-		if playerPos.Dist(&npc.Pos) < minDistToAttack {
-			// npc reached minimum range to player -> attack
-			// we are close enough -> change to attack state
-			npc.State = Attack
-		} else {
-			// we are to far away -> run to target
-			npc.State = Walk
-		}
-		return
-	}
-
-	if npc.State == Returning {
-		diffX := npc.spawnPos.X - npc.Pos.X
-		diffY := npc.spawnPos.Y - npc.Pos.Y
-
-		step := shared.Vector{
-			X: diffX,
-			Y: diffY,
-		}
-
-		if Abs(diffX) > StepSize {
-			if diffX > 0 {
-				step.X = StepSize
-			} else {
-				step.X = -StepSize
-			}
-		}
-		if Abs(diffY) > StepSize {
-			if diffY > 0 {
-				step.Y = StepSize
-			} else {
-				step.Y = -StepSize
-			}
-		}
-
-		// take step in player direction
-		npc.Pos.Add(step)
-		cell.AddEventToBroadcast(NewNpcTargetPositionEvent(cell.GridCellKey, npc.UUID, npc.Pos))
-
-		// check if npc is back at spawn
-		dist := npc.spawnPos.Dist(&npc.Pos)
-		if dist <= 50 {
-			npc.State = Idle
-		}
-
-		return
-	}
-
-	if npc.State == Walk {
-
-		if npc.movementCooldown > 0 {
-			npc.movementCooldown -= 1
-			return
-		}
-
-		// check if npc walked to far away from his spawn position -> switch to return mode
-		if npc.spawnPos.Dist(&npc.Pos) > 500 {
-			npc.State = Returning
-			npc.Hitpoints.Current = npc.Hitpoints.Max
-
-			cell.AddEventToBroadcast(NewUpdateNpcEvent(npc.UUID, npc.Hitpoints.Current, npc.Hitpoints.Max, false, cell.GridCellKey, 0, false))
+	npc.threat.decay(cell.threatDecayFactor())
+	if npc.targetedPlayer != nil {
+		if _, ok := npc.threat[npc.targetedPlayer.Id]; !ok {
 			npc.targetedPlayer = nil
-			return
-		}
-
-		playerPos := player.GetPos()
-		diffX := playerPos.X - npc.Pos.X
-		diffY := playerPos.Y - npc.Pos.Y
-
-		step := shared.Vector{
-			X: diffX,
-			Y: diffY,
-		}
-
-		// limit if step is to big
-		if Abs(diffX) > StepSize {
-			if diffX > 0 {
-				step.X = StepSize
-			} else {
-				step.X = -StepSize
-			}
-		}
-		if Abs(diffY) > StepSize {
-			if diffY > 0 {
-				step.Y = StepSize
-			} else {
-				step.Y = -StepSize
-			}
-		}
-
-		// take step in player direction
-		npc.Pos.Add(step)
-		cell.AddEventToBroadcast(NewNpcTargetPositionEvent(cell.GridCellKey, npc.UUID, npc.Pos))
-
-		// check if we are close enough to attack
-		if playerPos.Dist(&npc.Pos) < minDistToAttack {
-			npc.State = Attack
-			return
 		}
-
-		npc.State = Idle
-		npc.movementCooldown = 2
-		return
 	}
 
-	// check if we are still in attack range
-	if npc.State == Attack && npc.attackCooldown == 0 {
-		playerPos := player.GetPos()
-		if playerPos.Dist(&npc.Pos) > minDistToAttack {
-			npc.State = Walk
-			return
-		}
+	if npc.brain == nil {
+		npc.brain = ai.NewTree(npc.behavior)
 	}
 
-	if npc.State == Attack {
-		if npc.attackCooldown == 0 {
-			npcDamage := shared.RandIntInRange(20, 35)
-
-			crit := shared.RandIntInRange(1, 100) > int(npc.critChance*100)
-
-			if crit {
-				npcDamage *= 2
-			}
-
-			player.Hitpoints.Current -= npcDamage
-
-			cell.AddEventToBroadcast(NewNpcAttackAnimEvent(npc.UUID, 0))
-			cell.AddEventToBroadcast(NewUpdatePlayerEvent(player.Id, player.Hitpoints, npcDamage, 0, crit))
-
-			npc.attackCooldown = npc.AttackSpeed
-			npc.movementCooldown = 2
-		}
-	}
-	npc.attackCooldown -= 1
+	npc.State = Idle
+	npc.brain.Tick(&npcAgent{cell: cell, npc: &npc})
 }
 
 func (cell *GridCell) NpcUpdates() {
@@ -457,12 +332,16 @@ func (cell *GridCell) NpcUpdates() {
 
 	defer cell.NpcListMutex.Unlock()
 
-	// Remove npcs
-	for i, npc := range cell.NpcList {
+	// Remove npcs, they come back after their cooldown
+	alive := cell.NpcList[:0]
+	for _, npc := range cell.NpcList {
 		if npc.remove {
-			cell.NpcList = append(cell.NpcList[:i], cell.NpcList[i+1:]...)
+			cell.scheduleNpcRespawn(npc, time.Now())
+			continue
 		}
+		alive = append(alive, npc)
 	}
+	cell.NpcList = alive
 
 	// Update Npces
 	for index, npc := range cell.NpcList {
@@ -472,7 +351,7 @@ func (cell *GridCell) NpcUpdates() {
 }
 
 func (cell *GridCell) CellCoro() {
-	ticker := time.NewTicker(CellUpdateRate)
+	ticker := time.NewTicker(cell.config.World.CellUpdateRate)
 	defer ticker.Stop()
 
 	for {
@@ -497,19 +376,17 @@ func (cell *GridCell) CellCoro() {
 					}
 
 					if isConnected {
-						cell.AddEventToBroadcast(GetNewPlayerEvent(player.Id, player.GetPos(), player.Hitpoints))
+						cell.AddEventToBroadcast(GetNewPlayerEvent(player.Id, player.GetPos(), player.getHitpoints()))
 					}
 				}
 
 				newCellSubscription := !cell.CheckSubscription(client)
 				if newCellSubscription && isConnected {
 					// this code is executed if a client subs first time to a cell
-					client.send <- NewResourcePositionsEvent(cell.GetResources())
+					// npcs and resources follow with the keyframe at the end of this tick
 					client.send <- NewItemPositionsEvent(cell.GetItems(), cell.GridCellKey)
 					client.send <- NewCellDataEvent(cell.GridCellKey, cell.SubCells, cell.Pos, cell.SubCellBase64)
-
-					npcs := cell.GetNpcList()
-					client.send <- NewNpcListEvent(cell.GridCellKey, npcs)
+					cell.resetSubscriberState(client.Id)
 				}
 			}
 			// after all sub request have been processed, set to empty array
@@ -575,30 +452,26 @@ func (cell *GridCell) CellCoro() {
 			for _, c := range cell.playersToAdd {
 				cell.Players[c.Id] = c
 				pos := c.GetPos()
-				event := GetNewPlayerEvent(c.Id, pos, c.Hitpoints)
+				event := GetNewPlayerEvent(c.Id, pos, c.getHitpoints())
 				cell.AddEventToBroadcast(event)
 			}
 			cell.playersToAdd = []*Client{}
 			cell.playersToAddMutex.Unlock()
 			cell.CellMutex.Unlock()
 
+			cell.respawnNpcs(time.Now())
+			cell.pathBudget = cell.config.Npc.PathBudget
 			cell.NpcUpdates()
+			cell.reportDeaths()
+			cell.decayStructures(time.Now())
 			cell.AddQueuedItems()
 			// cell.RemoveQueuedItems()
 
 			// broadcast all events at once
-			eventsToBroadcast := cell.GetEventsToBroadcast()
-			if len(eventsToBroadcast) > 0 {
-				event := NewMultipleEvents(eventsToBroadcast)
-				for _, sub := range cell.GetSubscriptions() {
-					if sub.Player.getConnected() {
-						sub.Player.send <- event
-					}
-				}
-				// remove if all done
-				cell.eventsToBroadcast = []interface{}{}
-				// fmt.Printf("%s loop took %dms\n", cell.GridCellKey, time.Since(start).Milliseconds())
-			}
+			cell.broadcastEvents()
+			// after the events, so dead npcs play their animation before the state removes them
+			cell.sendStateUpdates()
+			// fmt.Printf("%s loop took %dms\n", cell.GridCellKey, time.Since(start).Milliseconds())
 
 			cell.ActiveMutex.Lock()
 			if !cell.Active {
@@ -613,10 +486,50 @@ func (cell *GridCell) CellCoro() {
 		*/
 		case event := <-cell.Broadcast:
 			cell.AddEventToBroadcast(event)
+
+		case <-cell.ctx.Done():
+			// server shuts down -> send everything that is still queued
+			cell.flushBroadcast()
+			return
 		}
 	}
 }
 
+func (cell *GridCell) broadcastEvents() {
+	eventsToBroadcast := cell.takeEventsToBroadcast()
+	if len(eventsToBroadcast) == 0 {
+		return
+	}
+
+	event := NewMultipleEvents(eventsToBroadcast)
+	for _, sub := range cell.GetSubscriptions() {
+		if sub.Player.getConnected() {
+			sub.Player.send <- event
+		}
+	}
+}
+
+func (cell *GridCell) flushBroadcast() {
+	for {
+		select {
+		case event := <-cell.Broadcast:
+			cell.AddEventToBroadcast(event)
+		default:
+			cell.broadcastEvents()
+			return
+		}
+	}
+}
+
+// broadcast queues an event for the subscribers of this cell,
+// events are dropped once the cell got stopped by a shutdown
+func (cell *GridCell) broadcast(event interface{}) {
+	select {
+	case cell.Broadcast <- event:
+	case <-cell.ctx.Done():
+	}
+}
+
 /*
 	Subscribe
 */
@@ -632,10 +545,20 @@ func (cell *GridCell) ActiveCheck() {
 	defer cell.ActiveMutex.Unlock()
 	if !cell.Active {
 		cell.Active = true
-		go cell.CellCoro()
+		cell.coros.Add(1)
+		go func() {
+			defer cell.coros.Done()
+			cell.CellCoro()
+		}()
 	}
 }
 
+func (cell *GridCell) isActive() bool {
+	cell.ActiveMutex.Lock()
+	defer cell.ActiveMutex.Unlock()
+	return cell.Active
+}
+
 func (cell *GridCell) AddResource(r *resource.Resource) {
 	cell.ResourcesMutex.Lock()
 	cell.Resources[r.Id] = r
@@ -699,9 +622,11 @@ func (cell *GridCell) AddEventToBroadcast(event interface{}) {
 	cell.eventsToBroadcastMutex.Unlock()
 }
 
-func (cell *GridCell) GetEventsToBroadcast() []interface{} {
+// takeEventsToBroadcast returns the queued events and clears the queue
+func (cell *GridCell) takeEventsToBroadcast() []interface{} {
 	cell.eventsToBroadcastMutex.Lock()
 	events := cell.eventsToBroadcast
+	cell.eventsToBroadcast = []interface{}{}
 	cell.eventsToBroadcastMutex.Unlock()
 	return events
 }
@@ -1,9 +1,12 @@
 package root
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sync"
+	"ws-game/bestiary"
+	"ws-game/item"
 	"ws-game/resource"
 	"ws-game/shared"
 )
@@ -18,46 +21,72 @@ type GridManager struct {
 	initCellChannel      chan *GridCell
 	UpdateClientPosition chan *Client
 	AddResource          chan *resource.Resource
+	RemovedResources     chan int // ids of resources the cells removed on their own, e.g. decayed structures
 	gridMutex            sync.RWMutex
+	config               Config
+	world                *World
+	bestiary             bestiary.Bestiary
+	items                item.Catalog
+	pathfinder           *Pathfinder
+	ctx                  context.Context
+	// cells get stopped before the rest of the hub to flush their pending events
+	cellCtx     context.Context
+	cancelCells context.CancelFunc
+	cellCoros   sync.WaitGroup
 }
 
-func NewGridManager(initCellChannel chan *GridCell) *GridManager {
+func NewGridManager(ctx context.Context, coros *sync.WaitGroup, initCellChannel chan *GridCell, config Config, world *World, b bestiary.Bestiary, items item.Catalog) *GridManager {
+	cellCtx, cancelCells := context.WithCancel(ctx)
+
 	gm := &GridManager{
 		Grid:                 make(map[int]map[int]*GridCell),
 		initCellChannel:      initCellChannel,
 		UpdateClientPosition: make(chan *Client),
 		AddResource:          make(chan *resource.Resource),
+		RemovedResources:     make(chan int, 64),
 		gridMutex:            sync.RWMutex{},
+		config:               config,
+		world:                world,
+		bestiary:             b,
+		items:                items,
+		ctx:                  ctx,
+		cellCtx:              cellCtx,
+		cancelCells:          cancelCells,
+		cellCoros:            sync.WaitGroup{},
 	}
+	gm.pathfinder = NewPathfinder(gm)
 
-	go GridManagerCoro(gm)
+	coros.Add(1)
+	go func() {
+		defer coros.Done()
+		GridManagerCoro(ctx, gm)
+	}()
 
 	return gm
 }
 
-func GridManagerCoro(gm *GridManager) {
+func GridManagerCoro(ctx context.Context, gm *GridManager) {
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case r := <-gm.AddResource:
 			cell := gm.GetCellFromPos(r.Pos)
 			cell.ResourcesMutex.Lock()
 			cell.Resources[r.Id] = r
-			newResources := make(map[int]resource.Resource)
-			newResources[r.Id] = *r
 			cell.ResourcesMutex.Unlock()
-			cell.Broadcast <- NewResourcePositionsEvent(newResources)
+			// subscribers get the resource with the next cell state
 
 		case c := <-gm.UpdateClientPosition:
 			// check if cell changed
 			cPos := c.GetPos()
-			newX := cPos.X / GridCellSize
-			newY := cPos.Y / GridCellSize
+			newCell := gm.world.cellOf(cPos)
 
 			gridCell := gm.GetCellFromPos(cPos)
-			gridCell.Broadcast <- NewPlayerTargetPositionEvent(cPos, c.Id, false)
+			gridCell.broadcast(NewPlayerTargetPositionEvent(cPos, c.Id, false))
 
 			clientCell := c.getGridCell()
-			if newX != clientCell.Pos.X || newY != clientCell.Pos.Y || c.NeedsInit {
+			if newCell != clientCell.Pos || c.NeedsInit {
 				gm.clientMovedCell(clientCell, gridCell, c)
 				c.NeedsInit = false
 			}
@@ -65,11 +94,8 @@ func GridManagerCoro(gm *GridManager) {
 	}
 }
 func (gm *GridManager) GetCellFromPos(clientPos shared.Vector) *GridCell {
-	// get x and y from pos by concidering the grid cell size
-	x := clientPos.X / GridCellSize
-	y := clientPos.Y / GridCellSize
-
-	return gm.GetCell(x, y)
+	cellPos := gm.world.cellOf(clientPos)
+	return gm.GetCell(cellPos.X, cellPos.Y)
 }
 
 func (gm *GridManager) ActiveCells() string {
@@ -100,19 +126,19 @@ func (gm *GridManager) GetCell(x int, y int) *GridCell {
 
 	if !colOk {
 		cell := gm.add(x, y)
-		cell.ActiveCheck()
+		gm.activate(cell)
 
 		return cell
 	}
 	_, cellOk := col[y]
 	if !cellOk {
 		cell := gm.add(x, y)
-		cell.ActiveCheck()
+		gm.activate(cell)
 
 		return cell
 	}
 	cell := gm.Grid[x][y]
-	cell.ActiveCheck()
+	gm.activate(cell)
 
 	return cell
 
@@ -154,7 +180,7 @@ func (gm *GridManager) clientMovedCell(oldCell *GridCell, newCell *GridCell, c *
 // cells provided to a client entering a new cell
 func (gm *GridManager) getCells(x int, y int) []*GridCell {
 	neighbourCells := []*GridCell{}
-	area := 2
+	area := gm.config.World.SubscriptionRadius
 	for xOffset := -area; xOffset <= area; xOffset++ {
 		for yOffset := -area; yOffset <= area; yOffset++ {
 			xIdx := x + xOffset
@@ -170,11 +196,53 @@ func (gm *GridManager) getCells(x int, y int) []*GridCell {
 	return neighbourCells
 }
 
+// StopCells stops all cell coroutines and waits until their pending events are sent
+func (gm *GridManager) StopCells() {
+	// cells are activated under the grid mutex, none can start after the cancel
+	gm.gridMutex.Lock()
+	gm.cancelCells()
+	gm.gridMutex.Unlock()
+
+	gm.cellCoros.Wait()
+}
+
+// activate starts the coroutine of cell unless the cells are stopped, gridMutex must be held
+func (gm *GridManager) activate(cell *GridCell) {
+	if gm.cellCtx.Err() != nil {
+		return
+	}
+	cell.ActiveCheck()
+}
+
+// newCell creates a cell whose coroutine is bound to the lifetime of the grid manager
+func (gm *GridManager) newCell(x int, y int) *GridCell {
+	cell := NewCell(x, y, gm.config, gm.world, gm.bestiary, gm.items)
+	cell.ctx = gm.cellCtx
+	cell.coros = &gm.cellCoros
+	cell.removedResources = gm.RemovedResources
+	cell.pathfinder = gm.pathfinder
+	return cell
+}
+
 func (gm *GridManager) add(x int, y int) *GridCell {
-	cell := NewCell(x, y)
+	cell := gm.newCell(x, y)
+	gm.initCell(cell)
+	gm.set(x, y, cell)
 
-	gm.initCellChannel <- cell
+	// gm.InitCellChannel <- cell
+	return cell
+}
+
+// initCell hands cell to the resource manager, which spawns its trees and stones
+func (gm *GridManager) initCell(cell *GridCell) {
+	// the resource manager is gone once the hub shuts down
+	select {
+	case gm.initCellChannel <- cell:
+	case <-gm.ctx.Done():
+	}
+}
 
+func (gm *GridManager) set(x int, y int, cell *GridCell) {
 	col, ok := gm.Grid[x]
 
 	if ok {
@@ -185,11 +253,87 @@ func (gm *GridManager) add(x int, y int) *GridCell {
 		gm.Grid[x] = make(map[int]*GridCell)
 		gm.Grid[x][y] = cell
 	}
+}
+
+// restoreCell adds a cell from a world snapshot,
+// only cells that never got their resources are queued for the resource manager
+func (gm *GridManager) restoreCell(s CellSnapshot) *GridCell {
+	cell := gm.newCell(s.X, s.Y)
+
+	cell.resourcesSpawned = s.ResourcesSpawned
+
+	cell.Items = make(map[string]*item.Item)
+	for i := range s.Items {
+		item := s.Items[i]
+		cell.Items[item.UUID] = &item
+	}
+
+	cell.NpcList = []Npc{}
+	for _, npcSnapshot := range s.Npcs {
+		cell.NpcList = append(cell.NpcList, npcFromSnapshot(npcSnapshot, gm.config.Npc, gm.bestiary))
+	}
+	cell.npcRespawns = append([]NpcRespawn{}, s.NpcRespawns...)
+
+	gm.gridMutex.Lock()
+	gm.set(s.X, s.Y, cell)
+	gm.gridMutex.Unlock()
+
+	if !s.ResourcesSpawned {
+		gm.initCell(cell)
+	}
 
-	// gm.InitCellChannel <- cell
 	return cell
 }
 
+// findCell returns a cell without creating it
+func (gm *GridManager) findCell(x int, y int) (*GridCell, bool) {
+	gm.gridMutex.Lock()
+	defer gm.gridMutex.Unlock()
+
+	cell, ok := gm.Grid[x][y]
+	return cell, ok
+}
+
+// findCellOfKey returns the existing cell with gridCellKey, e.g. the cell a resource belongs to
+func (gm *GridManager) findCellOfKey(gridCellKey string) (*GridCell, bool) {
+	var x, y int
+	if _, err := fmt.Sscanf(gridCellKey, "%d#%d", &x, &y); err != nil {
+		return nil, false
+	}
+	return gm.findCell(x, y)
+}
+
+// cellsAround returns the existing cells that overlap the square of radius around pos
+func (gm *GridManager) cellsAround(pos shared.Vector, radius int) []*GridCell {
+	cells := []*GridCell{}
+	seen := make(map[*GridCell]bool)
+
+	for _, x := range []int{pos.X - radius, pos.X + radius} {
+		for _, y := range []int{pos.Y - radius, pos.Y + radius} {
+			cellPos := gm.world.cellOf(shared.Vector{X: x, Y: y})
+			cell, ok := gm.findCell(cellPos.X, cellPos.Y)
+			if ok && !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return cells
+}
+
+func (gm *GridManager) getAllCells() []*GridCell {
+	gm.gridMutex.Lock()
+	defer gm.gridMutex.Unlock()
+
+	cells := []*GridCell{}
+	for _, col := range gm.Grid {
+		for _, cell := range col {
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
 func (gm *GridManager) GridMap() string {
 	gm.gridMutex.Lock()
 	minY := 0 //int(math.Inf(1))
@@ -1,25 +1,51 @@
 package root
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
+	"ws-game/bestiary"
+	"ws-game/item"
 	"ws-game/shared"
 )
 
-func consumer(c chan shared.Vector) {
+func consumer(c chan *GridCell) {
 	for {
 		x := <-c
-		fmt.Println(x)
+		fmt.Println(x.GridCellKey)
 	}
 }
 
 func TestGridManager(t *testing.T) {
 
-	channel := make(chan shared.Vector)
+	channel := make(chan *GridCell)
 	go consumer(channel)
-	gm := NewGridManager(&channel)
-	pos := gm.GetCellFromPos(shared.Vector{X: 0, Y: 0})
-	fmt.Println(pos)
+	gm := NewGridManager(context.Background(), &sync.WaitGroup{}, channel, DefaultConfig(), NewWorld(DefaultConfig().World), bestiary.Default(), item.Default())
+	cell := gm.GetCellFromPos(shared.Vector{X: 0, Y: 0})
 
-	t.Fail()
+	if cell.Pos.X != 0 || cell.Pos.Y != 0 {
+		t.Errorf("expected cell 0#0 got %s", cell.GridCellKey)
+	}
+}
+
+func TestNegativePositionsAreInTheCellOfTheirTerrain(t *testing.T) {
+	hub := newTestHub(t)
+	pos := shared.Vector{X: -10, Y: -hub.world.GridCellSize - 10}
+
+	cell := hub.GridManager.GetCellFromPos(pos)
+	if cell.Pos.X != -1 || cell.Pos.Y != -2 {
+		t.Fatalf("expected cell -1#-2 for %v, got %s", pos, cell.GridCellKey)
+	}
+
+	// pos is in the last sub cell of the last row
+	subCell := cell.SubCells[len(cell.SubCells)-1]
+	if terrain := hub.world.TerrainAt(pos); terrain != subCell.TerrainType {
+		t.Fatalf("terrain at %v is %s, its cell has %s", pos, terrain, subCell.TerrainType)
+	}
+
+	cells := hub.GridManager.cellsAround(pos, 1)
+	if len(cells) != 1 || cells[0] != cell {
+		t.Fatalf("expected only cell -1#-2 around %v, got %d cells", pos, len(cells))
+	}
 }
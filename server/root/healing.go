@@ -0,0 +1,146 @@
+package root
+
+import (
+	"math"
+	"time"
+	"ws-game/item"
+	"ws-game/shared"
+)
+
+// hitpoints of players regenerate once they were out of combat for config.Player.RegenDelay
+const RegenInterval = time.Second
+
+// damage subtracts amount from the hitpoints of the player, they don't drop below 0
+func (c *Client) damage(amount int, now time.Time) shared.Hitpoints {
+	c.HitpointsMutex.Lock()
+	defer c.HitpointsMutex.Unlock()
+
+	c.Hitpoints.Current -= amount
+	if c.Hitpoints.Current < 0 {
+		c.Hitpoints.Current = 0
+	}
+	c.lastCombat = now
+	return c.Hitpoints
+}
+
+func (c *Client) getHitpoints() shared.Hitpoints {
+	c.HitpointsMutex.Lock()
+	defer c.HitpointsMutex.Unlock()
+	return c.Hitpoints
+}
+
+// heal restores up to amount hitpoints and returns how many were restored, dead players can't be healed
+func (c *Client) heal(amount int) (int, shared.Hitpoints) {
+	c.HitpointsMutex.Lock()
+	defer c.HitpointsMutex.Unlock()
+
+	return c.healLocked(amount)
+}
+
+func (c *Client) healLocked(amount int) (int, shared.Hitpoints) {
+	if c.Hitpoints.Current <= 0 {
+		return 0, c.Hitpoints
+	}
+
+	healed := minInt(amount, c.Hitpoints.Max-c.Hitpoints.Current)
+	if healed < 0 {
+		healed = 0
+	}
+	c.Hitpoints.Current += healed
+	return healed, c.Hitpoints
+}
+
+// enterCombat delays the regeneration, players are in combat when they hit or get hit
+func (c *Client) enterCombat(now time.Time) {
+	c.HitpointsMutex.Lock()
+	c.lastCombat = now
+	c.HitpointsMutex.Unlock()
+}
+
+// regen heals amount if the player left combat at least delay ago
+func (c *Client) regen(now time.Time, delay time.Duration, amount int) (int, shared.Hitpoints) {
+	c.HitpointsMutex.Lock()
+	defer c.HitpointsMutex.Unlock()
+
+	if now.Sub(c.lastCombat) < delay {
+		return 0, c.Hitpoints
+	}
+	return c.healLocked(amount)
+}
+
+// regenPlayers is called every RegenInterval
+func (h *Hub) regenPlayers(now time.Time) {
+	rate := h.config.Player.RegenRate
+	if rate <= 0 {
+		return
+	}
+
+	for _, c := range h.clientList() {
+		if !c.isLoggedIn() || c.isDead() {
+			continue
+		}
+
+		amount := int(math.Ceil(float64(c.getHitpoints().Max) * rate * RegenInterval.Seconds()))
+		healed, hitpoints := c.regen(now, h.config.Player.RegenDelay, amount)
+		if healed > 0 {
+			// no heal amount, the clients would show a number every second
+			c.getGridCell().AddEventToBroadcast(NewUpdatePlayerEvent(c.Id, hitpoints, 0, 0, false))
+		}
+	}
+}
+
+// takeConsumable removes one consumable with uuid from the inventory, the returned stack has the quantity left
+func (c *Client) takeConsumable(uuid string) (item.Item, bool) {
+	c.ItemInventoryMutex.Lock()
+	defer c.ItemInventoryMutex.Unlock()
+
+	for i, inventoryItem := range c.ItemInventory {
+		if inventoryItem.UUID != uuid {
+			continue
+		}
+		if inventoryItem.ItemType != item.Consumable || inventoryItem.Quantity <= 0 {
+			return item.Item{}, false
+		}
+
+		inventoryItem.Quantity--
+		if inventoryItem.Quantity == 0 {
+			c.ItemInventory = append(c.ItemInventory[:i], c.ItemInventory[i+1:]...)
+		} else {
+			c.ItemInventory[i] = inventoryItem
+		}
+		return inventoryItem, true
+	}
+	return item.Item{}, false
+}
+
+// addConsumable adds the consumables to a stack of the same type, returns the updated stack
+func (c *Client) addConsumable(consumable item.Item) item.Item {
+	c.ItemInventoryMutex.Lock()
+	defer c.ItemInventoryMutex.Unlock()
+
+	for i, inventoryItem := range c.ItemInventory {
+		if inventoryItem.ItemType == item.Consumable && inventoryItem.ItemSubType == consumable.ItemSubType {
+			c.ItemInventory[i].Quantity += consumable.Quantity
+			return c.ItemInventory[i]
+		}
+	}
+
+	c.ItemInventory = append(c.ItemInventory, consumable)
+	return consumable
+}
+
+func (h *Hub) HandleUseItem(event UseItemEvent, c *Client) {
+	// full hitpoints, keep the potion
+	if hitpoints := c.getHitpoints(); hitpoints.Current >= hitpoints.Max {
+		return
+	}
+
+	stack, ok := c.takeConsumable(event.UUID)
+	if !ok {
+		return
+	}
+	c.send <- NewUpdateInventoryItemEvent(stack, stack.Quantity == 0)
+
+	healed, hitpoints := c.heal(stack.Heal)
+	c.getGridCell().AddEventToBroadcast(NewUpdatePlayerEvent(c.Id, hitpoints, 0, healed, false))
+}
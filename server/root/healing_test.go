@@ -0,0 +1,71 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/resource"
+)
+
+func TestUseConsumables(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.Herb, Quantity: 6})
+
+	// crafted potions stack
+	hub.HandleCraft(CraftEvent{RecipeId: "healthPotion"}, client)
+	hub.HandleCraft(CraftEvent{RecipeId: "healthPotion"}, client)
+	if len(client.ItemInventory) != 1 || client.ItemInventory[0].Quantity != 2 {
+		t.Fatalf("expected a stack of 2 potions, got %+v", client.ItemInventory)
+	}
+	potion := client.ItemInventory[0]
+
+	// nothing to heal
+	hub.HandleUseItem(UseItemEvent{UUID: potion.UUID}, client)
+	if client.ItemInventory[0].Quantity != 2 {
+		t.Fatal("used a potion with full hitpoints")
+	}
+
+	client.damage(client.Hitpoints.Max-100, time.Now())
+	hub.HandleUseItem(UseItemEvent{UUID: potion.UUID}, client)
+	if client.Hitpoints.Current != 100+potion.Heal || client.ItemInventory[0].Quantity != 1 {
+		t.Fatalf("expected %d hitpoints and 1 potion left, got %d and %+v", 100+potion.Heal, client.Hitpoints.Current, client.ItemInventory)
+	}
+
+	hub.HandleUseItem(UseItemEvent{UUID: potion.UUID}, client)
+	if len(client.ItemInventory) != 0 {
+		t.Fatalf("expected the empty stack to be removed, got %+v", client.ItemInventory)
+	}
+
+	// equipment can't be used
+	sword := hub.items.NewItem(client.getGridCell().Pos, 0, client.GetPos())
+	client.ItemInventory = append(client.ItemInventory, sword)
+	client.damage(100, time.Now())
+	hp := client.Hitpoints.Current
+	hub.HandleUseItem(UseItemEvent{UUID: sword.UUID}, client)
+	if client.Hitpoints.Current != hp || len(client.ItemInventory) != 1 {
+		t.Fatal("used a weapon")
+	}
+}
+
+func TestRegenOutOfCombat(t *testing.T) {
+	hub := newTestHub(t)
+	config := hub.config
+	client := NewClient(hub, nil, hub.getClientId())
+	amount := int(float64(client.Hitpoints.Max) * config.Player.RegenRate)
+
+	now := time.Now()
+	client.damage(500, now)
+	if healed, _ := client.regen(now.Add(config.Player.RegenDelay/2), config.Player.RegenDelay, amount); healed != 0 {
+		t.Fatalf("regenerated %d hitpoints in combat", healed)
+	}
+	if healed, hitpoints := client.regen(now.Add(config.Player.RegenDelay), config.Player.RegenDelay, amount); healed != amount || hitpoints.Current != hitpoints.Max-500+amount {
+		t.Fatalf("expected %d hitpoints to regenerate, got %d", amount, healed)
+	}
+
+	// dead players stay dead
+	client.damage(client.Hitpoints.Max, now)
+	if healed, _ := client.regen(now.Add(time.Hour), config.Player.RegenDelay, amount); healed != 0 {
+		t.Fatal("a dead player regenerated")
+	}
+}
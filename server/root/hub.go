@@ -1,24 +1,33 @@
 package root
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
+	"ws-game/bestiary"
 	"ws-game/item"
+	"ws-game/loot"
 	"ws-game/resource"
 	"ws-game/shared"
 
-	gUUID "github.com/google/uuid"
+	"github.com/gorilla/websocket"
 )
 
 // includes informations that are stored between connect / disconnect
 // - inventory
 // - position
 type ClientPersistance struct {
-	Pos           shared.Vector
-	Inventory     map[resource.ResourceType]resource.Resource
-	ItemInventory []item.Item
-	Hitpoints     shared.Hitpoints
-	EquippedItems []string
+	Pos           shared.Vector                               `json:"pos"`
+	Inventory     map[resource.ResourceType]resource.Resource `json:"inventory"`
+	ItemInventory []item.Item                                 `json:"itemInventory"`
+	Hitpoints     shared.Hitpoints                            `json:"hitpoints"`
+	Equipment     Equipment                                   `json:"equipment"`
+	Team          string                                      `json:"team"` // teams are assigned in the player store for now
+	RespawnPos    *shared.Vector                              `json:"respawnPos,omitempty"`
+
+	// saves from before the equipment slots, see equipmentFromList
+	EquippedItems []string `json:"equippedItems,omitempty"`
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
@@ -27,7 +36,17 @@ type Hub struct {
 	clients     map[int]*Client
 	ClientMutex sync.Mutex
 
-	persistedClientData map[string]ClientPersistance
+	// stores client progress between connects and server restarts
+	playerStore PlayerStore
+
+	// validates the session tokens of PLAYER_LOGIN_EVENT
+	authenticator *Authenticator
+
+	// player uuid to the client that is logged in with it, one connection per account
+	onlinePlayers map[string]*Client
+
+	// stores cells, resources, items and npcs between server restarts
+	worldStore WorldStore
 
 	// Register requests from the clients.
 	register chan *Client
@@ -41,34 +60,77 @@ type Hub struct {
 	idCnt      int
 	idCntMutex sync.Mutex
 
+	config     Config
 	gameConfig GameConfig
+	world      *World
+
+	// what resources and npcs drop
+	lootTables loot.Tables
+	// what random items can be
+	items item.Catalog
+
+	// players killed by npcs, see killPlayer
+	playerDeaths chan *Client
+
+	// cancelling ctx stops all coroutines of the hub, coros is used to wait for them
+	ctx    context.Context
+	cancel context.CancelFunc
+	coros  sync.WaitGroup
 }
 
-const MAX_LOOT_RANGE = 150
+const (
+	// time connected clients get to receive their pending events on shutdown
+	ShutdownClientTimeout = 5 * time.Second
+)
+
+func NewHub(config Config, lootTables loot.Tables, npcTypes bestiary.Bestiary, items item.Catalog, playerStore PlayerStore, worldStore WorldStore, authenticator *Authenticator) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
 
-func NewHub() *Hub {
+	world := NewWorld(config.World)
 
 	hub := &Hub{
-		register:            make(chan *Client),
-		unregister:          make(chan *Client),
-		clients:             make(map[int]*Client),
-		persistedClientData: make(map[string]ClientPersistance),
-		ClientMutex:         sync.Mutex{},
-		idCnt:               0,
-		idCntMutex:          sync.Mutex{},
+		ctx:           ctx,
+		cancel:        cancel,
+		coros:         sync.WaitGroup{},
+		register:      make(chan *Client),
+		unregister:    make(chan *Client),
+		clients:       make(map[int]*Client),
+		playerStore:   playerStore,
+		worldStore:    worldStore,
+		authenticator: authenticator,
+		onlinePlayers: make(map[string]*Client),
+		ClientMutex:   sync.Mutex{},
+		idCnt:         0,
+		idCntMutex:    sync.Mutex{},
+		config:        config,
+		world:         world,
+		lootTables:    lootTables,
+		items:         items,
+		playerDeaths:  make(chan *Client, playerDeathsBuffer),
 		gameConfig: GameConfig{
-			GridCellSize:   GridCellSize,
-			SubCells:       SubCells,
-			PlayerStepSize: StepSize,
-			SubCellSize:    SubCellSize,
+			GridCellSize: world.GridCellSize,
+			SubCells:     world.SubCells,
+			SubCellSize:  world.SubCellSize,
+			PlayerSpeed:  config.Player.Speed,
+			MovementTick: int(MovementTickRate / time.Millisecond),
+			TerrainSpeed: terrainSpeed,
+			LootRange:    config.Player.LootRange,
+			Recipes:      recipes,
+			Buildables:   buildables,
 		},
 	}
 
 	initCellChannel := make(chan *GridCell)
 
-	gm := NewGridManager(initCellChannel)
+	gm := NewGridManager(ctx, &hub.coros, initCellChannel, config, world, npcTypes, items)
 	hub.GridManager = gm
-	hub.ResourceManager = NewResourceManager(gm, initCellChannel)
+	hub.ResourceManager = NewResourceManager(ctx, &hub.coros, gm, initCellChannel, config.Resources)
+
+	hub.coros.Add(1)
+	go func() {
+		defer hub.coros.Done()
+		MovementCoro(ctx, hub)
+	}()
 
 	return hub
 }
@@ -97,8 +159,22 @@ func (h *Hub) GetClient(id int) *Client {
 }
 
 func (h *Hub) Run() {
+	autosaveTicker := time.NewTicker(PlayerAutosaveInterval)
+	defer autosaveTicker.Stop()
+
+	worldAutosaveTicker := time.NewTicker(WorldAutosaveInterval)
+	defer worldAutosaveTicker.Stop()
+
+	respawnTicker := time.NewTicker(RespawnCheckInterval)
+	defer respawnTicker.Stop()
+
+	regenTicker := time.NewTicker(RegenInterval)
+	defer regenTicker.Stop()
+
 	for {
 		select {
+		case <-h.ctx.Done():
+			return
 		case client := <-h.register:
 			h.SetClient(client)
 		case client := <-h.unregister:
@@ -112,70 +188,97 @@ func (h *Hub) Run() {
 
 				// remove from hub
 				delete(h.clients, client.Id)
+				if h.onlinePlayers[client.UUID] == client {
+					delete(h.onlinePlayers, client.UUID)
+				}
 				close(client.send)
 				client.conn.Close()
-			}
 
-			// store client progress in storage
-			persistanceEntry := ClientPersistance{
-				Pos:           client.Pos,
-				Inventory:     client.ResourceInventory,
-				ItemInventory: client.ItemInventory,
-				Hitpoints:     client.Hitpoints,
-				EquippedItems: client.EquippedItems,
+				// store client progress in storage
+				h.persistClient(client)
 			}
-			h.persistedClientData[client.UUID] = persistanceEntry
-
 			h.ClientMutex.Unlock()
+		case <-autosaveTicker.C:
+			h.autosaveClients()
+		case <-worldAutosaveTicker.C:
+			h.SaveWorld()
+		case <-respawnTicker.C:
+			h.respawnResources(time.Now())
+			h.respawnPlayers(time.Now())
+		case <-regenTicker.C:
+			h.regenPlayers(time.Now())
+		case c := <-h.playerDeaths:
+			h.dropOnDeath(c)
 		}
 	}
 }
 
-func (h *Hub) handleMovementEvent(event KeyBoardEvent, c *Client) {
-	newPos := &shared.Vector{X: c.Pos.X, Y: c.Pos.Y}
-
-	if event.Key == "w" {
-		newPos.Y -= StepSize
-	}
+// Shutdown disconnects all clients with the given reason, persists players and world and stops all coroutines.
+// New connections have to be rejected by the caller before.
+func (h *Hub) Shutdown(reason string) {
+	// stop the cells first, their pending events are queued for the clients before the connections get closed
+	h.GridManager.StopCells()
 
-	if event.Key == "a" {
-		newPos.X -= StepSize
+	h.ClientMutex.Lock()
+	for _, c := range h.clients {
+		c.Close(websocket.CloseGoingAway, reason)
 	}
+	h.ClientMutex.Unlock()
 
-	if event.Key == "s" {
-		newPos.Y += StepSize
+	// clients get persisted by Run when their connection is closed
+	deadline := time.Now().Add(ShutdownClientTimeout)
+	for h.connectedClients() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
 	}
 
-	if event.Key == "d" {
-		newPos.X += StepSize
-	}
+	// store clients that did not disconnect in time
+	h.autosaveClients()
+	h.SaveWorld()
 
-	collision := false
+	h.cancel()
+	h.coros.Wait()
+}
 
-	for _, resource := range c.getGridCell().GetResources() {
+func (h *Hub) connectedClients() int {
+	h.ClientMutex.Lock()
+	defer h.ClientMutex.Unlock()
+	return len(h.clients)
+}
 
-		if resource.GetRemove() {
-			c.GridCell.RemoveResource(&resource)
-			continue
-		}
+func (h *Hub) persistClient(c *Client) {
+	// clients that never logged in have no progress worth storing
+	if !c.isLoggedIn() {
+		return
+	}
 
-		if !resource.IsSolid {
-			continue
-		}
+	if err := h.playerStore.Save(c.UUID, c.getPersistance()); err != nil {
+		fmt.Printf("Error: failed to persist client %s: %s\n", c.UUID, err)
+	}
+}
 
-		if newPos.Dist(&resource.Pos) < 40 {
-			collision = true
-			break
-		}
+func (h *Hub) autosaveClients() {
+	for _, c := range h.clientList() {
+		h.persistClient(c)
 	}
+}
 
-	if !collision {
-		c.SetPos(*newPos)
-		h.GridManager.UpdateClientPosition <- c
+// clientList copies the clients, so they can be processed without holding the client mutex
+func (h *Hub) clientList() []*Client {
+	h.ClientMutex.Lock()
+	defer h.ClientMutex.Unlock()
+
+	clients := make([]*Client, 0, len(h.clients))
+	for _, c := range h.clients {
+		clients = append(clients, c)
 	}
+	return clients
 }
 
 func (h *Hub) HandleResourceHit(event HitResourceEvent, c *Client) {
+	if !c.tryAction(time.Now()) {
+		return
+	}
+
 	r, err := h.ResourceManager.GetResource(event.Id)
 
 	if err != nil {
@@ -186,17 +289,27 @@ func (h *Hub) HandleResourceHit(event HitResourceEvent, c *Client) {
 		return
 	}
 
+	if !h.canDamage(*r, c) {
+		return
+	}
+
 	dist := r.Pos.Dist((&c.Pos))
-	if dist < MAX_LOOT_RANGE {
+	if dist < float64(h.config.Player.LootRange) {
 		damage, isCrit := c.DamageRoll()
 		r.Hitpoints.Current -= damage
 
 		remove := r.Hitpoints.Current <= 0
 		cellToBroadCast := h.GridManager.GetCellFromPos(r.Pos)
-		cellToBroadCast.Broadcast <- NewUpdateResourceEvent(r.Id, r.Hitpoints.Current, r.Hitpoints.Max, remove, r.GridCellKey, damage, isCrit)
+		cellToBroadCast.broadcast(NewUpdateResourceEvent(r.Id, r.Hitpoints.Current, r.Hitpoints.Max, remove, r.GridCellKey, damage, isCrit))
 
 		if r.Hitpoints.Current <= 0 {
-			h.SpawnLoot(*r, c)
+			if c.isFriendly(*r) {
+				// deconstructed by the owner or the team
+				h.deconstruct(*r)
+			} else {
+				h.SpawnLoot(*r, c)
+				h.ResourceManager.ScheduleRespawn(*r)
+			}
 			h.ResourceManager.DeleteResource(r.Id)
 		}
 
@@ -206,56 +319,31 @@ func (h *Hub) HandleResourceHit(event HitResourceEvent, c *Client) {
 
 }
 
+// SpawnLoot drops the loot of a destroyed resource, the loot table is named after the resource type
 func (h *Hub) SpawnLoot(destroyedResource resource.Resource, c *Client) {
-	// Spawn subtype resources
-
-	// check how many should be spawned
-
-	//Todo: Add this as function in resource handler -> Get getloot from resource
-	quantity := 0
-	subType := resource.Brick
-
-	newResources := []*resource.Resource{}
-
-	if destroyedResource.ResourceType == resource.Stone {
-		quantity = shared.RandIntInRange(2, 5)
-		subType = resource.Brick
-		pos := destroyedResource.Pos.Copy()
-		r := resource.NewResource(subType, pos, h.ResourceManager.GetResourceId(), quantity, false, -1, true, destroyedResource.GridCellKey)
-		newResources = append(newResources, r)
+	drops := h.lootTables.Roll(string(destroyedResource.ResourceType), shared.GlobalRand, loot.Context{Biome: string(h.world.BiomeAt(destroyedResource.Pos))})
+	h.spawnDrops(drops, destroyedResource.Pos, destroyedResource.GridCellKey)
+}
 
-		shouldSpawnIronOre := shared.RandIntInRange(1, 10)
+// spawnDrops places rolled loot at pos
+func (h *Hub) spawnDrops(drops []loot.Drop, pos shared.Vector, gridCellKey string) {
+	for i, drop := range drops {
+		dropPos := pos.Copy()
+		if i > 0 {
+			// don't stack all drops on top of each other
+			dropPos.X += shared.RandIntInRange(-20, 20)
+			dropPos.Y += shared.RandIntInRange(-20, 20)
+		}
 
-		if shouldSpawnIronOre >= 7 {
-			quantity = shared.RandIntInRange(1, 3)
-			subType = resource.IronOre
-			pos := destroyedResource.Pos.Copy()
-			pos.X += shared.RandIntInRange(-20, 20)
-			pos.Y += shared.RandIntInRange(-20, 20)
-			r := resource.NewResource(subType, pos, h.ResourceManager.GetResourceId(), quantity, false, -1, true, destroyedResource.GridCellKey)
-			newResources = append(newResources, r)
+		if drop.Item {
+			cell := h.GridManager.GetCellFromPos(dropPos)
+			for n := 0; n < drop.Quantity; n++ {
+				cell.SpawnItem(dropPos)
+			}
+			continue
 		}
-	} else if destroyedResource.ResourceType == resource.Blockade {
-		quantity = 5
-		subType = resource.Brick
-		pos := destroyedResource.Pos.Copy()
-		r := resource.NewResource(subType, pos, h.ResourceManager.GetResourceId(), quantity, false, -1, true, destroyedResource.GridCellKey)
-		newResources = append(newResources, r)
-	} else if destroyedResource.ResourceType == resource.Tree {
-		quantity = shared.RandIntInRange(3, 5)
-		subType = resource.Log
-		pos := destroyedResource.Pos.Copy()
-		r := resource.NewResource(subType, pos, h.ResourceManager.GetResourceId(), quantity, false, -1, true, destroyedResource.GridCellKey)
-		newResources = append(newResources, r)
-	} else if destroyedResource.ResourceType == resource.WoodBlockade {
-		quantity = 5
-		subType = resource.Log
-		pos := destroyedResource.Pos.Copy()
-		r := resource.NewResource(subType, pos, h.ResourceManager.GetResourceId(), quantity, false, -1, true, destroyedResource.GridCellKey)
-		newResources = append(newResources, r)
-	}
 
-	for _, r := range newResources {
+		r := resource.NewResource(drop.Resource, dropPos, h.ResourceManager.GetResourceId(), drop.Quantity, false, -1, true, gridCellKey)
 		h.ResourceManager.AddResource <- r
 	}
 }
@@ -268,8 +356,9 @@ func (h *Hub) HandleLootResource(event LootResourceEvent, c *Client) {
 		return
 	}
 
-	if r.Pos.Dist((&c.Pos)) < MAX_LOOT_RANGE {
+	if r.Pos.Dist((&c.Pos)) < float64(h.config.Player.LootRange) {
 		// Handle looting
+		c.ResourceInventoryMutex.Lock()
 		if invRes, ok := c.ResourceInventory[r.ResourceType]; ok {
 			// already exists in inventory
 			invRes.Quantity += r.Quantity
@@ -277,10 +366,11 @@ func (h *Hub) HandleLootResource(event LootResourceEvent, c *Client) {
 		} else {
 			c.ResourceInventory[r.ResourceType] = *r
 		}
+		c.ResourceInventoryMutex.Unlock()
 
 		// broadcast update event that removes the resource
 		cell := h.GridManager.GetCellFromPos(r.Pos)
-		cell.Broadcast <- NewUpdateResourceEvent(r.Id, -1, -1, true, r.GridCellKey, 0, false)
+		cell.broadcast(NewUpdateResourceEvent(r.Id, -1, -1, true, r.GridCellKey, 0, false))
 
 		// Todo broadcast UpdateResourceEvent to clients subbed to cell
 
@@ -295,120 +385,133 @@ func (h *Hub) HandleLootResource(event LootResourceEvent, c *Client) {
 	}
 }
 
-func (h *Hub) HandlePlayerPlacedResource(event PlayerPlacedResourceEvent, c *Client) {
-	if event.ResourceType == string(resource.Blockade) {
-		costs := 5
-		buildResource := resource.Blockade
-		ingredientResource := resource.Brick
-		h.buildResource(c, costs, ingredientResource, buildResource, event.Pos, 500)
-	}
+// claimPlayer marks the player as online for this client, false if another connection already uses it
+func (h *Hub) claimPlayer(uuid string, client *Client) bool {
+	h.ClientMutex.Lock()
+	defer h.ClientMutex.Unlock()
 
-	if event.ResourceType == string(resource.WoodBlockade) {
-		costs := 5
-		buildResource := resource.WoodBlockade
-		ingredientResource := resource.Log
-		h.buildResource(c, costs, ingredientResource, buildResource, event.Pos, 200)
+	if other, ok := h.onlinePlayers[uuid]; ok && other != client {
+		return false
 	}
+	h.onlinePlayers[uuid] = client
+	return true
 }
 
-func (h *Hub) buildResource(c *Client, costs int, ingredientResource resource.ResourceType, buildResource resource.ResourceType, pos shared.Vector, hitpoints int) {
-	if invRes, ok := c.ResourceInventory[ingredientResource]; ok {
-		// check if enough materials in inventory to build this resource
-
-		if invRes.Quantity >= costs {
-			invRes.Quantity -= costs
-			c.ResourceInventory[ingredientResource] = invRes
-
-			buildResource := &resource.Resource{ResourceType: buildResource,
-				Pos:      pos,
-				Id:       h.ResourceManager.GetResourceId(),
-				Quantity: 1,
-				Hitpoints: shared.Hitpoints{
-					Current: hitpoints,
-					Max:     hitpoints,
-				},
-				IsSolid:    true,
-				IsLootable: false,
-			}
+// releasePlayer undoes claimPlayer for logins that failed after the claim
+func (h *Hub) releasePlayer(uuid string, client *Client) {
+	h.ClientMutex.Lock()
+	defer h.ClientMutex.Unlock()
 
-			h.ResourceManager.AddResource <- buildResource
+	if h.onlinePlayers[uuid] == client {
+		delete(h.onlinePlayers, uuid)
+	}
+}
 
-			// translate build resource to inventory update
+func (h *Hub) rejectLogin(client *Client, reason string) {
+	fmt.Printf("login rejected for client %d: %s\n", client.Id, reason)
+	client.send <- NewLoginFailedEvent(reason)
+	client.Close(websocket.ClosePolicyViolation, reason)
+}
 
-			resourceToRemoveFromInventry := resource.ResourceMin{
-				Quantity:     5,
-				ResourceType: ingredientResource,
-			}
+func (h *Hub) LoginPlayer(token string, client *Client) {
+	if client.isLoggedIn() {
+		// login is only allowed once per connection
+		return
+	}
 
-			c.send <- NewUpdateInventoryEvent(resourceToRemoveFromInventry, true)
-		}
+	claims, err := h.authenticator.ValidateToken(token)
+	if err != nil {
+		h.rejectLogin(client, err.Error())
+		return
 	}
-}
 
-func (h *Hub) LoginPlayer(uuid string, client *Client) {
+	uuid := claims.PlayerUUID
+	if !h.claimPlayer(uuid, client) {
+		h.rejectLogin(client, "account is already logged in")
+		return
+	}
 
-	h.ClientMutex.Lock()
-	persistanceEntry, ok := h.persistedClientData[uuid]
-	h.ClientMutex.Unlock()
+	persistanceEntry, ok, err := h.playerStore.Load(uuid)
+	if err != nil {
+		// a fresh character would overwrite the save on the next autosave
+		fmt.Printf("Error: failed to load client %s: %s\n", uuid, err)
+		h.releasePlayer(uuid, client)
+		h.rejectLogin(client, "failed to load the player")
+		return
+	}
 
+	client.UUID = uuid
+	client.ResourceInventoryMutex.Lock()
 	if ok {
 		fmt.Printf("existing client with uuid: %s\n", uuid)
 		// already logged in
-		client.UUID = uuid
 		client.Pos = persistanceEntry.Pos
 		client.ResourceInventory = persistanceEntry.Inventory
 		client.ItemInventory = persistanceEntry.ItemInventory
+		client.HitpointsMutex.Lock()
 		client.Hitpoints = persistanceEntry.Hitpoints
-		client.EquippedItems = persistanceEntry.EquippedItems
+		client.HitpointsMutex.Unlock()
+		client.Equipment = persistanceEntry.Equipment
+		if client.Equipment == nil {
+			client.Equipment = equipmentFromList(persistanceEntry.EquippedItems, persistanceEntry.ItemInventory)
+		}
+		client.Team = persistanceEntry.Team
+		client.respawnPos = persistanceEntry.RespawnPos
 	} else {
-		// Initialize new client
-		uuid := gUUID.New().String()
-		client.UUID = uuid
+		// Initialize the character of a new account
 		inventory := make(map[resource.ResourceType]resource.Resource)
 		inventory[resource.Brick] = *resource.NewResource(resource.Brick, shared.Vector{}, h.ResourceManager.GetResourceId(), 50, false, 100, false, "")
 		client.ResourceInventory = inventory
 	}
+	client.ResourceInventoryMutex.Unlock()
+
+	// players that logged out while dead are back at their respawn point
+	client.HitpointsMutex.Lock()
+	dead := client.Hitpoints.Current <= 0
+	if dead {
+		client.Hitpoints.Current = client.Hitpoints.Max
+	}
+	client.HitpointsMutex.Unlock()
+	if dead {
+		client.SetPos(h.respawnPos(client))
+	}
+
+	// the default spawn and positions saved before terrain collision existed might be in deep water
+	client.SetPos(h.world.nearestPassable(client.GetPos()))
+	client.setLoggedIn(true)
 
+	client.validateEquipment()
 	client.updateStats()
 	client.send <- NewUserInitEvent(client, h.gameConfig)
 
-	gridCell := h.GridManager.GetCellFromPos(client.Pos)
+	gridCell := h.GridManager.GetCellFromPos(client.GetPos())
 	gridCell.AddPlayer(client)
-	for _, cell := range h.GridManager.getCells(gridCell.Pos.X/GridCellSize, gridCell.Pos.Y/GridCellSize) {
+	for _, cell := range h.GridManager.getCells(gridCell.Pos.X, gridCell.Pos.Y) {
 		cell.Subscribe(client)
 	}
 }
 
 func (h *Hub) HandleNpcHit(event HitNpcEvent, client *Client) {
+	if !client.tryAction(time.Now()) {
+		return
+	}
 
 	clientPos := client.GetPos()
-	cells := h.GridManager.getCells(clientPos.X/GridCellSize, clientPos.Y/GridCellSize)
+	cellPos := h.world.cellOf(clientPos)
+	cells := h.GridManager.getCells(cellPos.X, cellPos.Y)
 
 	for _, cell := range cells {
-		cell.NpcListMutex.Lock()
-		defer cell.NpcListMutex.Unlock()
-		for npcIndex, npc := range cell.NpcList {
-			if npc.UUID == event.UUID {
-
-				damage, isCrit := client.DamageRoll()
-
-				npc.Hitpoints.Current -= damage
-				remove := npc.Hitpoints.Current <= 0
-
-				if remove {
-					npc.SetRemove(true)
-
-					// spawn some loot
-					for i := 0; i < 5; i++ {
-						cell.SpawnItem(npc.Pos)
-					}
-				}
+		npc, found := cell.hitNpc(event.UUID, client)
+		if !found {
+			continue
+		}
+		client.enterCombat(time.Now())
 
-				cell.NpcList[npcIndex] = npc
-				cell.Broadcast <- NewUpdateNpcEvent(npc.UUID, cell.NpcList[npcIndex].Hitpoints.Current, cell.NpcList[npcIndex].Hitpoints.Max, remove, cell.GridCellKey, damage, isCrit)
-				return
-			}
+		if npc.Hitpoints.Current <= 0 {
+			drops := h.lootTables.Roll(npc.loot, shared.GlobalRand, loot.Context{Biome: string(h.world.BiomeAt(npc.Pos))})
+			h.spawnDrops(drops, npc.Pos, cell.GridCellKey)
 		}
+		return
 	}
 }
 
@@ -1,11 +1,133 @@
 package root
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/loot"
+	"ws-game/resource"
 )
 
+func newTestAuthenticator() *Authenticator {
+	return NewAuthenticator(NewMemoryAccountStore(), []byte("0123456789abcdef0123456789abcdef"))
+}
+
+// newTestHub creates a hub with the default config and memory stores, it is shut down with the test
+func newTestHub(t *testing.T) *Hub {
+	hub := NewHub(DefaultConfig(), loot.Default(), bestiary.Default(), item.Default(), NewMemoryPlayerStore(), NewMemoryWorldStore(), newTestAuthenticator())
+	t.Cleanup(func() {
+		// test clients have no connection that Run could unregister, don't wait for them
+		hub.ClientMutex.Lock()
+		hub.clients = make(map[int]*Client)
+		hub.ClientMutex.Unlock()
+
+		hub.Shutdown("")
+	})
+	return hub
+}
+
 func TestHub(t *testing.T) {
-	hub := NewHub()
+	hub := newTestHub(t)
 	fmt.Println(hub)
 }
+
+func TestLoginPlayerRejectsSecondConnection(t *testing.T) {
+	auth := newTestAuthenticator()
+	hub := newTestHub(t)
+	hub.authenticator = auth
+
+	if _, err := auth.Register("alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	token, account, err := auth.Login("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := NewClient(hub, nil, hub.getClientId())
+	hub.LoginPlayer(token, first)
+	if !first.isLoggedIn() || first.UUID != account.PlayerUUID {
+		t.Fatal("expected first connection to be logged in with the account's player")
+	}
+
+	second := NewClient(hub, nil, hub.getClientId())
+	hub.LoginPlayer(token, second)
+	if second.isLoggedIn() {
+		t.Fatal("expected second connection of the same account to be rejected")
+	}
+
+	if _, ok := (<-second.send).(*LoginFailedEvent); !ok {
+		t.Error("expected login failed event for second connection")
+	}
+}
+
+func TestLoginPlayerRejectsInvalidToken(t *testing.T) {
+	hub := newTestHub(t)
+
+	client := NewClient(hub, nil, hub.getClientId())
+	hub.LoginPlayer("not-a-token", client)
+	if client.isLoggedIn() {
+		t.Fatal("expected invalid token to be rejected")
+	}
+}
+
+// brokenPlayerStore can't read saves, e.g. a corrupted file
+type brokenPlayerStore struct {
+	saved bool
+}
+
+func (s *brokenPlayerStore) Load(uuid string) (ClientPersistance, bool, error) {
+	return ClientPersistance{}, false, errors.New("unexpected end of JSON input")
+}
+
+func (s *brokenPlayerStore) Save(uuid string, entry ClientPersistance) error {
+	s.saved = true
+	return nil
+}
+
+func TestLoginPlayerRejectsUnreadableSave(t *testing.T) {
+	auth := newTestAuthenticator()
+	store := &brokenPlayerStore{}
+	hub := newTestHub(t)
+	hub.playerStore = store
+	hub.authenticator = auth
+
+	if _, err := auth.Register("alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	token, account, err := auth.Login("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(hub, nil, hub.getClientId())
+	hub.LoginPlayer(token, client)
+	if client.isLoggedIn() {
+		t.Fatal("expected the login to fail instead of creating a new character")
+	}
+	if _, ok := (<-client.send).(*LoginFailedEvent); !ok {
+		t.Error("expected login failed event")
+	}
+
+	hub.persistClient(client)
+	if store.saved {
+		t.Error("the save got overwritten")
+	}
+	if _, ok := hub.onlinePlayers[account.PlayerUUID]; ok {
+		t.Error("the failed login still claims the player")
+	}
+}
+
+func TestMalformedPayloadsAreDropped(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.setLoggedIn(true)
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+
+	// used to panic and take the whole server down
+	for _, eventType := range []EventType{PLAYER_LOGIN_EVENT, KEYBOARD_EVENT, HIT_RESOURCE_EVENT, HIT_NPC_EVENT, LOOT_RESOURCE_EVENT, PLAYER_PLACED_RESOURCE_EVENT, PLAYER_CLICKED_GROUND_ITEM_EVENT, PLAYER_CLICKED_INEVNTORY_ITEM_EVENT, CRAFT_EVENT, STATE_ACK_EVENT, REPAIR_EVENT, USE_ITEM_EVENT} {
+		UnmarshalClientEvents(BaseEvent{EventType: eventType, Payload: []byte(`[5]`)}, hub, client)
+	}
+}
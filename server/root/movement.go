@@ -0,0 +1,208 @@
+package root
+
+import (
+	"context"
+	"math"
+	"time"
+	"ws-game/shared"
+)
+
+const (
+	// players are moved by the server in this interval based on the keys they hold
+	MovementTickRate = 50 * time.Millisecond
+
+	// clients only send key changes, everything above this is spam and gets dropped
+	MaxInputEventsPerSecond = 30
+
+	// players can't get closer than this to solid resources
+	PlayerCollisionRadius = 40
+)
+
+const (
+	KeyUp   = 0
+	KeyDown = 1
+)
+
+var movementKeys = map[string]shared.Vector{
+	"w": {X: 0, Y: -1},
+	"a": {X: -1, Y: 0},
+	"s": {X: 0, Y: 1},
+	"d": {X: 1, Y: 0},
+}
+
+// movementInput is the last input state a client sent
+type movementInput struct {
+	pressed map[string]bool
+
+	// rate limiting of input events
+	windowStart    time.Time
+	eventsInWindow int
+
+	// true while the player moved in the last tick
+	moving bool
+	// true if the client already got a correction for the current blocked move
+	corrected bool
+}
+
+func newMovementInput() movementInput {
+	return movementInput{
+		pressed: make(map[string]bool),
+	}
+}
+
+// allowInputEvent counts the event in the current one second window
+func (m *movementInput) allowInputEvent(now time.Time) bool {
+	if now.Sub(m.windowStart) >= time.Second {
+		m.windowStart = now
+		m.eventsInWindow = 0
+	}
+
+	m.eventsInWindow++
+	return m.eventsInWindow <= MaxInputEventsPerSecond
+}
+
+// direction sums up the held keys, opposite keys cancel each other out
+func (m *movementInput) direction() shared.Vector {
+	dir := shared.Vector{}
+	for key, pressed := range m.pressed {
+		if pressed {
+			dir.Add(movementKeys[key])
+		}
+	}
+	return dir
+}
+
+// movementStep scales a direction to the distance a player moves in one tick,
+// diagonal moves are not faster than straight ones
+func movementStep(dir shared.Vector, speed int) shared.Vector {
+	if dir.X == 0 && dir.Y == 0 {
+		return dir
+	}
+
+	length := math.Sqrt(float64(dir.X*dir.X + dir.Y*dir.Y))
+	return shared.Vector{
+		X: int(math.Round(float64(dir.X) / length * float64(speed))),
+		Y: int(math.Round(float64(dir.Y) / length * float64(speed))),
+	}
+}
+
+func (h *Hub) handleKeyboardEvent(event KeyBoardEvent, c *Client) {
+	if _, ok := movementKeys[event.Key]; !ok {
+		return
+	}
+	if event.Value != KeyUp && event.Value != KeyDown {
+		return
+	}
+
+	c.InputMutex.Lock()
+	defer c.InputMutex.Unlock()
+
+	// releasing a key can only stop the player, dropping it would leave the key stuck
+	if !c.input.allowInputEvent(time.Now()) && event.Value == KeyDown {
+		return
+	}
+
+	c.input.pressed[event.Key] = event.Value == KeyDown
+}
+
+func MovementCoro(ctx context.Context, h *Hub) {
+	ticker := time.NewTicker(MovementTickRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.movePlayers()
+		}
+	}
+}
+
+func (h *Hub) movePlayers() {
+	h.ClientMutex.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for _, c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.ClientMutex.Unlock()
+
+	for _, c := range clients {
+		if !c.isLoggedIn() || !c.getConnected() || c.isDead() {
+			continue
+		}
+		h.movePlayer(c)
+	}
+}
+
+// movePlayer integrates the input of a client for one tick
+func (h *Hub) movePlayer(c *Client) {
+	if !h.stepPlayer(c) {
+		return
+	}
+
+	// the grid manager might be busy, the input of the client must not be locked meanwhile
+	select {
+	case h.GridManager.UpdateClientPosition <- c:
+	case <-h.ctx.Done():
+	}
+}
+
+// stepPlayer moves the player by its input, returns false if it didn't move
+func (h *Hub) stepPlayer(c *Client) bool {
+	c.InputMutex.Lock()
+	defer c.InputMutex.Unlock()
+
+	pos := c.GetPos()
+	move := movementStep(c.input.direction(), h.world.scaledSpeed(pos, h.config.Player.Speed))
+
+	if move.X == 0 && move.Y == 0 {
+		if c.input.moving {
+			// player stopped, make sure the client ends up where the server has it
+			c.input.moving = false
+			c.trySend(NewPlayerTargetPositionEvent(pos, c.Id, true))
+		}
+		return false
+	}
+
+	newPos, ok := h.tryMove(pos, move)
+	if !ok {
+		c.input.moving = false
+		if !c.input.corrected {
+			c.input.corrected = true
+			c.trySend(NewPlayerTargetPositionEvent(pos, c.Id, true))
+		}
+		return false
+	}
+
+	c.input.moving = true
+	c.input.corrected = false
+	c.SetPos(newPos)
+	return true
+}
+
+// tryMove returns the position after the move, if the way is blocked the player slides along the free axis
+func (h *Hub) tryMove(pos shared.Vector, move shared.Vector) (shared.Vector, bool) {
+	return walkStep(pos, move, h.canPlayerEnter)
+}
+
+// canPlayerEnter checks terrain and solid resources, the resources of neighbouring cells are
+// considered too since a player close to the cell border collides with them as well
+func (h *Hub) canPlayerEnter(pos shared.Vector) bool {
+	if !h.world.terrainPassable(pos) {
+		return false
+	}
+
+	for _, cell := range h.GridManager.cellsAround(pos, PlayerCollisionRadius) {
+		for _, resource := range cell.GetResources() {
+			if !resource.IsSolid {
+				continue
+			}
+
+			if pos.Dist(&resource.Pos) < PlayerCollisionRadius {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func TestInputEventsAreRateLimited(t *testing.T) {
+	input := newMovementInput()
+	now := time.Now()
+
+	for i := 0; i < MaxInputEventsPerSecond; i++ {
+		if !input.allowInputEvent(now) {
+			t.Fatalf("event %d should be allowed", i)
+		}
+	}
+	if input.allowInputEvent(now) {
+		t.Fatal("expected event above the limit to be dropped")
+	}
+	if !input.allowInputEvent(now.Add(time.Second)) {
+		t.Fatal("expected new window to allow events again")
+	}
+}
+
+func TestDiagonalMovementIsNotFaster(t *testing.T) {
+	speed := DefaultConfig().Player.Speed
+	move := movementStep(shared.Vector{X: 1, Y: 1}, speed)
+	if dist := move.Dist(&shared.Vector{}); dist > float64(speed+1) {
+		t.Fatalf("diagonal step of %f is faster than %d", dist, speed)
+	}
+}
+
+func TestMovePlayerIsBlockedBySolidResources(t *testing.T) {
+	hub := newTestHub(t)
+	c := NewClient(hub, nil, hub.getClientId())
+	pos := hub.world.nearestPassable(c.GetPos())
+	speed := hub.config.Player.Speed
+
+	cell := c.getGridCell()
+	stone := resource.NewResource(resource.Stone, shared.Vector{X: pos.X + speed + 30, Y: pos.Y}, hub.ResourceManager.GetResourceId(), 1, true, 100, false, cell.GridCellKey)
+	stone.IsSolid = true // NewResource creates non solid resources for now
+	cell.ResourcesMutex.Lock()
+	cell.Resources[stone.Id] = stone
+	cell.ResourcesMutex.Unlock()
+
+	if _, ok := hub.tryMove(pos, shared.Vector{X: speed, Y: 0}); ok {
+		t.Fatal("expected move into a stone to be blocked")
+	}
+
+	// diagonal moves slide along the free axis
+	newPos, ok := hub.tryMove(pos, movementStep(shared.Vector{X: 1, Y: 1}, speed))
+	if !ok || newPos.X != pos.X || newPos.Y <= pos.Y {
+		t.Fatalf("expected player to slide down, got %v", newPos)
+	}
+}
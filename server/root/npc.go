@@ -1,6 +1,9 @@
 package root
 
 import (
+	"time"
+	"ws-game/ai"
+	"ws-game/bestiary"
 	"ws-game/shared"
 
 	"github.com/google/uuid"
@@ -15,51 +18,197 @@ const (
 	Returning NpcState = 3
 )
 
+// npcs without an archetype (or one that doesn't set them) use these
+const (
+	defaultNpcLoot   = "npc"
+	defaultNpcSprite = "knight"
+)
+
 type Npc struct {
-	UUID             string           `json:"UUID"`
-	Pos              shared.Vector    `json:"pos"`
-	Hitpoints        shared.Hitpoints `json:"hitpoints"`
-	NpcType          string           `json:"npcType"`
-	AttackSpeed      int              `json:"attackSpeed"`
-	spawnPos         shared.Vector
-	movesBackToSpawn bool
-	aggressive       bool
-	critChance       float32
-	minDamage        float32
-	maxDamage        float32
-	movementCooldown int
-	walkCooldown     int
-	attackCooldown   int
-	targetedPlayer   *Client
-	remove           bool
-	State            NpcState
+	UUID            string           `json:"UUID" wire:"uuid"`
+	Pos             shared.Vector    `json:"pos"`
+	Hitpoints       shared.Hitpoints `json:"hitpoints"`
+	NpcType         string           `json:"npcType"` // name of the archetype in the bestiary
+	AttackSpeed     int              `json:"attackSpeed"`
+	Sprite          string           `json:"sprite"`
+	spawnPos        shared.Vector
+	aggressive      bool
+	critChance      float32
+	minDamage       float32
+	maxDamage       float32
+	targetedPlayer  *Client
+	threat          threatTable
+	stepSize        int
+	behavior        ai.Params
+	brain           ai.Node // built from behavior on the first update
+	respawnCooldown time.Duration
+	loot            string          // table rolled when the npc dies
+	path            []shared.Vector // waypoints to pathGoal, see walkTowards
+	pathGoal        subCellPos
+	remove          bool
+	State           NpcState
 }
 
 func (npc *Npc) SetRemove(value bool) {
 	npc.remove = value
 }
 
-func NewNpc(pos shared.Vector) Npc {
+func NewNpc(pos shared.Vector, config NpcConfig) Npc {
 	// simple test npc for ai implementation
 	return Npc{
-		UUID:             uuid.New().String(),
-		Pos:              pos,
-		spawnPos:         pos,
-		movesBackToSpawn: false,
+		UUID:     uuid.New().String(),
+		Pos:      pos,
+		spawnPos: pos,
 		Hitpoints: shared.Hitpoints{
-			Current: 25000,
-			Max:     25000,
+			Current: config.Hitpoints,
+			Max:     config.Hitpoints,
 		},
-		NpcType:          "",
-		aggressive:       false,
-		minDamage:        10,
-		maxDamage:        30,
-		critChance:       0.5,
-		targetedPlayer:   nil,
-		movementCooldown: 0,
-		walkCooldown:     0,
-		attackCooldown:   0,
-		State:            Idle,
-		AttackSpeed:      15,
+		NpcType:        "",
+		aggressive:     false,
+		minDamage:      float32(config.MinDamage),
+		maxDamage:      float32(config.MaxDamage),
+		critChance:     float32(config.CritChance),
+		targetedPlayer: nil,
+		threat:         threatTable{},
+		State:          Idle,
+		AttackSpeed:    config.AttackSpeed,
+		Sprite:         defaultNpcSprite,
+		stepSize:       config.StepSize,
+		behavior: ai.Params{
+			AggroRadius: config.AggroRadius,
+			LeashRange:  config.LeashRange,
+			AttackRange: config.AttackRange,
+			AttackSpeed: config.AttackSpeed,
+			StepTicks:   config.StepTicks,
+			Idle:        ai.Stand,
+			RoamRadius:  config.RoamRadius,
+		},
+		respawnCooldown: config.RespawnCooldown,
+		loot:            defaultNpcLoot,
+	}
+}
+
+// NewNpcOf creates an npc of an archetype, the stats it leaves at zero come from the config
+func NewNpcOf(pos shared.Vector, npcType string, a bestiary.Archetype, config NpcConfig) Npc {
+	npc := NewNpc(pos, config)
+	npc.NpcType = npcType
+
+	if a.Hitpoints > 0 {
+		npc.Hitpoints = shared.Hitpoints{Current: a.Hitpoints, Max: a.Hitpoints}
+	}
+	if a.MinDamage > 0 {
+		npc.minDamage = float32(a.MinDamage)
+	}
+	if a.MaxDamage > 0 {
+		npc.maxDamage = float32(a.MaxDamage)
+	}
+	// see bestiary.CheckDamage, a min above the max of the config must not break the damage roll
+	if npc.maxDamage < npc.minDamage {
+		npc.maxDamage = npc.minDamage
+	}
+	if a.CritChance > 0 {
+		npc.critChance = float32(a.CritChance)
+	}
+	if a.AttackSpeed > 0 {
+		npc.AttackSpeed = a.AttackSpeed
+		npc.behavior.AttackSpeed = a.AttackSpeed
+	}
+	if a.StepSize > 0 {
+		npc.stepSize = a.StepSize
+	}
+	if a.AggroRadius > 0 {
+		npc.behavior.AggroRadius = a.AggroRadius
+	}
+	if a.LeashRange > 0 {
+		npc.behavior.LeashRange = a.LeashRange
+	}
+	if a.AttackRange > 0 {
+		npc.behavior.AttackRange = a.AttackRange
+	}
+	if a.Idle != "" {
+		npc.behavior.Idle = a.Idle
+	}
+	if a.RoamRadius > 0 {
+		npc.behavior.RoamRadius = a.RoamRadius
+	}
+	npc.behavior.FleeBelow = a.FleeBelow
+	npc.behavior.HelpRadius = a.HelpRadius
+	if a.Respawn > 0 {
+		npc.respawnCooldown = a.Respawn
+	}
+	if a.Loot != "" {
+		npc.loot = a.Loot
+	}
+	if a.Sprite != "" {
+		npc.Sprite = a.Sprite
+	}
+	return npc
+}
+
+// walkTowards moves the npc one step along its path to target, the path is planned again once the target
+// leaves its sub cell. Returns false if the target can't be reached.
+func (cell *GridCell) walkTowards(npc *Npc, target shared.Vector) bool {
+	if cell.pathfinder == nil {
+		return npc.stepTowards(cell.world, target)
+	}
+
+	goal := cell.world.subCellOf(target)
+	if len(npc.path) == 0 || npc.pathGoal != goal {
+		path, result := cell.pathfinder.FindPath(npc.Pos, target, &cell.pathBudget, time.Now())
+		if result == pathDeferred {
+			// the cell searched enough this tick, wait
+			return true
+		}
+		if result == pathUnreachable {
+			npc.path = nil
+			return false
+		}
+		npc.path = path
+		npc.pathGoal = goal
+	}
+
+	// the target moves inside the goal sub cell
+	npc.path[len(npc.path)-1] = target
+
+	if !npc.stepTowards(cell.world, npc.path[0]) {
+		npc.path = nil
+		return false
+	}
+	if npc.Pos == npc.path[0] {
+		npc.path = npc.path[1:]
+	}
+	return true
+}
+
+// stepTowards moves the npc up to its step size per axis towards target. The step is slowed down by the terrain
+// and deep water is avoided, returns false if the npc is stuck.
+func (npc *Npc) stepTowards(w *World, target shared.Vector) bool {
+	maxStep := w.scaledSpeed(npc.Pos, npc.stepSize)
+
+	step := shared.Vector{
+		X: clamp(target.X-npc.Pos.X, -maxStep, maxStep),
+		Y: clamp(target.Y-npc.Pos.Y, -maxStep, maxStep),
+	}
+
+	if step.X == 0 && step.Y == 0 {
+		return true
+	}
+
+	newPos, ok := walkStep(npc.Pos, step, w.terrainPassable)
+	if !ok {
+		return false
+	}
+
+	npc.Pos = newPos
+	return true
+}
+
+func clamp(v int, min int, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
 	}
+	return v
 }
@@ -0,0 +1,136 @@
+package root
+
+import (
+	"math"
+	"time"
+	"ws-game/shared"
+)
+
+// npcAgent lets a behavior tree control a npc of the cell, the cell holds its npc list lock meanwhile
+type npcAgent struct {
+	cell *GridCell
+	npc  *Npc
+}
+
+func (a *npcAgent) Pos() shared.Vector {
+	return a.npc.Pos
+}
+
+func (a *npcAgent) SpawnPos() shared.Vector {
+	return a.npc.spawnPos
+}
+
+func (a *npcAgent) Hitpoints() shared.Hitpoints {
+	return a.npc.Hitpoints
+}
+
+func (a *npcAgent) Rand() shared.Rand {
+	return shared.GlobalRand
+}
+
+func (a *npcAgent) HasTarget() bool {
+	return a.npc.targetedPlayer != nil
+}
+
+func (a *npcAgent) TargetPos() shared.Vector {
+	return a.npc.targetedPlayer.GetPos()
+}
+
+// FindTarget adds the proximity threat of the players within radius and picks the player with the most threat
+func (a *npcAgent) FindTarget(radius int) bool {
+	perTick := a.cell.config.Npc.ProximityThreat * a.cell.config.World.CellUpdateRate.Seconds()
+	for _, sub := range a.cell.playerSubscriptions {
+		playerPos := sub.Player.GetPos()
+		dist := playerPos.Dist(&a.npc.Pos)
+		if dist < float64(radius) && !sub.Player.isDead() {
+			// at least minThreat, players entering the radius are noticed right away
+			a.npc.threat.add(sub.Player, math.Max(minThreat, perTick*(1-dist/float64(radius))))
+		}
+	}
+
+	target := a.npc.threat.pick(a.npc.targetedPlayer)
+	if target != a.npc.targetedPlayer {
+		a.npc.path = nil
+	}
+	a.npc.targetedPlayer = target
+	return target != nil
+}
+
+// DropTarget forgets the target, the npc picks the player with the next most threat
+func (a *npcAgent) DropTarget() {
+	if a.npc.targetedPlayer != nil {
+		a.npc.threat.forget(a.npc.targetedPlayer)
+	}
+	a.npc.targetedPlayer = nil
+	a.npc.path = nil
+}
+
+func (a *npcAgent) ClearThreat() {
+	a.npc.threat = threatTable{}
+	a.npc.targetedPlayer = nil
+	a.npc.path = nil
+}
+
+func (a *npcAgent) MoveTowards(pos shared.Vector) bool {
+	if a.npc.targetedPlayer == nil {
+		a.npc.State = Returning
+	} else {
+		a.npc.State = Walk
+	}
+	return a.cell.walkTowards(a.npc, pos)
+}
+
+func (a *npcAgent) ResetToSpawn() {
+	a.npc.Pos = a.npc.spawnPos
+	a.npc.path = nil
+}
+
+func (a *npcAgent) Heal() {
+	a.npc.Hitpoints.Current = a.npc.Hitpoints.Max
+}
+
+func (a *npcAgent) Attack(ranged bool) {
+	npc := a.npc
+	player := npc.targetedPlayer
+	npc.State = Attack
+
+	// max is inclusive, archetypes may hit for a fixed amount
+	npcDamage := randIntInRange(shared.GlobalRand, int(npc.minDamage), int(npc.maxDamage)+1)
+
+	crit := shared.GlobalRand.Float64() < float64(npc.critChance)
+	if crit {
+		npcDamage *= 2
+	}
+
+	npcDamage = player.absorbDamage(npcDamage)
+	hitpoints := player.damage(npcDamage, time.Now())
+
+	attackID := 0
+	if ranged {
+		attackID = 1
+	}
+	a.cell.AddEventToBroadcast(NewNpcAttackAnimEvent(npc.UUID, attackID))
+	a.cell.AddEventToBroadcast(NewUpdatePlayerEvent(player.Id, hitpoints, npcDamage, 0, crit))
+
+	if hitpoints.Current == 0 {
+		a.cell.killPlayer(player)
+	}
+}
+
+// CallForHelp gives the idle npcs of the same type within radius threat against the target
+func (a *npcAgent) CallForHelp(radius int) int {
+	helpers := 0
+	for i := range a.cell.NpcList {
+		other := &a.cell.NpcList[i]
+		if other.UUID == a.npc.UUID || other.remove || other.targetedPlayer != nil || other.NpcType != a.npc.NpcType {
+			continue
+		}
+
+		if other.Pos.Dist(&a.npc.Pos) <= float64(radius) {
+			other.threat.add(a.npc.targetedPlayer, helpThreat)
+			other.targetedPlayer = a.npc.targetedPlayer
+			helpers++
+		}
+	}
+	return helpers
+}
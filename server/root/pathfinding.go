@@ -0,0 +1,324 @@
+package root
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// npcs plan their way over the sub cell grid with A*. Sub cells with deep water or a resource on them
+// are blocked, so npcs walk around lakes, trees and blockades. A search may cross into the neighbouring
+// grid cells of start and goal, cells that don't exist yet only contribute their terrain.
+
+const (
+	// paths are reused for this long, resources that got placed or destroyed meanwhile are ignored
+	pathCacheTTL = 2 * time.Second
+	// expired paths are dropped once the cache grows beyond this
+	pathCacheSize = 1024
+	// a single search gives up after expanding this many sub cells
+	maxSearchNodes = 4000
+)
+
+type pathResult int
+
+const (
+	pathFound       pathResult = 0
+	pathUnreachable pathResult = 1
+	pathDeferred    pathResult = 2 // budget of the tick is used up, try again next tick
+)
+
+// subCellPos is the position of a sub cell in the whole world (not relative to its grid cell)
+type subCellPos struct {
+	X int
+	Y int
+}
+
+func (w *World) subCellOf(pos shared.Vector) subCellPos {
+	return subCellPos{X: floorDiv(pos.X, w.SubCellSize), Y: floorDiv(pos.Y, w.SubCellSize)}
+}
+
+func (w *World) subCellCenter(p subCellPos) shared.Vector {
+	return shared.Vector{X: p.X*w.SubCellSize + w.SubCellSize/2, Y: p.Y*w.SubCellSize + w.SubCellSize/2}
+}
+
+// gridCellOf returns the position of the grid cell that contains sub cell p
+func (w *World) gridCellOf(p subCellPos) shared.Vector {
+	return shared.Vector{X: floorDiv(p.X, w.SubCells), Y: floorDiv(p.Y, w.SubCells)}
+}
+
+type pathKey struct {
+	from subCellPos
+	to   subCellPos
+}
+
+type cachedPath struct {
+	path    []subCellPos
+	expires time.Time
+}
+
+// Pathfinder is shared by all cells of a grid manager
+type Pathfinder struct {
+	gm         *GridManager
+	cache      map[pathKey]cachedPath
+	cacheMutex sync.Mutex
+}
+
+func NewPathfinder(gm *GridManager) *Pathfinder {
+	return &Pathfinder{
+		gm:         gm,
+		cache:      make(map[pathKey]cachedPath),
+		cacheMutex: sync.Mutex{},
+	}
+}
+
+// FindPath returns the waypoints from `from` to `to`, the last one is `to` itself. Every expanded
+// sub cell is taken from budget, a search that starts without budget left is deferred.
+func (p *Pathfinder) FindPath(from shared.Vector, to shared.Vector, budget *int, now time.Time) ([]shared.Vector, pathResult) {
+	w := p.gm.world
+	start := w.subCellOf(from)
+	goal := w.subCellOf(to)
+
+	if start == goal {
+		return []shared.Vector{to}, pathFound
+	}
+	if !w.TerrainAt(w.subCellCenter(goal)).Passable() {
+		return nil, pathUnreachable
+	}
+
+	key := pathKey{from: start, to: goal}
+	path, ok := p.cached(key, now)
+	if !ok {
+		if *budget <= 0 {
+			return nil, pathDeferred
+		}
+
+		var expanded int
+		path, expanded = p.search(start, goal)
+		*budget -= expanded
+		if path == nil {
+			return nil, pathUnreachable
+		}
+		p.store(key, path, now)
+	}
+
+	// the start is where the npc already is
+	waypoints := make([]shared.Vector, 0, len(path)-1)
+	for _, node := range path[1:] {
+		waypoints = append(waypoints, w.subCellCenter(node))
+	}
+	waypoints[len(waypoints)-1] = to
+	return waypoints, pathFound
+}
+
+func (p *Pathfinder) cached(key pathKey, now time.Time) ([]subCellPos, bool) {
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+
+	entry, ok := p.cache[key]
+	if !ok || now.After(entry.expires) {
+		return nil, false
+	}
+	return entry.path, true
+}
+
+func (p *Pathfinder) store(key pathKey, path []subCellPos, now time.Time) {
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+
+	if len(p.cache) >= pathCacheSize {
+		for k, entry := range p.cache {
+			if now.After(entry.expires) {
+				delete(p.cache, k)
+			}
+		}
+	}
+	// everything is still fresh, start over instead of growing without limit
+	if len(p.cache) >= pathCacheSize {
+		p.cache = make(map[pathKey]cachedPath)
+	}
+
+	p.cache[key] = cachedPath{path: path, expires: now.Add(pathCacheTTL)}
+}
+
+// search runs A* from start to goal, returns nil if the goal can't be reached within the search area
+func (p *Pathfinder) search(start subCellPos, goal subCellPos) ([]subCellPos, int) {
+	w := p.gm.world
+	area := w.searchArea(start, goal)
+	obstacles := p.obstacles(area)
+
+	blocked := func(node subCellPos) bool {
+		cell := w.gridCellOf(node)
+		if cell.X < area.min.X || cell.X > area.max.X || cell.Y < area.min.Y || cell.Y > area.max.Y {
+			return true
+		}
+		if node != start && node != goal && obstacles[node] {
+			return true
+		}
+		return !w.TerrainAt(w.subCellCenter(node)).Passable()
+	}
+
+	open := &pathQueue{}
+	heap.Push(open, &pathNode{pos: start, cost: 0, estimate: octile(start, goal)})
+	costs := map[subCellPos]float64{start: 0}
+	cameFrom := map[subCellPos]subCellPos{}
+	closed := map[subCellPos]bool{}
+
+	expanded := 0
+	for open.Len() > 0 && expanded < maxSearchNodes {
+		current := heap.Pop(open).(*pathNode)
+		if closed[current.pos] {
+			continue
+		}
+		if current.pos == goal {
+			return reconstructPath(cameFrom, start, goal), expanded
+		}
+		closed[current.pos] = true
+		expanded++
+
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+
+				next := subCellPos{X: current.pos.X + dx, Y: current.pos.Y + dy}
+				if closed[next] || blocked(next) {
+					continue
+				}
+				// don't cut corners, a diagonal step needs both straight neighbours to be free
+				if dx != 0 && dy != 0 && (blocked(subCellPos{X: current.pos.X + dx, Y: current.pos.Y}) || blocked(subCellPos{X: current.pos.X, Y: current.pos.Y + dy})) {
+					continue
+				}
+
+				step := 1.0
+				if dx != 0 && dy != 0 {
+					step = math.Sqrt2
+				}
+				cost := current.cost + step/w.TerrainAt(w.subCellCenter(next)).SpeedFactor()
+
+				if known, ok := costs[next]; ok && known <= cost {
+					continue
+				}
+				costs[next] = cost
+				cameFrom[next] = current.pos
+				heap.Push(open, &pathNode{pos: next, cost: cost, estimate: cost + octile(next, goal)})
+			}
+		}
+	}
+
+	return nil, expanded
+}
+
+// cellArea is a box of grid cells, min and max are inclusive
+type cellArea struct {
+	min shared.Vector
+	max shared.Vector
+}
+
+// searchArea is the box of grid cells around start and goal including their neighbours
+func (w *World) searchArea(start subCellPos, goal subCellPos) cellArea {
+	a := w.gridCellOf(start)
+	b := w.gridCellOf(goal)
+
+	return cellArea{
+		min: shared.Vector{X: minInt(a.X, b.X) - 1, Y: minInt(a.Y, b.Y) - 1},
+		max: shared.Vector{X: maxInt(a.X, b.X) + 1, Y: maxInt(a.Y, b.Y) + 1},
+	}
+}
+
+// obstacles returns the sub cells covered by resources in the existing cells of area
+func (p *Pathfinder) obstacles(area cellArea) map[subCellPos]bool {
+	w := p.gm.world
+	obstacles := make(map[subCellPos]bool)
+
+	for x := area.min.X; x <= area.max.X; x++ {
+		for y := area.min.Y; y <= area.max.Y; y++ {
+			cell, ok := p.gm.findCell(x, y)
+			if !ok {
+				continue
+			}
+
+			for _, r := range cell.GetResources() {
+				if !blocksPath(r) {
+					continue
+				}
+
+				buildable, ok := findBuildable(string(r.ResourceType))
+				if !ok {
+					obstacles[w.subCellOf(r.Pos)] = true
+					continue
+				}
+
+				covered := w.snapFootprint(r.Pos, buildable.Footprint)
+				for x := covered.min.X; x < covered.max.X; x += w.SubCellSize {
+					for y := covered.min.Y; y < covered.max.Y; y += w.SubCellSize {
+						obstacles[w.subCellOf(shared.Vector{X: x, Y: y})] = true
+					}
+				}
+			}
+		}
+	}
+	return obstacles
+}
+
+// blocksPath is true for trees, stones and structures, dropped resources can be walked over
+func blocksPath(r resource.Resource) bool {
+	return !r.IsLootable
+}
+
+// octile is the shortest distance between two sub cells with diagonal steps on the fastest terrain
+func octile(a subCellPos, b subCellPos) float64 {
+	dx := float64(Abs(a.X - b.X))
+	dy := float64(Abs(a.Y - b.Y))
+	return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+}
+
+func reconstructPath(cameFrom map[subCellPos]subCellPos, start subCellPos, goal subCellPos) []subCellPos {
+	path := []subCellPos{goal}
+	for current := goal; current != start; {
+		current = cameFrom[current]
+		path = append(path, current)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+type pathNode struct {
+	pos      subCellPos
+	cost     float64 // from the start
+	estimate float64 // cost plus the heuristic to the goal
+}
+
+// pathQueue is the open set of the search, ordered by the estimated total cost
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].estimate < q[j].estimate }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(*pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
@@ -0,0 +1,88 @@
+package root
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// findLand returns the center sub cell of the first 3x3 block of land around the spawn
+func findLand(w *World) (subCellPos, bool) {
+	for x := -60; x <= 60; x++ {
+		for y := -60; y <= 60; y++ {
+			if w.onLand(w.snapFootprint(w.subCellCenter(subCellPos{X: x, Y: y}), 3)) {
+				return subCellPos{X: x, Y: y}, true
+			}
+		}
+	}
+	return subCellPos{}, false
+}
+
+func TestFindPathAvoidsObstacles(t *testing.T) {
+	channel := make(chan *GridCell)
+	go consumer(channel)
+	gm := NewGridManager(context.Background(), &sync.WaitGroup{}, channel, DefaultConfig(), NewWorld(DefaultConfig().World), bestiary.Default(), item.Default())
+
+	w := gm.world
+	center, ok := findLand(w)
+	if !ok {
+		t.Skip("no land around the spawn")
+	}
+	from := w.subCellCenter(subCellPos{X: center.X - 1, Y: center.Y})
+	to := w.subCellCenter(subCellPos{X: center.X + 1, Y: center.Y})
+
+	cell := gm.GetCellFromPos(w.subCellCenter(center))
+	cell.AddResource(resource.NewResource(resource.Blockade, w.subCellCenter(center), 1, 1, true, 500, false, cell.GridCellKey))
+
+	budget := 100
+	path, result := gm.pathfinder.FindPath(from, to, &budget, time.Now())
+	if result != pathFound {
+		t.Fatalf("expected a path around the blockade, got %d", result)
+	}
+	if path[len(path)-1] != to {
+		t.Errorf("path ends at %+v instead of %+v", path[len(path)-1], to)
+	}
+	for _, waypoint := range path {
+		if w.subCellOf(waypoint) == center {
+			t.Fatalf("path goes through the blockade %+v", path)
+		}
+	}
+	if budget >= 100 {
+		t.Error("search did not use the budget")
+	}
+
+	// the same search is cached and doesn't need any budget
+	budget = 0
+	if _, result := gm.pathfinder.FindPath(from, to, &budget, time.Now()); result != pathFound {
+		t.Errorf("expected the cached path, got %d", result)
+	}
+	if _, result := gm.pathfinder.FindPath(from, to, &budget, time.Now().Add(pathCacheTTL*2)); result != pathDeferred {
+		t.Errorf("expected the search to wait for the next tick, got %d", result)
+	}
+}
+
+func TestFindPathToWater(t *testing.T) {
+	channel := make(chan *GridCell)
+	go consumer(channel)
+	gm := NewGridManager(context.Background(), &sync.WaitGroup{}, channel, DefaultConfig(), NewWorld(DefaultConfig().World), bestiary.Default(), item.Default())
+
+	w := gm.world
+	for x := -200; x <= 200; x++ {
+		water := w.subCellCenter(subCellPos{X: x, Y: 0})
+		if w.TerrainAt(water) != Water {
+			continue
+		}
+
+		budget := 100
+		if _, result := gm.pathfinder.FindPath(shared.Vector{X: 0, Y: 0}, water, &budget, time.Now()); result != pathUnreachable {
+			t.Fatalf("expected deep water to be unreachable, got %d", result)
+		}
+		return
+	}
+	t.Skip("no deep water around the spawn")
+}
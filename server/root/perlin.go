@@ -3,8 +3,6 @@ package root
 import (
 	"math"
 	"ws-game/shared"
-
-	perl2 "github.com/aquilax/go-perlin"
 )
 
 type TerrainType string
@@ -14,48 +12,76 @@ const (
 	Water        TerrainType = "Water"
 	ShallowWater TerrainType = "ShallowWater"
 	Sand         TerrainType = "Sand"
+	Rock         TerrainType = "Rock"
+	Mud          TerrainType = "Mud"
+	Snow         TerrainType = "Snow"
 )
 
 type SubCell struct {
 	Pos         shared.Vector `json:"pos"`
 	TerrainType TerrainType   `json:"terrainType"`
+	Biome       Biome         `json:"biome"`
+}
+
+// worldNoise contains one noise layer per property of the terrain
+type worldNoise struct {
+	elevation   *shared.Perlin
+	moisture    *shared.Perlin
+	temperature *shared.Perlin
 }
 
-func getTerrainType(noise float64) TerrainType {
-	if noise < 0.35 {
-		return Grass
+func newTerrainNoise(seed int64) worldNoise {
+	return worldNoise{
+		elevation:   shared.NewPerlin(2.0, 2.0, 3, seed),
+		moisture:    shared.NewPerlin(2.0, 2.0, 3, seed+1),
+		temperature: shared.NewPerlin(2.0, 2.0, 3, seed+2),
 	}
-	if noise > 0.35 && noise < 0.45 {
-		return Sand
+}
+
+// getTerrainType maps the depth (distance of the elevation noise from 0) to water and shore,
+// the remaining land gets the terrain of its biome
+func getTerrainType(depth float64, biome Biome) TerrainType {
+	if depth > 0.49 {
+		return Water
 	}
-	if noise > 0.45 && noise < 0.49 {
+	if depth > 0.45 {
 		return ShallowWater
 	}
+	if depth > 0.35 {
+		return Sand
+	}
 
-	return Water
+	return biomes[biome].terrain
 }
 
-func getSubCells(cellX int, cellY int) []SubCell {
-	alpha := 2.0
-	beta := 2.0
-	var n int32 = 3
-	pn := perl2.NewPerlin(alpha, beta, n, 54000)
+// subCellTerrain returns terrain and biome of sub cell x/y inside grid cell cellX/cellY
+func (w *World) subCellTerrain(cellX int, cellY int, x int, y int) (TerrainType, Biome) {
+	// one grid cell spans one unit of noise input, whatever the number of its sub cells
+	step := 1 / float64(w.SubCells)
+	mx := float64(x) * step
+	my := float64(y) * step
+	vx := (float64(cellX) + mx) * .5
+	vy := (float64(cellY) + my) * .5
+	depth := math.Abs(w.noise.elevation.Noise2D(vx, vy))
 
-	cells := []SubCell{}
+	// biomes are larger than the lakes and hills of the elevation
+	moisture := w.noise.moisture.Noise2D(vx*biomeScale, vy*biomeScale)
+	temperature := w.noise.temperature.Noise2D(vx*biomeScale, vy*biomeScale)
 
-	for x := 0; x < SubCells; x++ {
-		for y := 0; y < SubCells; y++ {
-			mx := float64(x) * 0.05
-			my := float64(y) * 0.05
-			vx := (float64(cellX) + mx) * .5
-			vy := (float64(cellY) + my) * .5
-			nv := math.Abs(pn.Noise2D(vx, vy))
+	biome := getBiome(depth, moisture, temperature)
+	return getTerrainType(depth, biome), biome
+}
 
-			// fmt.Printf("%f %f %f %f= %f\n", vx, vy, mx, my, nv)
+func (w *World) getSubCells(cellX int, cellY int) []SubCell {
+	cells := []SubCell{}
 
+	for x := 0; x < w.SubCells; x++ {
+		for y := 0; y < w.SubCells; y++ {
+			terrain, biome := w.subCellTerrain(cellX, cellY, x, y)
 			cells = append(cells, SubCell{
 				Pos:         shared.Vector{X: x, Y: y},
-				TerrainType: getTerrainType(nv),
+				TerrainType: terrain,
+				Biome:       biome,
 			})
 		}
 	}
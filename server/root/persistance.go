@@ -0,0 +1,146 @@
+package root
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// connected players get written to the store in this interval
+	// so a crash only loses the progress since the last autosave
+	PlayerAutosaveInterval = 30 * time.Second
+)
+
+// PlayerStore is the backend that keeps ClientPersistance entries between connects
+// and server restarts. Load returns false if no entry exists for the uuid.
+type PlayerStore interface {
+	Load(uuid string) (ClientPersistance, bool, error)
+	Save(uuid string, entry ClientPersistance) error
+}
+
+// MemoryPlayerStore keeps everything in memory, progress is lost on restart
+type MemoryPlayerStore struct {
+	entries      map[string]ClientPersistance
+	entriesMutex sync.Mutex
+}
+
+func NewMemoryPlayerStore() *MemoryPlayerStore {
+	return &MemoryPlayerStore{
+		entries:      make(map[string]ClientPersistance),
+		entriesMutex: sync.Mutex{},
+	}
+}
+
+func (s *MemoryPlayerStore) Load(uuid string) (ClientPersistance, bool, error) {
+	s.entriesMutex.Lock()
+	defer s.entriesMutex.Unlock()
+
+	entry, ok := s.entries[uuid]
+	return entry, ok, nil
+}
+
+func (s *MemoryPlayerStore) Save(uuid string, entry ClientPersistance) error {
+	s.entriesMutex.Lock()
+	defer s.entriesMutex.Unlock()
+
+	s.entries[uuid] = entry
+	return nil
+}
+
+// FilePlayerStore stores one json file per player inside dir
+type FilePlayerStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func NewFilePlayerStore(dir string) (*FilePlayerStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FilePlayerStore{
+		dir:   dir,
+		mutex: sync.Mutex{},
+	}, nil
+}
+
+func (s *FilePlayerStore) path(playerUUID string) (string, error) {
+	// the uuid is provided by the client -> make sure it can't be used to escape the store directory
+	if _, err := uuid.Parse(playerUUID); err != nil {
+		return "", fmt.Errorf("invalid player uuid %q", playerUUID)
+	}
+	return filepath.Join(s.dir, playerUUID+".json"), nil
+}
+
+func (s *FilePlayerStore) Load(playerUUID string) (ClientPersistance, bool, error) {
+	entry := ClientPersistance{}
+
+	path, err := s.path(playerUUID)
+	if err != nil {
+		return entry, false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false, err
+	}
+
+	return entry, true, nil
+}
+
+func (s *FilePlayerStore) Save(playerUUID string, entry ClientPersistance) error {
+	path, err := s.path(playerUUID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes to a temp file first and renames it afterwards,
+// a crash while writing never leaves a half written file behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
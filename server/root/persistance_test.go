@@ -0,0 +1,65 @@
+package root
+
+import (
+	"testing"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+
+	"github.com/google/uuid"
+)
+
+func TestFilePlayerStore(t *testing.T) {
+	store, err := NewFilePlayerStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playerUUID := uuid.New().String()
+
+	_, ok, err := store.Load(playerUUID)
+	if err != nil || ok {
+		t.Fatalf("expected no entry for new player, got ok=%v err=%v", ok, err)
+	}
+
+	entry := ClientPersistance{
+		Pos: shared.Vector{X: 1200, Y: -300},
+		Inventory: map[resource.ResourceType]resource.Resource{
+			resource.Log: {ResourceType: resource.Log, Quantity: 7},
+		},
+		Hitpoints: shared.Hitpoints{Current: 50, Max: 100},
+		Equipment: Equipment{item.MainHandSlot: "a"},
+	}
+
+	if err := store.Save(playerUUID, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok, err := store.Load(playerUUID)
+	if err != nil || !ok {
+		t.Fatalf("expected stored entry, got ok=%v err=%v", ok, err)
+	}
+
+	if loaded.Pos != entry.Pos || loaded.Hitpoints != entry.Hitpoints {
+		t.Errorf("loaded %+v does not match saved %+v", loaded, entry)
+	}
+
+	if loaded.Equipment[item.MainHandSlot] != "a" {
+		t.Errorf("expected the equipment to be stored, got %+v", loaded.Equipment)
+	}
+
+	if loaded.Inventory[resource.Log].Quantity != 7 {
+		t.Errorf("expected 7 logs got %d", loaded.Inventory[resource.Log].Quantity)
+	}
+}
+
+func TestFilePlayerStoreRejectsInvalidUUID(t *testing.T) {
+	store, err := NewFilePlayerStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save("../../etc/passwd", ClientPersistance{}); err == nil {
+		t.Error("expected error for path like uuid")
+	}
+}
@@ -0,0 +1,158 @@
+package root
+
+import (
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// Buildable is a structure players can place in the world
+type Buildable struct {
+	ResourceType resource.ResourceType  `json:"resourceType"`
+	Costs        []resource.ResourceMin `json:"costs"`
+	Hitpoints    int                    `json:"hitpoints"`
+	Footprint    int                    `json:"footprint"` // width and height in sub cells
+	IsSolid      bool                   `json:"isSolid"`
+}
+
+// buildables are sent to the clients with the game config, the order is the order of the build menu
+var buildables = []Buildable{
+	{
+		ResourceType: resource.Blockade,
+		Costs:        []resource.ResourceMin{{ResourceType: resource.Brick, Quantity: 5}},
+		Hitpoints:    500,
+		Footprint:    1,
+		IsSolid:      true,
+	},
+	{
+		ResourceType: resource.WoodBlockade,
+		Costs:        []resource.ResourceMin{{ResourceType: resource.Log, Quantity: 5}},
+		Hitpoints:    200,
+		Footprint:    1,
+		IsSolid:      true,
+	},
+	{
+		ResourceType: resource.Furnace,
+		Costs:        []resource.ResourceMin{{ResourceType: resource.Brick, Quantity: 10}},
+		Hitpoints:    1000,
+		Footprint:    1,
+		IsSolid:      true,
+	},
+}
+
+// a player standing closer than this to a footprint blocks it
+const placementPlayerRadius = 20
+
+func findBuildable(resourceType string) (Buildable, bool) {
+	for _, b := range buildables {
+		if string(b.ResourceType) == resourceType {
+			return b, true
+		}
+	}
+	return Buildable{}, false
+}
+
+// footprint is the area a structure occupies, min is inclusive and max exclusive
+type footprint struct {
+	min shared.Vector
+	max shared.Vector
+}
+
+// snapFootprint centers the footprint of size sub cells on the sub cell that contains pos
+func (w *World) snapFootprint(pos shared.Vector, size int) footprint {
+	originX := (floorDiv(pos.X, w.SubCellSize) - (size-1)/2) * w.SubCellSize
+	originY := (floorDiv(pos.Y, w.SubCellSize) - (size-1)/2) * w.SubCellSize
+
+	return footprint{
+		min: shared.Vector{X: originX, Y: originY},
+		max: shared.Vector{X: originX + size*w.SubCellSize, Y: originY + size*w.SubCellSize},
+	}
+}
+
+func (f footprint) center() shared.Vector {
+	return shared.Vector{X: (f.min.X + f.max.X) / 2, Y: (f.min.Y + f.max.Y) / 2}
+}
+
+// contains checks if pos is inside the footprint grown by margin on every side
+func (f footprint) contains(pos shared.Vector, margin int) bool {
+	return pos.X >= f.min.X-margin && pos.X < f.max.X+margin && pos.Y >= f.min.Y-margin && pos.Y < f.max.Y+margin
+}
+
+// onLand checks the terrain of every sub cell of the footprint
+func (w *World) onLand(f footprint) bool {
+	for x := f.min.X; x < f.max.X; x += w.SubCellSize {
+		for y := f.min.Y; y < f.max.Y; y += w.SubCellSize {
+			terrain := w.TerrainAt(shared.Vector{X: x, Y: y})
+			if terrain == Water || terrain == ShallowWater {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (h *Hub) HandlePlayerPlacedResource(event PlayerPlacedResourceEvent, c *Client) {
+	buildable, ok := findBuildable(event.ResourceType)
+	if !ok {
+		return
+	}
+
+	area := h.world.snapFootprint(event.Pos, buildable.Footprint)
+	pos := area.center()
+
+	if !h.canPlace(area, c) {
+		return
+	}
+
+	if !c.takeResources(buildable.Costs) {
+		return
+	}
+	for _, cost := range buildable.Costs {
+		c.send <- NewUpdateInventoryEvent(cost, true)
+	}
+
+	cellPos := h.world.cellOf(pos)
+	built := resource.NewResource(buildable.ResourceType, pos, h.ResourceManager.GetResourceId(), 1, buildable.IsSolid, buildable.Hitpoints, false, getKey(cellPos.X, cellPos.Y))
+	built.IsSolid = buildable.IsSolid
+	built.Owner = c.UUID
+	built.Team = c.Team
+	built.DecaysAt = time.Now().Add(h.config.Structures.DecayDelay).Unix()
+
+	h.ResourceManager.AddResource <- built
+}
+
+// canPlace validates the footprint against the range of the player, terrain, resources and players
+func (h *Hub) canPlace(area footprint, c *Client) bool {
+	clientPos := c.GetPos()
+	center := area.center()
+	if center.Dist(&clientPos) > float64(h.config.Player.BuildRange) {
+		return false
+	}
+
+	if !h.world.onLand(area) {
+		return false
+	}
+
+	// footprints are at most a few sub cells, the neighbouring cells contain everything that can overlap
+	cellPos := h.world.cellOf(center)
+	for x := cellPos.X - 1; x <= cellPos.X+1; x++ {
+		for y := cellPos.Y - 1; y <= cellPos.Y+1; y++ {
+			for _, r := range h.GridManager.GetCell(x, y).GetResources() {
+				// loot on the ground doesn't block building
+				if !r.IsLootable && area.contains(r.Pos, 0) {
+					return false
+				}
+			}
+		}
+	}
+
+	h.ClientMutex.Lock()
+	defer h.ClientMutex.Unlock()
+	for _, other := range h.clients {
+		if other.isLoggedIn() && area.contains(other.GetPos(), placementPlayerRadius) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func TestSnapFootprint(t *testing.T) {
+	w := NewWorld(DefaultConfig().World)
+	area := w.snapFootprint(shared.Vector{X: w.SubCellSize + 3, Y: -2}, 1)
+	if area.min != (shared.Vector{X: w.SubCellSize, Y: -w.SubCellSize}) || area.max != (shared.Vector{X: 2 * w.SubCellSize, Y: 0}) {
+		t.Fatalf("unexpected footprint %+v", area)
+	}
+
+	// odd sizes are centered on the sub cell of the cursor
+	area = w.snapFootprint(shared.Vector{X: 1, Y: 1}, 3)
+	if area.center() != (shared.Vector{X: w.SubCellSize / 2, Y: w.SubCellSize / 2}) {
+		t.Fatalf("footprint is not centered %+v", area)
+	}
+}
+
+// findSubCell returns the first sub cell around pos that is (not) on land
+func findSubCell(w *World, pos shared.Vector, land bool) (footprint, bool) {
+	for dx := -100; dx <= 100; dx++ {
+		for dy := -100; dy <= 100; dy++ {
+			area := w.snapFootprint(shared.Vector{X: pos.X + dx*w.SubCellSize, Y: pos.Y + dy*w.SubCellSize}, 1)
+			if w.onLand(area) == land {
+				return area, true
+			}
+		}
+	}
+	return footprint{}, false
+}
+
+func TestCanPlace(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+
+	area, ok := findSubCell(hub.world, client.GetPos(), true)
+	if !ok {
+		t.Fatal("no land around the spawn")
+	}
+	// next to the footprint, outside of the player radius
+	client.SetPos(shared.Vector{X: area.min.X - placementPlayerRadius*2, Y: area.min.Y})
+
+	if !hub.canPlace(area, client) {
+		t.Fatalf("expected %+v to be free", area)
+	}
+
+	water, _ := findSubCell(hub.world, client.GetPos(), false)
+	client.SetPos(shared.Vector{X: water.min.X - placementPlayerRadius*2, Y: water.min.Y})
+	if hub.canPlace(water, client) {
+		t.Errorf("placed on water %+v", water)
+	}
+	client.SetPos(shared.Vector{X: area.min.X - placementPlayerRadius*2, Y: area.min.Y})
+
+	far := hub.world.snapFootprint(shared.Vector{X: area.min.X + hub.config.Player.BuildRange*2, Y: area.min.Y}, 1)
+	if hub.canPlace(far, client) {
+		t.Error("placed out of build range")
+	}
+
+	// players standing on the footprint block it
+	other := NewClient(hub, nil, hub.getClientId())
+	other.SetPos(area.center())
+	other.setLoggedIn(true)
+	hub.clients[other.Id] = other
+	if hub.canPlace(area, client) {
+		t.Error("placed on a player")
+	}
+	delete(hub.clients, other.Id)
+
+	cell := hub.GridManager.GetCellFromPos(area.center())
+	blockade := resource.NewResource(resource.Blockade, area.center(), hub.ResourceManager.GetResourceId(), 1, true, 500, false, cell.GridCellKey)
+	cell.ResourcesMutex.Lock()
+	cell.Resources[blockade.Id] = blockade
+	cell.ResourcesMutex.Unlock()
+	if hub.canPlace(area, client) {
+		t.Error("placed on another structure")
+	}
+}
+
+func TestPlaceAtNegativePosition(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.Brick, Quantity: 5})
+
+	cell := hub.GridManager.GetCell(-1, -1)
+	var area footprint
+	found := false
+	for _, subCell := range cell.SubCells {
+		area = hub.world.snapFootprint(shared.Vector{X: -hub.world.GridCellSize + subCell.Pos.X*hub.world.SubCellSize, Y: -hub.world.GridCellSize + subCell.Pos.Y*hub.world.SubCellSize}, 1)
+		client.SetPos(shared.Vector{X: area.min.X - placementPlayerRadius*2, Y: area.min.Y})
+		if hub.canPlace(area, client) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Skip("no free land in cell -1#-1")
+	}
+
+	hub.HandlePlayerPlacedResource(PlayerPlacedResourceEvent{ResourceType: string(resource.Blockade), Pos: area.center()}, client)
+
+	// the resource manager hands the structure to the cell in its own coroutine
+	deadline := time.Now().Add(time.Second)
+	for {
+		for _, r := range cell.GetResources() {
+			if r.Owner == client.UUID {
+				if r.GridCellKey != cell.GridCellKey {
+					t.Fatalf("structure in cell %s has key %s", cell.GridCellKey, r.GridCellKey)
+				}
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("structure didn't end up in cell -1#-1")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
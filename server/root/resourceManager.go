@@ -1,6 +1,7 @@
 package root
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -12,107 +13,107 @@ type ResourceManager struct {
 	resources        map[int]*resource.Resource
 	resourcesMutex   sync.Mutex
 	idCnt            int
+	idCntMutex       sync.Mutex
 	GridManager      *GridManager
 	AddResource      chan *resource.Resource
 	initCellChannel  chan *GridCell
 	cellsToInit      []*GridCell
 	cellsToInitMutex sync.Mutex
+	respawns         []ResourceRespawn // harvested trees and stones waiting to regrow, see respawn.go
+	respawnsMutex    sync.Mutex
+	config           ResourcesConfig
 }
 
-func NewResourceManager(gm *GridManager, initCellChannel chan *GridCell) *ResourceManager {
+func NewResourceManager(ctx context.Context, coros *sync.WaitGroup, gm *GridManager, initCellChannel chan *GridCell, config ResourcesConfig) *ResourceManager {
 	rM := &ResourceManager{
 		resources:        make(map[int]*resource.Resource),
 		resourcesMutex:   sync.Mutex{},
 		idCnt:            0,
+		idCntMutex:       sync.Mutex{},
 		GridManager:      gm,
 		AddResource:      make(chan *resource.Resource),
 		initCellChannel:  initCellChannel,
 		cellsToInit:      []*GridCell{},
 		cellsToInitMutex: sync.Mutex{},
+		respawns:         []ResourceRespawn{},
+		respawnsMutex:    sync.Mutex{},
+		config:           config,
 	}
 
-	go ResourcemanagerCoro(rM)
+	coros.Add(1)
+	go func() {
+		defer coros.Done()
+		ResourcemanagerCoro(ctx, rM)
+	}()
 
 	return rM
 }
 
-func intInSlice(a int, list []int) bool {
-	for _, b := range list {
-		if b == a {
-			return true
-		}
-	}
-	return false
+func (w *World) getRealResourcePos(gridCellPos shared.Vector, subCellPos shared.Vector, r shared.Rand) shared.Vector {
+	x := (gridCellPos.X * w.GridCellSize) + subCellPos.X*w.SubCellSize
+	y := (gridCellPos.Y * w.GridCellSize) + subCellPos.Y*w.SubCellSize
+	x += w.SubCellSize / 2
+	y += w.SubCellSize / 2
+	x += shared.RandIntInRangeFrom(r, -10, 10)
+	y += shared.RandIntInRangeFrom(r, -10, 10)
+	return shared.Vector{X: x, Y: y}
 }
 
-func getRealResourcePos(gridCellPos shared.Vector, spawnPositions []shared.Vector, i int) shared.Vector {
-	x := (gridCellPos.X * GridCellSize) + spawnPositions[i].X*SubCellSize
-	y := (gridCellPos.Y * GridCellSize) + spawnPositions[i].Y*SubCellSize
-	x += SubCellSize / 2
-	y += SubCellSize / 2
-	x += shared.RandIntInRange(-10, 10)
-	y += shared.RandIntInRange(-10, 10)
-	return shared.Vector{X: x, Y: y}
+// queueCellInit spawns the trees and stones of cell with the next tick
+func (rM *ResourceManager) queueCellInit(cell *GridCell) {
+	rM.cellsToInitMutex.Lock()
+	rM.cellsToInit = append(rM.cellsToInit, cell)
+	rM.cellsToInitMutex.Unlock()
 }
 
-func ResourcemanagerCoro(rM *ResourceManager) {
+func ResourcemanagerCoro(ctx context.Context, rM *ResourceManager) {
 	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case r := <-rM.AddResource:
 			rM.resourcesMutex.Lock()
 			rM.resources[r.Id] = r
 			rM.resourcesMutex.Unlock()
-			rM.GridManager.AddResource <- r
+			// the grid manager may create the cell of the resource and wait for us to take it
+			for sent := false; !sent; {
+				select {
+				case rM.GridManager.AddResource <- r:
+					sent = true
+				case cell := <-rM.initCellChannel:
+					rM.queueCellInit(cell)
+				case <-ctx.Done():
+					return
+				}
+			}
+		case id := <-rM.GridManager.RemovedResources:
+			rM.resourcesMutex.Lock()
+			delete(rM.resources, id)
+			rM.resourcesMutex.Unlock()
 		case cell := <-rM.initCellChannel:
-			rM.cellsToInitMutex.Lock()
-			rM.cellsToInit = append(rM.cellsToInit, cell)
-			rM.cellsToInitMutex.Unlock()
+			rM.queueCellInit(cell)
 		case <-t.C:
 			rM.cellsToInitMutex.Lock()
 			rM.resourcesMutex.Lock()
 			for _, cell := range rM.cellsToInit {
-
-				numTrees := shared.RandIntInRange(20, 50)
-				numStones := shared.RandIntInRange(5, 20)
-
-				spawnPositions := []shared.Vector{}
-				usedSpawnIndicies := []int{}
-
-				for len(spawnPositions) < numStones+numTrees {
-					subCellIndicie := shared.RandIntInRange(0, len(cell.SubCells)-1)
-					subCell := cell.SubCells[subCellIndicie]
-					if subCell.TerrainType != Water && subCell.TerrainType != ShallowWater && !intInSlice(subCellIndicie, usedSpawnIndicies) {
-						spawnPositions = append(spawnPositions, subCell.Pos)
-						usedSpawnIndicies = append(usedSpawnIndicies, subCellIndicie)
-					}
-				}
-
-				newResources := make(map[int]resource.Resource)
-				for i := 0; i < numStones; i++ {
-					pos := getRealResourcePos(cell.Pos, spawnPositions, i)
-
-					id := rM.GetResourceId()
-					r := resource.NewResource(resource.Stone, pos, id, 100, true, 100, false, cell.GridCellKey)
-					rM.resources[r.Id] = r
-					newResources[r.Id] = *r
-					cell.ResourcesMutex.Lock()
-					cell.Resources[r.Id] = r
+				cell.ResourcesMutex.Lock()
+				if cell.resourcesSpawned {
 					cell.ResourcesMutex.Unlock()
+					continue
 				}
 
-				// spawn trees
-				for i := numStones; i < numTrees+numStones; i++ {
-					pos := getRealResourcePos(cell.Pos, spawnPositions, i)
-
+				for _, spawn := range cell.world.generateResources(cell.Pos, cell.SubCells, rM.config) {
 					id := rM.GetResourceId()
-					r := resource.NewResource(resource.Tree, pos, id, 100, true, 100, false, cell.GridCellKey)
+					r := resource.NewResource(spawn.resourceType, spawn.pos, id, 100, true, 100, false, cell.GridCellKey)
 					rM.resources[r.Id] = r
-					newResources[r.Id] = *r
 					cell.Resources[r.Id] = r
 				}
 
-				cell.Broadcast <- NewResourcePositionsEvent(newResources)
+				cell.resourcesSpawned = true
+				cell.ResourcesMutex.Unlock()
 			}
 			// all new cells initialized
 			rM.cellsToInit = []*GridCell{}
@@ -146,12 +147,44 @@ func (gm *ResourceManager) SetResource(r *resource.Resource) {
 
 func (gm *ResourceManager) DeleteResource(id int) {
 	gm.resourcesMutex.Lock()
-	gm.resources[id].SetRemove(true)
-	delete(gm.resources, id)
+	// might have decayed in the meantime
+	if r, ok := gm.resources[id]; ok {
+		r.SetRemove(true)
+		delete(gm.resources, id)
+	}
 	gm.resourcesMutex.Unlock()
 }
 
 func (rm *ResourceManager) GetResourceId() int {
+	rm.idCntMutex.Lock()
+	defer rm.idCntMutex.Unlock()
+
 	rm.idCnt++
 	return rm.idCnt
 }
+
+func (rm *ResourceManager) getIdCnt() int {
+	rm.idCntMutex.Lock()
+	defer rm.idCntMutex.Unlock()
+
+	return rm.idCnt
+}
+
+func (rm *ResourceManager) setIdCnt(idCnt int) {
+	rm.idCntMutex.Lock()
+	defer rm.idCntMutex.Unlock()
+
+	rm.idCnt = idCnt
+}
+
+// restoreResources adds resources from a world snapshot to the manager and their cell
+func (rm *ResourceManager) restoreResources(cell *GridCell, resources []*resource.Resource) {
+	rm.resourcesMutex.Lock()
+	cell.ResourcesMutex.Lock()
+	for _, r := range resources {
+		rm.resources[r.Id] = r
+		cell.Resources[r.Id] = r
+	}
+	cell.ResourcesMutex.Unlock()
+	rm.resourcesMutex.Unlock()
+}
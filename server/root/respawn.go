@@ -0,0 +1,170 @@
+package root
+
+import (
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+const (
+	// due respawns are processed in this interval
+	RespawnCheckInterval = time.Second
+
+	// spawn points that are blocked by a player or a structure are tried again after this delay
+	respawnRetryDelay = 30 * time.Second
+)
+
+// ResourceRespawn is a harvested tree or stone that regrows at its spawn point
+type ResourceRespawn struct {
+	ResourceType resource.ResourceType `json:"resourceType"`
+	Pos          shared.Vector         `json:"pos"`
+	At           time.Time             `json:"at"`
+}
+
+// isRespawnable is true for the trees and stones of the world generation
+func isRespawnable(r resource.Resource) bool {
+	return r.Owner == "" && !r.IsLootable && (r.ResourceType == resource.Tree || r.ResourceType == resource.Stone)
+}
+
+// ScheduleRespawn regrows a destroyed resource after the respawn delay, other resources are ignored
+func (rm *ResourceManager) ScheduleRespawn(r resource.Resource) {
+	if !isRespawnable(r) {
+		return
+	}
+
+	rm.addRespawn(ResourceRespawn{
+		ResourceType: r.ResourceType,
+		Pos:          r.Pos,
+		At:           time.Now().Add(rm.config.RespawnDelay),
+	})
+}
+
+func (rm *ResourceManager) addRespawn(respawn ResourceRespawn) {
+	rm.respawnsMutex.Lock()
+	rm.respawns = append(rm.respawns, respawn)
+	rm.respawnsMutex.Unlock()
+}
+
+// takeDueRespawns removes the respawns that are due at now from the queue
+func (rm *ResourceManager) takeDueRespawns(now time.Time) []ResourceRespawn {
+	rm.respawnsMutex.Lock()
+	defer rm.respawnsMutex.Unlock()
+
+	due := []ResourceRespawn{}
+	pending := []ResourceRespawn{}
+	for _, respawn := range rm.respawns {
+		if now.Before(respawn.At) {
+			pending = append(pending, respawn)
+		} else {
+			due = append(due, respawn)
+		}
+	}
+	rm.respawns = pending
+
+	return due
+}
+
+func (rm *ResourceManager) getRespawns() []ResourceRespawn {
+	rm.respawnsMutex.Lock()
+	defer rm.respawnsMutex.Unlock()
+
+	return append([]ResourceRespawn{}, rm.respawns...)
+}
+
+func (rm *ResourceManager) setRespawns(respawns []ResourceRespawn) {
+	rm.respawnsMutex.Lock()
+	defer rm.respawnsMutex.Unlock()
+
+	rm.respawns = append([]ResourceRespawn{}, respawns...)
+}
+
+// respawnResources regrows the due resources and sends them to the subscribers of their cell
+func (h *Hub) respawnResources(now time.Time) {
+	for _, respawn := range h.ResourceManager.takeDueRespawns(now) {
+		if !h.canRespawn(respawn.Pos) {
+			respawn.At = now.Add(respawnRetryDelay)
+			h.ResourceManager.addRespawn(respawn)
+			continue
+		}
+
+		cell := h.GridManager.GetCellFromPos(respawn.Pos)
+		r := resource.NewResource(respawn.ResourceType, respawn.Pos, h.ResourceManager.GetResourceId(), 100, true, 100, false, cell.GridCellKey)
+		h.ResourceManager.SetResource(r)
+		cell.AddResource(r)
+
+		// inactive cells have no subscribers, new ones get the resource with the cell state
+		if cell.isActive() {
+			cell.broadcast(NewResourcePositionsEvent(map[int]resource.Resource{r.Id: *r}))
+		}
+	}
+}
+
+// canRespawn checks the terrain and that no player or structure is in the way
+func (h *Hub) canRespawn(pos shared.Vector) bool {
+	terrain := h.world.TerrainAt(pos)
+	if terrain == Water || terrain == ShallowWater {
+		return false
+	}
+
+	for _, cell := range h.GridManager.cellsAround(pos, h.world.SubCellSize) {
+		for _, r := range cell.GetResources() {
+			if r.Owner == "" {
+				continue
+			}
+
+			buildable, ok := findBuildable(string(r.ResourceType))
+			if ok && h.world.snapFootprint(r.Pos, buildable.Footprint).contains(pos, 0) {
+				return false
+			}
+		}
+	}
+
+	h.ClientMutex.Lock()
+	defer h.ClientMutex.Unlock()
+	for _, c := range h.clients {
+		clientPos := c.GetPos()
+		if c.isLoggedIn() && clientPos.Dist(&pos) < PlayerCollisionRadius {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NpcRespawn is a killed npc that comes back at its spawn point after its cooldown
+type NpcRespawn struct {
+	NpcType  string        `json:"npcType"`
+	SpawnPos shared.Vector `json:"spawnPos"`
+	At       time.Time     `json:"at"`
+}
+
+func (cell *GridCell) scheduleNpcRespawn(npc Npc, now time.Time) {
+	cell.npcRespawnsMutex.Lock()
+	cell.npcRespawns = append(cell.npcRespawns, NpcRespawn{
+		NpcType:  npc.NpcType,
+		SpawnPos: npc.spawnPos,
+		At:       now.Add(npc.respawnCooldown),
+	})
+	cell.npcRespawnsMutex.Unlock()
+}
+
+// respawnNpcs brings back the due npcs, as long as the cell is below its npc limit
+func (cell *GridCell) respawnNpcs(now time.Time) {
+	cell.NpcListMutex.Lock()
+	defer cell.NpcListMutex.Unlock()
+
+	cell.npcRespawnsMutex.Lock()
+	defer cell.npcRespawnsMutex.Unlock()
+
+	pending := []NpcRespawn{}
+	for _, respawn := range cell.npcRespawns {
+		if now.Before(respawn.At) || len(cell.NpcList) >= cell.config.Npc.MaxPerCell {
+			pending = append(pending, respawn)
+			continue
+		}
+
+		npc := NewNpcOf(respawn.SpawnPos, respawn.NpcType, cell.bestiary.Archetypes[respawn.NpcType], cell.config.Npc)
+		cell.NpcList = append(cell.NpcList, npc)
+	}
+	cell.npcRespawns = pending
+}
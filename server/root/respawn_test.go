@@ -0,0 +1,98 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func TestResourceRespawn(t *testing.T) {
+	hub := newTestHub(t)
+	area, ok := findSubCell(hub.world, shared.Vector{X: 0, Y: 0}, true)
+	if !ok {
+		t.Fatal("no land around the spawn")
+	}
+	pos := area.center()
+
+	tree := resource.NewResource(resource.Tree, pos, 1, 100, true, 100, false, "")
+	hub.ResourceManager.ScheduleRespawn(*tree)
+	hub.ResourceManager.ScheduleRespawn(*resource.NewResource(resource.Log, pos, 2, 1, false, -1, true, ""))
+	if len(hub.ResourceManager.getRespawns()) != 1 {
+		t.Fatalf("expected only the tree to respawn, got %+v", hub.ResourceManager.getRespawns())
+	}
+
+	hub.respawnResources(time.Now())
+	if len(hub.ResourceManager.getRespawns()) != 1 {
+		t.Fatal("respawned before the delay")
+	}
+
+	// a player standing on the spawn point blocks it
+	client := NewClient(hub, nil, hub.getClientId())
+	client.SetPos(pos)
+	client.setLoggedIn(true)
+	hub.SetClient(client)
+
+	later := time.Now().Add(hub.config.Resources.RespawnDelay)
+	hub.respawnResources(later)
+	respawns := hub.ResourceManager.getRespawns()
+	if len(respawns) != 1 || !respawns[0].At.Equal(later.Add(respawnRetryDelay)) {
+		t.Fatalf("expected the respawn to be retried later, got %+v", respawns)
+	}
+
+	client.setLoggedIn(false)
+	hub.respawnResources(later.Add(respawnRetryDelay))
+	if len(hub.ResourceManager.getRespawns()) != 0 {
+		t.Fatal("tree did not respawn")
+	}
+
+	found := false
+	for _, r := range hub.GridManager.GetCellFromPos(pos).GetResources() {
+		if r.ResourceType == resource.Tree && r.Pos == pos {
+			found = true
+			if _, err := hub.ResourceManager.GetResource(r.Id); err != nil {
+				t.Error("respawned tree is unknown to the resource manager")
+			}
+		}
+	}
+	if !found {
+		t.Error("tree is not in its cell")
+	}
+}
+
+func TestNpcRespawn(t *testing.T) {
+	config := DefaultConfig()
+	config.Npc.MaxPerCell = 1
+	cell := NewCell(0, 0, config, NewWorld(config.World), bestiary.Default(), item.Default())
+	cell.NpcList = []Npc{}
+
+	npc := NewNpcOf(shared.Vector{X: 10, Y: 10}, "wolf", bestiary.Default().Archetypes["wolf"], config.Npc)
+	npc.Pos = shared.Vector{X: 50, Y: 50}
+	npc.SetRemove(true)
+	cell.NpcList = append(cell.NpcList, npc)
+	cell.NpcUpdates()
+	if len(cell.NpcList) != 0 || len(cell.npcRespawns) != 1 {
+		t.Fatalf("expected the dead npc to wait for its respawn, got %d npcs", len(cell.NpcList))
+	}
+
+	cell.respawnNpcs(time.Now())
+	if len(cell.NpcList) != 0 {
+		t.Fatal("respawned before the cooldown")
+	}
+
+	// the cell is full, the respawn has to wait
+	later := time.Now().Add(npc.respawnCooldown)
+	cell.NpcList = append(cell.NpcList, NewNpc(shared.Vector{}, config.Npc))
+	cell.respawnNpcs(later)
+	if len(cell.npcRespawns) != 1 {
+		t.Fatal("respawned above the npc limit")
+	}
+
+	cell.NpcList = []Npc{}
+	cell.respawnNpcs(later)
+	if len(cell.NpcList) != 1 || cell.NpcList[0].NpcType != "wolf" || cell.NpcList[0].Pos != npc.spawnPos {
+		t.Fatalf("expected a wolf at its spawn point, got %+v", cell.NpcList)
+	}
+}
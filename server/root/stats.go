@@ -0,0 +1,171 @@
+package root
+
+import (
+	"math"
+	"time"
+	"ws-game/item"
+	"ws-game/shared"
+)
+
+// stats of a player are derived from the base attributes of the config plus the boni of the equipped items:
+//   - strength adds damage
+//   - agility adds crit chance and attack speed
+//   - vitality adds hitpoints
+//   - absorb of the items reduces the damage players take
+const (
+	baseMinDamage        = 10
+	baseMaxDamage        = 20
+	hitpointsPerVitality = 20
+
+	baseCritChance  = 0.05
+	critPerAgility  = 0.005
+	maxCritChance   = 0.75
+	baseAttackSpeed = 100 // percent, more attacks faster
+
+	// absorb that halves the damage, more absorb has diminishing returns
+	absorbHalving = 100
+
+	// time between two actions at 100% attack speed, the same as BASE_ACTION_FRAMES of the client
+	BaseActionInterval = 500 * time.Millisecond
+	// actions may arrive a bit early, the latency of the connection varies
+	actionTolerance = 0.8
+)
+
+type Attributes struct {
+	Strength int `yaml:"strength" json:"strength"`
+	Agility  int `yaml:"agility" json:"agility"`
+	Vitality int `yaml:"vitality" json:"vitality"`
+}
+
+type Stats struct {
+	Attributes      Attributes `json:"attributes"` // base plus equipment
+	MaxHitpoints    int        `json:"maxHitpoints"`
+	MinDamage       int        `json:"minDamage"`
+	MaxDamage       int        `json:"maxDamage"`
+	CritChance      float64    `json:"critChance"`  // 0 to 1
+	AttackSpeed     int        `json:"attackSpeed"` // percent of the base attack speed
+	Absorb          int        `json:"absorb"`
+	DamageReduction float64    `json:"damageReduction"` // part of the damage absorb takes away
+}
+
+func calculateStats(base Attributes, baseHitpoints int, equipped []item.Item) Stats {
+	stats := Stats{
+		Attributes:  base,
+		MinDamage:   baseMinDamage,
+		MaxDamage:   baseMaxDamage,
+		AttackSpeed: baseAttackSpeed,
+	}
+
+	for _, equippedItem := range equipped {
+		stats.MinDamage += maxInt(equippedItem.MinDamage, 0)
+		stats.MaxDamage += maxInt(equippedItem.MaxDamage, 0)
+		stats.AttackSpeed += equippedItem.AttackSpeed
+		stats.Absorb += maxInt(equippedItem.Absorb, 0)
+
+		for _, boni := range equippedItem.Boni {
+			switch boni.Attribute {
+			case item.Strength:
+				stats.Attributes.Strength += boni.Value
+			case item.Agility:
+				stats.Attributes.Agility += boni.Value
+			case item.Vitality:
+				stats.Attributes.Vitality += boni.Value
+			}
+		}
+	}
+
+	a := stats.Attributes
+	stats.MinDamage += a.Strength
+	stats.MaxDamage += a.Strength
+	stats.MaxHitpoints = maxInt(baseHitpoints+a.Vitality*hitpointsPerVitality, 1)
+	stats.CritChance = math.Max(0, math.Min(baseCritChance+float64(a.Agility)*critPerAgility, maxCritChance))
+	stats.AttackSpeed = maxInt(stats.AttackSpeed+a.Agility, 1)
+	stats.DamageReduction = float64(stats.Absorb) / float64(stats.Absorb+absorbHalving)
+
+	// negative boni must not turn the damage range around, the roll needs max > min
+	stats.MinDamage = maxInt(stats.MinDamage, 0)
+	stats.MaxDamage = maxInt(stats.MaxDamage, stats.MinDamage+1)
+
+	return stats
+}
+
+// updateStats recalculates the stats from the equipped items, the hitpoints are capped to the new max
+func (c *Client) updateStats() Stats {
+	c.EquipmentMutex.Lock()
+	c.ItemInventoryMutex.Lock()
+	equipped := c.equippedItems(c.Equipment, c.itemsByUUID())
+	c.ItemInventoryMutex.Unlock()
+	c.EquipmentMutex.Unlock()
+
+	stats := calculateStats(c.hub.config.Player.Attributes, c.hub.config.Player.Hitpoints, equipped)
+
+	c.statsMutex.Lock()
+	c.stats = stats
+	c.statsMutex.Unlock()
+
+	c.HitpointsMutex.Lock()
+	c.Hitpoints.Max = stats.MaxHitpoints
+	if c.Hitpoints.Current > c.Hitpoints.Max {
+		c.Hitpoints.Current = c.Hitpoints.Max
+	}
+	c.HitpointsMutex.Unlock()
+
+	return stats
+}
+
+func (c *Client) getStats() Stats {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+	return c.stats
+}
+
+// actionInterval is the time a player with attackSpeed needs between two hits
+func actionInterval(attackSpeed int) time.Duration {
+	return time.Duration(float64(BaseActionInterval) * float64(baseAttackSpeed) / float64(maxInt(attackSpeed, 1)))
+}
+
+// tryAction starts the cooldown of a hit or loot, false if the player is still on cooldown
+func (c *Client) tryAction(now time.Time) bool {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	cooldown := time.Duration(float64(actionInterval(c.stats.AttackSpeed)) * actionTolerance)
+	if now.Sub(c.lastAction) < cooldown {
+		return false
+	}
+	c.lastAction = now
+	return true
+}
+
+func (c *Client) DamageRoll() (int, bool) {
+	stats := c.getStats()
+
+	damage := shared.RandIntInRange(stats.MinDamage, stats.MaxDamage)
+	isCrit := shared.GlobalRand.Float64() < stats.CritChance
+	if isCrit {
+		damage *= 2
+	}
+	return damage, isCrit
+}
+
+// absorbDamage is the part of damage that gets through the absorb of the player
+func (c *Client) absorbDamage(damage int) int {
+	reduction := c.getStats().DamageReduction
+	return int(math.Round(float64(damage) * (1 - reduction)))
+}
+
+// equipmentChanged recalculates the stats and sends them to the player, others see the new max hitpoints
+func (h *Hub) equipmentChanged(c *Client) {
+	before := c.getStats()
+	stats := c.updateStats()
+
+	c.send <- NewPlayerStatsEvent(stats)
+
+	if stats.MaxHitpoints != before.MaxHitpoints {
+		c.HitpointsMutex.Lock()
+		hitpoints := c.Hitpoints
+		c.HitpointsMutex.Unlock()
+
+		c.getGridCell().AddEventToBroadcast(NewUpdatePlayerEvent(c.Id, hitpoints, 0, 0, false))
+	}
+}
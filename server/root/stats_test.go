@@ -0,0 +1,80 @@
+package root
+
+import (
+	"math"
+	"testing"
+	"time"
+	"ws-game/item"
+)
+
+func TestCalculateStats(t *testing.T) {
+	base := Attributes{Strength: 5, Agility: 10, Vitality: 5}
+
+	naked := calculateStats(base, 1000, []item.Item{})
+	if naked.MaxHitpoints != 1000+5*hitpointsPerVitality || naked.MinDamage != baseMinDamage+5 || naked.MaxDamage != baseMaxDamage+5 {
+		t.Fatalf("unexpected base stats %+v", naked)
+	}
+	if naked.DamageReduction != 0 || naked.AttackSpeed != baseAttackSpeed+10 {
+		t.Fatalf("unexpected base stats %+v", naked)
+	}
+
+	armour := item.Item{ItemType: item.Armour, Absorb: absorbHalving, Boni: []item.Boni{{Attribute: item.Vitality, Value: 10}}}
+	sword := item.Item{ItemType: item.Weapon, MinDamage: 100, MaxDamage: 200, AttackSpeed: -20, Boni: []item.Boni{{Attribute: item.Agility, Value: 1000}}}
+
+	equipped := calculateStats(base, 1000, []item.Item{armour, sword})
+	if equipped.Attributes.Vitality != 15 || equipped.MaxHitpoints != 1000+15*hitpointsPerVitality {
+		t.Errorf("vitality of the armour wasn't applied %+v", equipped)
+	}
+	if equipped.MinDamage != naked.MinDamage+100 || equipped.MaxDamage != naked.MaxDamage+200 {
+		t.Errorf("damage of the sword wasn't applied %+v", equipped)
+	}
+	if equipped.CritChance != maxCritChance {
+		t.Errorf("expected the crit chance to be capped, got %f", equipped.CritChance)
+	}
+	if math.Abs(equipped.DamageReduction-0.5) > 1e-9 {
+		t.Errorf("expected absorb to halve the damage, got %f", equipped.DamageReduction)
+	}
+
+	// negative boni don't break the damage roll
+	cursed := item.Item{Boni: []item.Boni{{Attribute: item.Strength, Value: -100}}}
+	weak := calculateStats(base, 1000, []item.Item{cursed})
+	if weak.MinDamage < 0 || weak.MaxDamage <= weak.MinDamage {
+		t.Errorf("invalid damage range %d to %d", weak.MinDamage, weak.MaxDamage)
+	}
+}
+
+func TestHitsWaitForTheAttackSpeed(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.setLoggedIn(true)
+
+	cell := hub.GridManager.GetCellFromPos(client.GetPos())
+	npc := NewNpc(client.GetPos(), hub.config.Npc)
+	cell.NpcListMutex.Lock()
+	cell.NpcList = append(cell.NpcList, npc)
+	cell.NpcListMutex.Unlock()
+
+	// a modified client sends hits as fast as it can
+	for i := 0; i < 10; i++ {
+		hub.HandleNpcHit(HitNpcEvent{UUID: npc.UUID}, client)
+	}
+	cell.NpcListMutex.Lock()
+	hitpoints := cell.NpcList[len(cell.NpcList)-1].Hitpoints.Current
+	cell.NpcListMutex.Unlock()
+	if lost := npc.Hitpoints.Max - hitpoints; lost <= 0 || lost > 2*client.getStats().MaxDamage {
+		t.Fatalf("expected a single hit, the npc lost %d hitpoints", lost)
+	}
+
+	// agility makes the cooldown shorter
+	now := time.Now()
+	slow := actionInterval(client.getStats().AttackSpeed)
+	if !client.tryAction(now.Add(slow)) || client.tryAction(now.Add(slow+slow/2)) {
+		t.Fatal("expected one action per interval")
+	}
+	client.statsMutex.Lock()
+	client.stats.AttackSpeed *= 2
+	client.statsMutex.Unlock()
+	if !client.tryAction(now.Add(slow + slow/2)) {
+		t.Fatal("expected double the attack speed to halve the interval")
+	}
+}
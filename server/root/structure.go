@@ -0,0 +1,128 @@
+package root
+
+import (
+	"math"
+	"time"
+	"ws-game/loot"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// isFriendly checks if the client built the structure or is in the team of the owner.
+// world resources have no owner and are never friendly
+func (c *Client) isFriendly(r resource.Resource) bool {
+	if r.Owner == "" {
+		return false
+	}
+	return r.Owner == c.UUID || (r.Team != "" && r.Team == c.Team)
+}
+
+// canDamage checks if the client is allowed to hit the resource, friendly structures can be
+// deconstructed, the ones of other players only be raided if the server allows it
+func (h *Hub) canDamage(r resource.Resource, c *Client) bool {
+	if r.Owner == "" || c.isFriendly(r) {
+		return true
+	}
+	return h.config.Structures.Raidable
+}
+
+// deconstruct gives back what the structure cost to build, dropped at its position
+func (h *Hub) deconstruct(r resource.Resource) {
+	buildable, ok := findBuildable(string(r.ResourceType))
+	if !ok {
+		return
+	}
+
+	drops := []loot.Drop{}
+	for _, cost := range buildable.Costs {
+		drops = append(drops, loot.Drop{Resource: cost.ResourceType, Quantity: cost.Quantity})
+	}
+	h.spawnDrops(drops, r.Pos, r.GridCellKey)
+}
+
+// repairCosts are the building costs scaled by the missing hitpoints, rounded up
+func repairCosts(buildable Buildable, hitpoints shared.Hitpoints) []resource.ResourceMin {
+	costs := []resource.ResourceMin{}
+	missing := float64(hitpoints.Max-hitpoints.Current) / float64(hitpoints.Max)
+	for _, cost := range buildable.Costs {
+		quantity := int(math.Ceil(float64(cost.Quantity) * missing))
+		if quantity > 0 {
+			costs = append(costs, resource.ResourceMin{ResourceType: cost.ResourceType, Quantity: quantity})
+		}
+	}
+	return costs
+}
+
+func (h *Hub) HandleRepair(event RepairEvent, c *Client) {
+	r, err := h.ResourceManager.GetResource(event.Id)
+	if err != nil || r.GetRemove() {
+		return
+	}
+
+	if !c.isFriendly(*r) {
+		return
+	}
+
+	buildable, ok := findBuildable(string(r.ResourceType))
+	if !ok {
+		return
+	}
+
+	clientPos := c.GetPos()
+	if r.Pos.Dist(&clientPos) > float64(h.config.Player.BuildRange) {
+		return
+	}
+
+	// decay of the cell coroutine changes the hitpoints as well
+	cell, ok := h.GridManager.findCellOfKey(r.GridCellKey)
+	if !ok {
+		return
+	}
+	cell.ResourcesMutex.Lock()
+	defer cell.ResourcesMutex.Unlock()
+
+	costs := repairCosts(buildable, r.Hitpoints)
+	if !c.takeResources(costs) {
+		return
+	}
+	for _, cost := range costs {
+		c.send <- NewUpdateInventoryEvent(cost, true)
+	}
+
+	// subscribers get the new hitpoints with the next cell state
+	r.Hitpoints.Current = r.Hitpoints.Max
+	r.DecaysAt = time.Now().Add(h.config.Structures.DecayDelay).Unix()
+}
+
+// decayStructures damages structures that were not repaired in time and removes the ones that fell apart.
+// cells that were inactive catch up one decay step per tick
+func (cell *GridCell) decayStructures(now time.Time) {
+	config := cell.config.Structures
+	removed := []int{}
+
+	cell.ResourcesMutex.Lock()
+	for _, r := range cell.Resources {
+		if r.DecaysAt == 0 || r.GetRemove() || now.Unix() < r.DecaysAt {
+			continue
+		}
+
+		r.Hitpoints.Current -= int(math.Ceil(float64(r.Hitpoints.Max) * config.DecayDamage))
+		r.DecaysAt += int64(config.DecayInterval / time.Second)
+
+		if r.Hitpoints.Current <= 0 {
+			r.SetRemove(true)
+			delete(cell.Resources, r.Id)
+			removed = append(removed, r.Id)
+		}
+	}
+	cell.ResourcesMutex.Unlock()
+
+	// the resource manager forgets them as well
+	for _, id := range removed {
+		select {
+		case cell.removedResources <- id:
+		case <-cell.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+// addStructure puts a blockade of owner next to the client without going through placement
+func addStructure(hub *Hub, c *Client, owner string, team string) *resource.Resource {
+	pos := c.GetPos()
+	cell := hub.GridManager.GetCellFromPos(pos)
+	r := resource.NewResource(resource.Blockade, pos, hub.ResourceManager.GetResourceId(), 1, true, 500, false, cell.GridCellKey)
+	r.Owner = owner
+	r.Team = team
+	r.DecaysAt = time.Now().Add(hub.config.Structures.DecayDelay).Unix()
+
+	hub.ResourceManager.SetResource(r)
+	cell.AddResource(r)
+	return r
+}
+
+func TestStructureDecay(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	r := addStructure(hub, client, client.UUID, "")
+	cell := hub.GridManager.GetCellFromPos(r.Pos)
+
+	cell.decayStructures(time.Now())
+	if r.Hitpoints.Current != r.Hitpoints.Max {
+		t.Fatal("decayed before the delay")
+	}
+
+	later := time.Now().Add(hub.config.Structures.DecayDelay)
+	cell.decayStructures(later)
+	if r.Hitpoints.Current != 475 {
+		t.Fatalf("expected 475 hitpoints after one step, got %d", r.Hitpoints.Current)
+	}
+
+	// a cell that was inactive catches up one step per tick until the structure falls apart
+	for i := 0; i < 19; i++ {
+		cell.decayStructures(later.Add(100 * hub.config.Structures.DecayInterval))
+	}
+	if !r.GetRemove() {
+		t.Fatalf("expected the structure to be removed, has %d hitpoints", r.Hitpoints.Current)
+	}
+	if _, ok := cell.GetResources()[r.Id]; ok {
+		t.Error("structure is still in the cell")
+	}
+
+	// the resource manager forgets it in its own coroutine
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := hub.ResourceManager.GetResource(r.Id); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("structure is still known to the resource manager")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStructureRepair(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.Brick, Quantity: 5})
+
+	r := addStructure(hub, client, client.UUID, "")
+	r.Hitpoints.Current = 150
+	r.DecaysAt = 1
+
+	hub.HandleRepair(RepairEvent{Id: r.Id}, client)
+	if r.Hitpoints.Current != r.Hitpoints.Max {
+		t.Fatalf("not repaired %+v", r.Hitpoints)
+	}
+	if r.DecaysAt <= time.Now().Unix() {
+		t.Error("decay timer was not reset")
+	}
+	// 70% of 5 bricks, rounded up
+	if client.ResourceInventory[resource.Brick].Quantity != 1 {
+		t.Errorf("expected 4 bricks to be used, %d left", client.ResourceInventory[resource.Brick].Quantity)
+	}
+
+	// only the owner and the team can repair
+	other := NewClient(hub, nil, hub.getClientId())
+	other.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	other.addResource(resource.ResourceMin{ResourceType: resource.Brick, Quantity: 5})
+	r.Hitpoints.Current = 100
+	hub.HandleRepair(RepairEvent{Id: r.Id}, other)
+	if r.Hitpoints.Current != 100 {
+		t.Error("repaired by a stranger")
+	}
+
+	other.Team = "red"
+	r.Team = "red"
+	hub.HandleRepair(RepairEvent{Id: r.Id}, other)
+	if r.Hitpoints.Current != r.Hitpoints.Max {
+		t.Error("not repaired by a team member")
+	}
+}
+
+func TestStructureRepairAtNegativePosition(t *testing.T) {
+	hub := newTestHub(t)
+	client := NewClient(hub, nil, hub.getClientId())
+	client.SetPos(shared.Vector{X: -10, Y: -10})
+	client.ResourceInventory = make(map[resource.ResourceType]resource.Resource)
+	client.addResource(resource.ResourceMin{ResourceType: resource.Brick, Quantity: 5})
+
+	r := addStructure(hub, client, client.UUID, "")
+	if r.GridCellKey != "-1#-1" {
+		t.Fatalf("expected the structure in cell -1#-1, got %s", r.GridCellKey)
+	}
+	cell, ok := hub.GridManager.findCellOfKey(r.GridCellKey)
+	if !ok {
+		t.Fatal("the cell of the key doesn't exist")
+	}
+	if _, ok := cell.GetResources()[r.Id]; !ok {
+		t.Fatal("the cell of the key doesn't own the structure")
+	}
+
+	r.Hitpoints.Current = 150
+	hub.HandleRepair(RepairEvent{Id: r.Id}, client)
+	if r.Hitpoints.Current != r.Hitpoints.Max {
+		t.Fatalf("not repaired %+v", r.Hitpoints)
+	}
+}
+
+func TestStructureDamageRules(t *testing.T) {
+	hub := newTestHub(t)
+	hub.config.Structures.Raidable = false
+	owner := NewClient(hub, nil, hub.getClientId())
+	stranger := NewClient(hub, nil, hub.getClientId())
+
+	wall := resource.Resource{Owner: owner.UUID, Hitpoints: shared.Hitpoints{Current: 10, Max: 10}}
+	tree := resource.Resource{Hitpoints: shared.Hitpoints{Current: 10, Max: 10}}
+
+	if !hub.canDamage(wall, owner) || !hub.canDamage(tree, stranger) {
+		t.Error("owners and world resources can always be hit")
+	}
+	if hub.canDamage(wall, stranger) {
+		t.Error("raided although raids are disabled")
+	}
+
+	hub.config.Structures.Raidable = true
+	if !hub.canDamage(wall, stranger) {
+		t.Error("raids are enabled")
+	}
+}
@@ -0,0 +1,109 @@
+package root
+
+import (
+	"math"
+	"ws-game/shared"
+)
+
+// movement speed multiplier per terrain type, 0 means the terrain can't be entered
+var terrainSpeed = map[TerrainType]float64{
+	Grass:        1,
+	Sand:         1,
+	ShallowWater: 0.5,
+	Water:        0,
+	Rock:         1,
+	Mud:          0.7,
+	Snow:         0.8,
+}
+
+func (t TerrainType) Passable() bool {
+	return t.SpeedFactor() > 0
+}
+
+func (t TerrainType) SpeedFactor() float64 {
+	return terrainSpeed[t]
+}
+
+func floorDiv(a int, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// TerrainAt returns the terrain at a world position. The terrain is generated from the same noise as
+// the sub cells of a grid cell, so positions in cells that don't exist yet can be checked too.
+func (w *World) TerrainAt(pos shared.Vector) TerrainType {
+	subX := floorDiv(pos.X, w.SubCellSize)
+	subY := floorDiv(pos.Y, w.SubCellSize)
+	cellX := floorDiv(subX, w.SubCells)
+	cellY := floorDiv(subY, w.SubCells)
+
+	terrain, _ := w.subCellTerrain(cellX, cellY, subX-cellX*w.SubCells, subY-cellY*w.SubCells)
+	return terrain
+}
+
+// nearestPassable searches the sub cells around pos in growing rings for passable terrain,
+// used to get players and npcs out of deep water. Returns pos if nothing is found within two grid cells.
+func (w *World) nearestPassable(pos shared.Vector) shared.Vector {
+	if w.terrainPassable(pos) {
+		return pos
+	}
+
+	for r := 1; r <= 2*w.SubCells; r++ {
+		for dx := -r; dx <= r; dx++ {
+			for dy := -r; dy <= r; dy++ {
+				// only the ring, the inner sub cells were checked already
+				if Abs(dx) != r && Abs(dy) != r {
+					continue
+				}
+
+				candidate := shared.Vector{X: pos.X + dx*w.SubCellSize, Y: pos.Y + dy*w.SubCellSize}
+				if w.terrainPassable(candidate) {
+					return candidate
+				}
+			}
+		}
+	}
+
+	return pos
+}
+
+// scaledSpeed is the distance something with the given base speed moves per step on the terrain at pos
+func (w *World) scaledSpeed(pos shared.Vector, speed int) int {
+	terrain := w.TerrainAt(pos)
+	if !terrain.Passable() {
+		// only happens if something got placed in deep water, let it walk out slowly
+		return int(math.Round(float64(speed) * ShallowWater.SpeedFactor()))
+	}
+	return int(math.Round(float64(speed) * terrain.SpeedFactor()))
+}
+
+// walkStep tries to move pos by step, if the target is blocked it slides along the free axis
+func walkStep(pos shared.Vector, step shared.Vector, canEnter func(shared.Vector) bool) (shared.Vector, bool) {
+	candidates := []shared.Vector{
+		{X: pos.X + step.X, Y: pos.Y + step.Y},
+		{X: pos.X + step.X, Y: pos.Y},
+		{X: pos.X, Y: pos.Y + step.Y},
+	}
+
+	for i, candidate := range candidates {
+		if candidate == pos {
+			continue
+		}
+		// diagonal moves only slide, a straight move that is blocked stays blocked
+		if i > 0 && (step.X == 0 || step.Y == 0) {
+			break
+		}
+		if canEnter(candidate) {
+			return candidate, true
+		}
+	}
+
+	return pos, false
+}
+
+func (w *World) terrainPassable(pos shared.Vector) bool {
+	return w.TerrainAt(pos).Passable()
+}
@@ -0,0 +1,56 @@
+package root
+
+import (
+	"testing"
+	"ws-game/shared"
+)
+
+func TestTerrainAtMatchesSubCells(t *testing.T) {
+	w := NewWorld(DefaultConfig().World)
+	for _, cellPos := range []shared.Vector{{X: 0, Y: 0}, {X: 3, Y: -2}, {X: -1, Y: -1}} {
+		for _, subCell := range w.getSubCells(cellPos.X, cellPos.Y) {
+			pos := shared.Vector{
+				X: cellPos.X*w.GridCellSize + subCell.Pos.X*w.SubCellSize + w.SubCellSize/2,
+				Y: cellPos.Y*w.GridCellSize + subCell.Pos.Y*w.SubCellSize + w.SubCellSize/2,
+			}
+			if terrain := w.TerrainAt(pos); terrain != subCell.TerrainType {
+				t.Fatalf("terrain at %v is %s, sub cell has %s", pos, terrain, subCell.TerrainType)
+			}
+		}
+	}
+}
+
+func TestTerrainIsContinuousAcrossCells(t *testing.T) {
+	config := DefaultConfig().World
+	config.SubCells = 8
+	w := NewWorld(config)
+
+	// the sub cell right of the last one of a cell is the first one of the next cell
+	for cellX := -5; cellX < 5; cellX++ {
+		for y := 0; y < w.SubCells; y++ {
+			beyond, _ := w.subCellTerrain(cellX, 0, w.SubCells, y)
+			first, _ := w.subCellTerrain(cellX+1, 0, 0, y)
+			if beyond != first {
+				t.Fatalf("terrain jumps at the border of cell %d#0, row %d: %s and %s", cellX, y, beyond, first)
+			}
+		}
+	}
+}
+
+func TestNpcDoesNotWalkIntoWater(t *testing.T) {
+	w := NewWorld(DefaultConfig().World)
+
+	// the center of cell 0#0 is deep water
+	water := shared.Vector{X: w.GridCellSize / 2, Y: w.GridCellSize / 2}
+	if w.TerrainAt(water).Passable() {
+		t.Skip("terrain generation changed, center of cell 0#0 is not water anymore")
+	}
+
+	npc := NewNpc(w.nearestPassable(water), DefaultConfig().Npc)
+	for i := 0; i < 50; i++ {
+		npc.stepTowards(w, water)
+		if !w.TerrainAt(npc.Pos).Passable() {
+			t.Fatalf("npc walked into water at %v", npc.Pos)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package root
+
+import "math"
+
+const (
+	// a player needs this much more threat than the current target to draw the npc away from it
+	threatSwitchRatio = 1.1
+	// players with less threat are forgotten
+	minThreat = 0.5
+	// threat against the target of an ally that called for help
+	helpThreat = 10
+)
+
+type threatEntry struct {
+	player *Client
+	threat float64
+}
+
+// threatTable contains the threat of every player a npc is fighting by client id. Damage and standing
+// close to the npc cause threat, it decays over time and the npc fights the player with the most.
+type threatTable map[int]*threatEntry
+
+func (t threatTable) add(player *Client, amount float64) {
+	entry, ok := t[player.Id]
+	if !ok {
+		entry = &threatEntry{player: player}
+		t[player.Id] = entry
+	}
+	entry.threat += amount
+}
+
+// decay keeps factor of the threat of every player and forgets dead and disconnected players
+func (t threatTable) decay(factor float64) {
+	for id, entry := range t {
+		entry.threat *= factor
+		if entry.threat < minThreat || !entry.player.getConnected() || entry.player.isDead() {
+			delete(t, id)
+		}
+	}
+}
+
+func (t threatTable) forget(player *Client) {
+	delete(t, player.Id)
+}
+
+// pick returns the player the npc should fight, current is kept unless someone has clearly more threat
+func (t threatTable) pick(current *Client) *Client {
+	var top *threatEntry
+	for _, entry := range t {
+		// lowest id on a tie, map order is random
+		if top == nil || entry.threat > top.threat || (entry.threat == top.threat && entry.player.Id < top.player.Id) {
+			top = entry
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	if current != nil {
+		if entry, ok := t[current.Id]; ok && top.threat <= entry.threat*threatSwitchRatio {
+			return current
+		}
+	}
+	return top.player
+}
+
+// threatDecayFactor is the part of the threat that is left after one cell tick
+func (cell *GridCell) threatDecayFactor() float64 {
+	return math.Pow(1-cell.config.Npc.ThreatDecay, cell.config.World.CellUpdateRate.Seconds())
+}
@@ -0,0 +1,79 @@
+package root
+
+import (
+	"testing"
+	"time"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/shared"
+)
+
+func TestThreatTable(t *testing.T) {
+	hub := newTestHub(t)
+	tank := NewClient(hub, nil, hub.getClientId())
+	healer := NewClient(hub, nil, hub.getClientId())
+
+	threat := threatTable{}
+	threat.add(tank, 100)
+	threat.add(healer, 105)
+	if threat.pick(tank) != tank {
+		t.Error("expected the npc to stay on its target with slightly less threat")
+	}
+	if threat.pick(nil) != healer {
+		t.Error("expected the player with the most threat without a target")
+	}
+
+	threat.add(healer, 20)
+	if threat.pick(tank) != healer {
+		t.Error("expected the npc to switch to the player with clearly more threat")
+	}
+
+	threat.decay(0.5)
+	if threat[tank.Id].threat != 50 {
+		t.Errorf("expected the threat to decay, got %f", threat[tank.Id].threat)
+	}
+
+	healer.setConnected(false)
+	tank.die(time.Now())
+	threat.decay(1)
+	if len(threat) != 0 {
+		t.Errorf("expected disconnected and dead players to be forgotten, got %d", len(threat))
+	}
+}
+
+func TestNpcTargetsByThreat(t *testing.T) {
+	hub := newTestHub(t)
+	cell := NewCell(0, 0, hub.config, hub.world, bestiary.Default(), item.Default())
+
+	// a ranged npc doesn't have to walk over the terrain around it
+	npc := NewNpc(shared.Vector{X: 500, Y: 500}, hub.config.Npc)
+	npc.behavior.AttackRange = 500
+	cell.NpcList = []Npc{npc}
+
+	near := NewClient(hub, nil, hub.getClientId())
+	near.SetPos(shared.Vector{X: 550, Y: 500})
+	far := NewClient(hub, nil, hub.getClientId())
+	far.SetPos(shared.Vector{X: 900, Y: 500})
+	cell.playerSubscriptions[near.Id] = GridSubscription{Player: near}
+	cell.playerSubscriptions[far.Id] = GridSubscription{Player: far}
+
+	cell.NpcUpdates()
+	if cell.NpcList[0].targetedPlayer != near {
+		t.Fatal("expected the npc to target the player within its aggro radius")
+	}
+
+	// damage from outside the aggro radius draws the npc away
+	for i := 0; i < 20; i++ {
+		cell.hitNpc(npc.UUID, far)
+	}
+	cell.NpcUpdates()
+	if cell.NpcList[0].targetedPlayer != far {
+		t.Fatal("expected the npc to target the player that dealt damage")
+	}
+
+	far.setConnected(false)
+	cell.NpcUpdates()
+	if cell.NpcList[0].targetedPlayer != near {
+		t.Fatal("expected the npc to return to the remaining player")
+	}
+}
@@ -0,0 +1,309 @@
+package root
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Outbound events are written either as json or in a compact binary format. Both encodings are
+// derived from the event structs registered in outboundEvents: json uses the json tags, the binary
+// format writes the same fields in declaration order without names. Clients that negotiate the
+// binary subprotocol receive the schema as first (json) message and decode everything after it.
+//
+// binary format:
+//   bool        1 byte
+//   int / uint  zigzag / unsigned varint
+//   float       float32 / float64 little endian
+//   string      uvarint length + utf8 bytes
+//   uuid        string field tagged `wire:"uuid"`, uvarint 0 + 16 bytes or length+1 + bytes if it is no uuid
+//   list        uvarint length + elements
+//   map         uvarint length + key/value pairs
+//   optional    1 byte (0 = null) + value
+//   struct      fields in declaration order
+//   event       interface{} value, written as the struct registered for its event type (eventType first)
+// Messages sent by the client are json in both cases.
+
+const (
+	BinarySubprotocol = "ws-game.binary.v1"
+	JSONSubprotocol   = "ws-game.json.v1"
+)
+
+// WireType describes the binary layout of a value, the frontend builds its decoder from it
+type WireType struct {
+	Kind   string      `json:"kind"`
+	Elem   *WireType   `json:"elem,omitempty"`
+	Key    *WireType   `json:"key,omitempty"`
+	Fields []WireField `json:"fields,omitempty"`
+}
+
+type WireField struct {
+	Name string   `json:"name"`
+	Type WireType `json:"type"`
+}
+
+type WireSchemaEvent struct {
+	EventType EventType              `json:"eventType"`
+	Events    map[EventType]WireType `json:"events"`
+}
+
+func NewWireSchemaEvent() interface{} {
+	return &WireSchemaEvent{EventType: WIRE_SCHEMA_EVENT, Events: wireSchema}
+}
+
+var wireSchema = mustBuildWireSchema()
+
+func mustBuildWireSchema() map[EventType]WireType {
+	schema, err := buildWireSchema()
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+func buildWireSchema() (map[EventType]WireType, error) {
+	schema := make(map[EventType]WireType, len(outboundEvents))
+	eventTypeType := reflect.TypeOf(EventType(0))
+
+	for eventType, event := range outboundEvents {
+		t := reflect.TypeOf(event)
+		if t.Kind() != reflect.Struct || t.NumField() == 0 || t.Field(0).Type != eventTypeType {
+			return nil, fmt.Errorf("event %d: %s needs EventType as first field", eventType, t)
+		}
+
+		wireType, err := wireTypeOf(t, false)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", eventType, err)
+		}
+		schema[eventType] = wireType
+	}
+
+	return schema, nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func wireTypeOf(t reflect.Type, isUUID bool) (WireType, error) {
+	if reflect.PtrTo(t).Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		// the json of these types can't be derived from the struct
+		return WireType{}, fmt.Errorf("%s has a custom json encoding", t)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return WireType{Kind: "bool"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return WireType{Kind: "int"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return WireType{Kind: "uint"}, nil
+	case reflect.Float32:
+		return WireType{Kind: "float32"}, nil
+	case reflect.Float64:
+		return WireType{Kind: "float64"}, nil
+	case reflect.String:
+		if isUUID {
+			return WireType{Kind: "uuid"}, nil
+		}
+		return WireType{Kind: "string"}, nil
+	case reflect.Interface:
+		return WireType{Kind: "event"}, nil
+	case reflect.Ptr:
+		elem, err := wireTypeOf(t.Elem(), isUUID)
+		return WireType{Kind: "optional", Elem: &elem}, err
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return WireType{}, fmt.Errorf("%s is base64 in json", t)
+		}
+		elem, err := wireTypeOf(t.Elem(), isUUID)
+		return WireType{Kind: "list", Elem: &elem}, err
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		default:
+			return WireType{}, fmt.Errorf("unsupported map key %s", t.Key())
+		}
+		key, err := wireTypeOf(t.Key(), false)
+		if err != nil {
+			return WireType{}, err
+		}
+		elem, err := wireTypeOf(t.Elem(), isUUID)
+		return WireType{Kind: "map", Key: &key, Elem: &elem}, err
+	case reflect.Struct:
+		wireType := WireType{Kind: "struct", Fields: []WireField{}}
+		for _, f := range wireFields(t) {
+			fieldType, err := wireTypeOf(t.Field(f.index).Type, f.uuid)
+			if err != nil {
+				return WireType{}, fmt.Errorf("%s.%s: %w", t, t.Field(f.index).Name, err)
+			}
+			wireType.Fields = append(wireType.Fields, WireField{Name: f.name, Type: fieldType})
+		}
+		return wireType, nil
+	}
+
+	return WireType{}, fmt.Errorf("unsupported type %s", t)
+}
+
+type wireField struct {
+	index int
+	name  string
+	uuid  bool
+}
+
+var wireFieldCache sync.Map // reflect.Type -> []wireField
+
+// wireFields returns the fields that end up in the json of a struct
+func wireFields(t reflect.Type) []wireField {
+	if cached, ok := wireFieldCache.Load(t); ok {
+		return cached.([]wireField)
+	}
+
+	fields := []wireField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		fields = append(fields, wireField{index: i, name: name, uuid: f.Tag.Get("wire") == "uuid"})
+	}
+
+	wireFieldCache.Store(t, fields)
+	return fields
+}
+
+type wireEncoder struct {
+	buf     []byte
+	scratch [binary.MaxVarintLen64]byte
+}
+
+// EncodeBinaryEvent writes an outbound event in the binary format
+func EncodeBinaryEvent(event interface{}) ([]byte, error) {
+	e := &wireEncoder{buf: make([]byte, 0, 64)}
+	if err := e.event(reflect.ValueOf(event)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (e *wireEncoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:n]...)
+}
+
+func (e *wireEncoder) varint(v int64) {
+	n := binary.PutVarint(e.scratch[:], v)
+	e.buf = append(e.buf, e.scratch[:n]...)
+}
+
+func (e *wireEncoder) event(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return fmt.Errorf("nil event")
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || v.NumField() == 0 || v.Field(0).Type() != reflect.TypeOf(EventType(0)) {
+		return fmt.Errorf("%s is no event", v.Type())
+	}
+
+	eventType := EventType(v.Field(0).Int())
+	if registered, ok := outboundEvents[eventType]; !ok || reflect.TypeOf(registered) != v.Type() {
+		return fmt.Errorf("%s is not registered for event type %d", v.Type(), eventType)
+	}
+
+	return e.value(v, false)
+}
+
+func (e *wireEncoder) value(v reflect.Value, isUUID bool) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.varint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.uvarint(v.Uint())
+	case reflect.Float32:
+		binary.LittleEndian.PutUint32(e.scratch[:4], math.Float32bits(float32(v.Float())))
+		e.buf = append(e.buf, e.scratch[:4]...)
+	case reflect.Float64:
+		binary.LittleEndian.PutUint64(e.scratch[:8], math.Float64bits(v.Float()))
+		e.buf = append(e.buf, e.scratch[:8]...)
+	case reflect.String:
+		s := v.String()
+		if isUUID {
+			if id, err := uuid.Parse(s); err == nil && id.String() == s {
+				e.uvarint(0)
+				e.buf = append(e.buf, id[:]...)
+				return nil
+			}
+			e.uvarint(uint64(len(s)) + 1)
+		} else {
+			e.uvarint(uint64(len(s)))
+		}
+		e.buf = append(e.buf, s...)
+	case reflect.Interface:
+		return e.event(v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0)
+			return nil
+		}
+		e.buf = append(e.buf, 1)
+		return e.value(v.Elem(), isUUID)
+	case reflect.Slice, reflect.Array:
+		e.uvarint(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i), isUUID); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		e.uvarint(uint64(v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.value(iter.Key(), false); err != nil {
+				return err
+			}
+			if err := e.value(iter.Value(), isUUID); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for _, f := range wireFields(v.Type()) {
+			if err := e.value(v.Field(f.index), f.uuid); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package root
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+
+	"github.com/google/uuid"
+)
+
+// decodeWire mirrors the decoder of the frontend, it builds the same generic value json.Unmarshal would
+func decodeWire(r *bytes.Reader, t WireType, schema map[EventType]WireType) (interface{}, error) {
+	switch t.Kind {
+	case "bool":
+		b, err := r.ReadByte()
+		return b == 1, err
+	case "int":
+		v, err := binary.ReadVarint(r)
+		return float64(v), err
+	case "uint":
+		v, err := binary.ReadUvarint(r)
+		return float64(v), err
+	case "float32":
+		buf := make([]byte, 4)
+		_, err := r.Read(buf)
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(buf))), err
+	case "float64":
+		buf := make([]byte, 8)
+		_, err := r.Read(buf)
+		return math.Float64frombits(binary.LittleEndian.Uint64(buf)), err
+	case "string", "uuid":
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == "uuid" {
+			if n == 0 {
+				id := uuid.UUID{}
+				_, err := r.Read(id[:])
+				return id.String(), err
+			}
+			n--
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(r, buf)
+		return string(buf), err
+	case "optional":
+		present, err := r.ReadByte()
+		if err != nil || present == 0 {
+			return nil, err
+		}
+		return decodeWire(r, *t.Elem, schema)
+	case "list":
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for i := uint64(0); i < n; i++ {
+			v, err := decodeWire(r, *t.Elem, schema)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case "map":
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{}
+		for i := uint64(0); i < n; i++ {
+			k, err := decodeWire(r, *t.Key, schema)
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeWire(r, *t.Elem, schema)
+			if err != nil {
+				return nil, err
+			}
+			if f, ok := k.(float64); ok {
+				k = strconv.Itoa(int(f))
+			}
+			m[k.(string)] = v
+		}
+		return m, nil
+	case "struct":
+		m := map[string]interface{}{}
+		for _, f := range t.Fields {
+			v, err := decodeWire(r, f.Type, schema)
+			if err != nil {
+				return nil, err
+			}
+			m[f.Name] = v
+		}
+		return m, nil
+	case "event":
+		eventType, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		eventSchema, ok := schema[EventType(eventType)]
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %d", eventType)
+		}
+		m := map[string]interface{}{eventSchema.Fields[0].Name: float64(eventType)}
+		for _, f := range eventSchema.Fields[1:] {
+			v, err := decodeWire(r, f.Type, schema)
+			if err != nil {
+				return nil, err
+			}
+			m[f.Name] = v
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown kind %s", t.Kind)
+}
+
+func TestWireSchemaCoversOutboundEvents(t *testing.T) {
+	schema, err := buildWireSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema) != len(outboundEvents) {
+		t.Fatalf("expected %d events in schema, got %d", len(outboundEvents), len(schema))
+	}
+}
+
+func TestBinaryEncodingMatchesJSON(t *testing.T) {
+	npcUUID := uuid.New().String()
+	testItem := item.Default().NewItem(shared.Vector{X: 1, Y: 2}, 1, shared.Vector{X: 50, Y: -20})
+	tree := resource.NewResource(resource.Tree, shared.Vector{X: 10, Y: 20}, 7, 100, true, 100, false, "0#0")
+
+	events := []interface{}{
+		NewPlayerTargetPositionEvent(shared.Vector{X: -300, Y: 20}, 3, true),
+		NewNpcTargetPositionEvent("0#0", npcUUID, shared.Vector{X: 1, Y: 2}),
+		NewUpdateNpcEvent(npcUUID, 10, 20, false, "0#-1", 5, true),
+		NewResourcePositionsEvent(map[int]resource.Resource{tree.Id: *tree}),
+		NewUpdateInventoryItemEvent(testItem, false),
+		NewRemoveItemEvent("not-a-uuid"),
+		NewLoginFailedEvent("nope"),
+		NewMultipleEvents([]interface{}{
+			NewRemovePlayerEvent(2),
+			NewNpcAttackAnimEvent(npcUUID, 1),
+		}),
+		&UserInitEvent{
+			EventType:  USER_INIT_EVENT,
+			UUID:       uuid.New().String(),
+			GameConfig: GameConfig{PlayerSpeed: 18, TerrainSpeed: terrainSpeed, Recipes: recipes, Buildables: buildables},
+			Resources:  []resource.ResourceMin{{ResourceType: resource.Brick, Quantity: 50}},
+			Items:      []item.Item{testItem},
+			Equipment:  Equipment{item.MainHandSlot: testItem.UUID},
+		},
+	}
+
+	for _, event := range events {
+		data, err := EncodeBinaryEvent(event)
+		if err != nil {
+			t.Fatalf("%T: %s", event, err)
+		}
+
+		decoded, err := decodeWire(bytes.NewReader(data), WireType{Kind: "event"}, wireSchema)
+		if err != nil {
+			t.Fatalf("%T: %s", event, err)
+		}
+
+		jsonData, _ := json.Marshal(event)
+		var expected interface{}
+		json.Unmarshal(jsonData, &expected)
+
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Errorf("%T: binary decoded to\n%v\nexpected\n%v", event, decoded, expected)
+		}
+	}
+}
+
+func TestBinaryEncodingIsSmaller(t *testing.T) {
+	event := NewNpcTargetPositionEvent("12#-3", uuid.New().String(), shared.Vector{X: 12345, Y: -3456})
+
+	data, err := EncodeBinaryEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, _ := json.Marshal(event)
+
+	if len(data)*3 > len(jsonData) {
+		t.Errorf("binary event has %d bytes, json %d", len(data), len(jsonData))
+	}
+}
+
+func TestEncodeBinaryEventRejectsUnregisteredEvents(t *testing.T) {
+	if _, err := EncodeBinaryEvent(NewWireSchemaEvent()); err == nil {
+		t.Error("expected error for an event that is not in the schema")
+	}
+}
@@ -0,0 +1,30 @@
+package root
+
+import "ws-game/shared"
+
+// World is the geometry and terrain of the world, set up from WorldConfig by NewHub. It is only read
+// after creation, so the hub, the grid manager and the cell coroutines share it without locking.
+type World struct {
+	GridCellSize int
+	SubCells     int // per row of a grid cell
+	SubCellSize  int
+	Seed         int64
+
+	noise worldNoise
+}
+
+func NewWorld(config WorldConfig) *World {
+	return &World{
+		GridCellSize: config.GridCellSize,
+		SubCells:     config.SubCells,
+		SubCellSize:  config.GridCellSize / config.SubCells,
+		Seed:         config.Seed,
+		noise:        newTerrainNoise(config.Seed),
+	}
+}
+
+// cellOf returns the position of the grid cell that contains pos, the cells left of and above 0#0
+// are negative, so the division has to round down
+func (w *World) cellOf(pos shared.Vector) shared.Vector {
+	return shared.Vector{X: floorDiv(pos.X, w.GridCellSize), Y: floorDiv(pos.Y, w.GridCellSize)}
+}
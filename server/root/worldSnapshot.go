@@ -0,0 +1,276 @@
+package root
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+const (
+	// bump this if the layout of WorldSnapshot changes in a way old snapshots can't be read anymore
+	WorldSnapshotVersion = 1
+
+	// the world gets written to the store in this interval
+	WorldAutosaveInterval = 5 * time.Minute
+)
+
+// WorldSnapshot contains everything needed to recreate the world after a restart
+type WorldSnapshot struct {
+	Version       int            `json:"version"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	ResourceIdCnt int            `json:"resourceIdCnt"`
+	Cells         []CellSnapshot `json:"cells"`
+
+	// harvested trees and stones that did not regrow yet, missing in snapshots of older servers
+	Respawns []ResourceRespawn `json:"respawns,omitempty"`
+}
+
+type CellSnapshot struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	// false if the cell was created but the resource manager did not spawn its trees/stones yet
+	ResourcesSpawned bool                `json:"resourcesSpawned"`
+	Resources        []resource.Resource `json:"resources"`
+	Items            []item.Item         `json:"items"`
+	Npcs             []NpcSnapshot       `json:"npcs"`
+	NpcRespawns      []NpcRespawn        `json:"npcRespawns,omitempty"`
+}
+
+// NpcSnapshot keeps the persistent part of a npc, runtime state like the targeted player is dropped
+type NpcSnapshot struct {
+	UUID        string           `json:"uuid"`
+	Pos         shared.Vector    `json:"pos"`
+	SpawnPos    shared.Vector    `json:"spawnPos"`
+	Hitpoints   shared.Hitpoints `json:"hitpoints"`
+	NpcType     string           `json:"npcType"`
+	AttackSpeed int              `json:"attackSpeed"`
+	Aggressive  bool             `json:"aggressive"`
+	CritChance  float32          `json:"critChance"`
+	MinDamage   float32          `json:"minDamage"`
+	MaxDamage   float32          `json:"maxDamage"`
+}
+
+func (npc *Npc) snapshot() NpcSnapshot {
+	return NpcSnapshot{
+		UUID:        npc.UUID,
+		Pos:         npc.Pos,
+		SpawnPos:    npc.spawnPos,
+		Hitpoints:   npc.Hitpoints,
+		NpcType:     npc.NpcType,
+		AttackSpeed: npc.AttackSpeed,
+		Aggressive:  npc.aggressive,
+		CritChance:  npc.critChance,
+		MinDamage:   npc.minDamage,
+		MaxDamage:   npc.maxDamage,
+	}
+}
+
+// npcFromSnapshot takes everything the snapshot doesn't contain from the archetype
+func npcFromSnapshot(s NpcSnapshot, config NpcConfig, b bestiary.Bestiary) Npc {
+	npc := NewNpcOf(s.SpawnPos, s.NpcType, b.Archetypes[s.NpcType], config)
+	npc.UUID = s.UUID
+	npc.Pos = s.Pos
+	npc.Hitpoints = s.Hitpoints
+	npc.AttackSpeed = s.AttackSpeed
+	npc.behavior.AttackSpeed = s.AttackSpeed
+	npc.aggressive = s.Aggressive
+	npc.critChance = s.CritChance
+	npc.minDamage = s.MinDamage
+	npc.maxDamage = s.MaxDamage
+	return npc
+}
+
+func (cell *GridCell) snapshot() CellSnapshot {
+	s := CellSnapshot{
+		X:         cell.Pos.X,
+		Y:         cell.Pos.Y,
+		Resources: []resource.Resource{},
+		Items:     []item.Item{},
+		Npcs:      []NpcSnapshot{},
+	}
+
+	cell.ResourcesMutex.Lock()
+	s.ResourcesSpawned = cell.resourcesSpawned
+	for _, r := range cell.Resources {
+		if r.GetRemove() {
+			continue
+		}
+		s.Resources = append(s.Resources, *r)
+	}
+	cell.ResourcesMutex.Unlock()
+
+	s.Items = append(s.Items, cell.GetItems()...)
+
+	// items that were dropped but not yet added by the cell coroutine
+	cell.ItemsToAddMutex.Lock()
+	s.Items = append(s.Items, cell.ItemsToAdd...)
+	cell.ItemsToAddMutex.Unlock()
+
+	cell.NpcListMutex.Lock()
+	for i := range cell.NpcList {
+		if cell.NpcList[i].remove {
+			continue
+		}
+		s.Npcs = append(s.Npcs, cell.NpcList[i].snapshot())
+	}
+	cell.NpcListMutex.Unlock()
+
+	cell.npcRespawnsMutex.Lock()
+	s.NpcRespawns = append(s.NpcRespawns, cell.npcRespawns...)
+	cell.npcRespawnsMutex.Unlock()
+
+	return s
+}
+
+func (h *Hub) SnapshotWorld() WorldSnapshot {
+	cells := h.GridManager.getAllCells()
+
+	snapshot := WorldSnapshot{
+		Version:       WorldSnapshotVersion,
+		CreatedAt:     time.Now(),
+		ResourceIdCnt: h.ResourceManager.getIdCnt(),
+		Cells:         make([]CellSnapshot, 0, len(cells)),
+		Respawns:      h.ResourceManager.getRespawns(),
+	}
+
+	for _, cell := range cells {
+		snapshot.Cells = append(snapshot.Cells, cell.snapshot())
+	}
+
+	return snapshot
+}
+
+// RestoreWorld recreates the cells of a snapshot, has to be called before clients connect
+func (h *Hub) RestoreWorld(snapshot WorldSnapshot) error {
+	if snapshot.Version != WorldSnapshotVersion {
+		return fmt.Errorf("unsupported world snapshot version %d, expected %d", snapshot.Version, WorldSnapshotVersion)
+	}
+
+	h.ResourceManager.setIdCnt(snapshot.ResourceIdCnt)
+	h.ResourceManager.setRespawns(snapshot.Respawns)
+
+	for _, cellSnapshot := range snapshot.Cells {
+		cell := h.GridManager.restoreCell(cellSnapshot)
+
+		resources := make([]*resource.Resource, 0, len(cellSnapshot.Resources))
+		for i := range cellSnapshot.Resources {
+			r := cellSnapshot.Resources[i]
+			resources = append(resources, &r)
+		}
+		h.ResourceManager.restoreResources(cell, resources)
+	}
+
+	fmt.Printf("restored %d cells from world snapshot\n", len(snapshot.Cells))
+	return nil
+}
+
+func (h *Hub) SaveWorld() {
+	if err := h.worldStore.Save(h.SnapshotWorld()); err != nil {
+		fmt.Printf("Error: failed to save world: %s\n", err)
+	}
+}
+
+// LoadWorld restores the last saved snapshot, a missing snapshot is not an error
+func (h *Hub) LoadWorld() error {
+	snapshot, ok, err := h.worldStore.Load()
+	if err != nil || !ok {
+		return err
+	}
+
+	return h.RestoreWorld(snapshot)
+}
+
+// WorldStore is the backend for world snapshots. Load returns false if nothing was saved yet.
+type WorldStore interface {
+	Load() (WorldSnapshot, bool, error)
+	Save(snapshot WorldSnapshot) error
+}
+
+// MemoryWorldStore keeps the last snapshot in memory
+type MemoryWorldStore struct {
+	snapshot      *WorldSnapshot
+	snapshotMutex sync.Mutex
+}
+
+func NewMemoryWorldStore() *MemoryWorldStore {
+	return &MemoryWorldStore{
+		snapshot:      nil,
+		snapshotMutex: sync.Mutex{},
+	}
+}
+
+func (s *MemoryWorldStore) Load() (WorldSnapshot, bool, error) {
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+
+	if s.snapshot == nil {
+		return WorldSnapshot{}, false, nil
+	}
+	return *s.snapshot, true, nil
+}
+
+func (s *MemoryWorldStore) Save(snapshot WorldSnapshot) error {
+	s.snapshotMutex.Lock()
+	defer s.snapshotMutex.Unlock()
+
+	s.snapshot = &snapshot
+	return nil
+}
+
+// FileWorldStore writes the snapshot as json to a single file
+type FileWorldStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileWorldStore(path string) (*FileWorldStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileWorldStore{
+		path:  path,
+		mutex: sync.Mutex{},
+	}, nil
+}
+
+func (s *FileWorldStore) Load() (WorldSnapshot, bool, error) {
+	snapshot := WorldSnapshot{}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, false, nil
+	}
+	if err != nil {
+		return snapshot, false, err
+	}
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, false, err
+	}
+
+	return snapshot, true, nil
+}
+
+func (s *FileWorldStore) Save(snapshot WorldSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return writeFileAtomic(s.path, data)
+}
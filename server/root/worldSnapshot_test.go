@@ -0,0 +1,76 @@
+package root
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+)
+
+func TestWorldSnapshotRoundTrip(t *testing.T) {
+	store := NewMemoryWorldStore()
+	hub := newTestHub(t)
+	hub.worldStore = store
+
+	cell := hub.GridManager.GetCell(3, -2)
+	r := resource.NewResource(resource.Blockade, shared.Vector{X: 3050, Y: -1950}, hub.ResourceManager.GetResourceId(), 1, true, 500, false, cell.GridCellKey)
+	hub.ResourceManager.restoreResources(cell, []*resource.Resource{r})
+
+	hub.SaveWorld()
+
+	restored := newTestHub(t)
+	restored.worldStore = store
+	if err := restored.LoadWorld(); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredResource, err := restored.ResourceManager.GetResource(r.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredResource.ResourceType != resource.Blockade || restoredResource.Pos != r.Pos {
+		t.Errorf("restored resource %+v does not match %+v", restoredResource, r)
+	}
+
+	if restored.ResourceManager.GetResourceId() <= r.Id {
+		t.Error("resource ids must continue after the restored id counter")
+	}
+
+	restoredCell := restored.GridManager.GetCell(3, -2)
+	if len(restoredCell.GetNpcList()) != len(cell.GetNpcList()) {
+		t.Errorf("expected %d npcs got %d", len(cell.GetNpcList()), len(restoredCell.GetNpcList()))
+	}
+}
+
+func TestRestoreWorldRejectsUnknownVersion(t *testing.T) {
+	hub := newTestHub(t)
+
+	if err := hub.RestoreWorld(WorldSnapshot{Version: WorldSnapshotVersion + 1}); err == nil {
+		t.Error("expected error for unknown snapshot version")
+	}
+}
+
+func TestRestoreCellAfterShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// nobody receives the cells to init
+	gm := NewGridManager(ctx, &sync.WaitGroup{}, make(chan *GridCell), DefaultConfig(), NewWorld(DefaultConfig().World), bestiary.Default(), item.Default())
+
+	restored := make(chan *GridCell)
+	go func() {
+		restored <- gm.restoreCell(CellSnapshot{X: 1, Y: 2})
+	}()
+
+	select {
+	case cell := <-restored:
+		if cell.Pos != (shared.Vector{X: 1, Y: 2}) {
+			t.Fatalf("expected cell 1#2, got %s", cell.GridCellKey)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("restoring a cell blocked after the shutdown")
+	}
+}
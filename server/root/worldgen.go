@@ -0,0 +1,150 @@
+package root
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/resource"
+	"ws-game/shared"
+
+	"github.com/google/uuid"
+)
+
+// World generation only depends on the world seed and the cell coordinates, a cell that gets
+// generated again (e.g. lost world snapshot) looks exactly like before. Every part of the
+// generation has its own random stream, so changing how many numbers one of them draws doesn't
+// change the others.
+const (
+	resourcesStream = "resources"
+	npcsStream      = "npcs"
+	itemsStream     = "items"
+)
+
+// cellRand returns the random generator for one part of the generation of cell x#y
+func cellRand(seed int64, x int, y int, stream string) *rand.Rand {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	binary.Write(h, binary.LittleEndian, int64(x))
+	binary.Write(h, binary.LittleEndian, int64(y))
+	h.Write([]byte(stream))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// randIntInRange is shared.RandIntInRangeFrom but also accepts min == max, config ranges may be empty
+func randIntInRange(r shared.Rand, min int, max int) int {
+	if min == max {
+		return min
+	}
+	return shared.RandIntInRangeFrom(r, min, max)
+}
+
+type resourceSpawn struct {
+	resourceType resource.ResourceType
+	pos          shared.Vector
+}
+
+// generateResources returns the trees and stones of a cell, at most one per sub cell on land.
+// The configured numbers are spread over the sub cells and scaled by the density of their biome.
+func (w *World) generateResources(cellPos shared.Vector, subCells []SubCell, config ResourcesConfig) []resourceSpawn {
+	r := cellRand(w.Seed, cellPos.X, cellPos.Y, resourcesStream)
+
+	numTrees := randIntInRange(r, config.MinTrees, config.MaxTrees)
+	numStones := randIntInRange(r, config.MinStones, config.MaxStones)
+	treeChance := float64(numTrees) / float64(len(subCells))
+	stoneChance := float64(numStones) / float64(len(subCells))
+
+	spawns := []resourceSpawn{}
+	for _, subCell := range subCells {
+		if subCell.TerrainType == Water || subCell.TerrainType == ShallowWater {
+			continue
+		}
+
+		biome := biomes[subCell.Biome]
+		var resourceType resource.ResourceType
+		switch {
+		case r.Float64() < treeChance*biome.treeDensity:
+			resourceType = resource.Tree
+		case r.Float64() < stoneChance*biome.stoneDensity:
+			resourceType = resource.Stone
+		default:
+			continue
+		}
+
+		spawns = append(spawns, resourceSpawn{resourceType: resourceType, pos: w.getRealResourcePos(cellPos, subCell.Pos, r)})
+	}
+
+	return spawns
+}
+
+// zone levels grow by one every zoneLevelCells cells away from 0#0
+const zoneLevelCells = 5
+
+// ZoneLevel is 1 around the center of the world and increases towards the outside
+func ZoneLevel(x int, y int) int {
+	distance := Abs(x)
+	if Abs(y) > distance {
+		distance = Abs(y)
+	}
+	return 1 + distance/zoneLevelCells
+}
+
+// generateNpcs returns the npcs a new cell starts with. Every land sub cell rolls against the density
+// of the spawn table matching its biome and the zone level, the npc spawns at the center of the sub cell.
+func (w *World) generateNpcs(x int, y int, subCells []SubCell, config NpcConfig, b bestiary.Bestiary) []Npc {
+	r := cellRand(w.Seed, x, y, npcsStream)
+	level := ZoneLevel(x, y)
+
+	npcs := []Npc{}
+	for _, subCell := range subCells {
+		if len(npcs) >= config.MaxPerCell {
+			break
+		}
+		if subCell.TerrainType == Water || subCell.TerrainType == ShallowWater {
+			continue
+		}
+
+		table, ok := b.SpawnTable(string(subCell.Biome), level)
+		if !ok || r.Float64() >= table.Density/float64(len(subCells)) {
+			continue
+		}
+
+		npcType, ok := table.Pick(r)
+		if !ok {
+			continue
+		}
+
+		npcPos := shared.Vector{
+			X: x*w.GridCellSize + subCell.Pos.X*w.SubCellSize + w.SubCellSize/2,
+			Y: y*w.GridCellSize + subCell.Pos.Y*w.SubCellSize + w.SubCellSize/2,
+		}
+		npc := NewNpcOf(npcPos, npcType, b.Archetypes[npcType], config)
+		npc.UUID = uuid.Must(uuid.NewRandomFromReader(r)).String()
+		npcs = append(npcs, npc)
+	}
+	return npcs
+}
+
+// generateItems returns the starter items lying around in a new cell
+func (w *World) generateItems(x int, y int, items item.Catalog) []item.Item {
+	spawned := []item.Item{}
+
+	// spawn some items for testing
+	if x != 0 || y != 0 {
+		return spawned
+	}
+
+	r := cellRand(w.Seed, x, y, itemsStream)
+	cellPos := shared.Vector{X: x, Y: y}
+	cellCenter := shared.Vector{X: (x * w.GridCellSize) + w.GridCellSize/2, Y: (y * w.GridCellSize) + w.GridCellSize/2}
+	for i := 0; i < 5; i++ {
+		// start with center
+		spawnPos := cellCenter.Copy()
+		spawnPos.X += shared.RandIntInRangeFrom(r, -w.GridCellSize/2, w.GridCellSize/2)
+		spawnPos.Y += shared.RandIntInRangeFrom(r, -w.GridCellSize/2, w.GridCellSize/2)
+
+		spawned = append(spawned, items.NewItemFrom(r, cellPos, ZoneLevel(x, y), spawnPos))
+	}
+	return spawned
+}
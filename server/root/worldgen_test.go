@@ -0,0 +1,42 @@
+package root
+
+import (
+	"reflect"
+	"testing"
+	"ws-game/bestiary"
+	"ws-game/item"
+	"ws-game/shared"
+)
+
+func TestWorldgenIsReproducible(t *testing.T) {
+	config := DefaultConfig()
+	w := NewWorld(config.World)
+	cellPos := shared.Vector{X: 0, Y: 0}
+	subCells := w.getSubCells(cellPos.X, cellPos.Y)
+
+	resources := w.generateResources(cellPos, subCells, config.Resources)
+	if len(resources) == 0 {
+		t.Fatal("expected resources in cell 0#0")
+	}
+	if !reflect.DeepEqual(resources, w.generateResources(cellPos, subCells, config.Resources)) {
+		t.Fatal("same seed and cell generated different resources")
+	}
+	otherSeed := config.World
+	otherSeed.Seed++
+	if reflect.DeepEqual(resources, NewWorld(otherSeed).generateResources(cellPos, subCells, config.Resources)) {
+		t.Fatal("different seeds generated the same resources")
+	}
+
+	npcs := w.generateNpcs(0, 0, subCells, config.Npc, bestiary.Default())
+	if !reflect.DeepEqual(npcs, w.generateNpcs(0, 0, subCells, config.Npc, bestiary.Default())) {
+		t.Fatal("same seed and cell generated different npcs")
+	}
+	if len(npcs) > config.Npc.MaxPerCell {
+		t.Fatalf("expected at most %d npcs, got %d", config.Npc.MaxPerCell, len(npcs))
+	}
+
+	items := w.generateItems(0, 0, item.Default())
+	if len(items) == 0 || !reflect.DeepEqual(items, w.generateItems(0, 0, item.Default())) {
+		t.Fatal("same seed and cell generated different items")
+	}
+}
@@ -1,6 +1,7 @@
 package shared
 
 import (
+	cryptorand "crypto/rand"
 	"fmt"
 	"math"
 	"math/rand"
@@ -11,13 +12,42 @@ type Hitpoints struct {
 	Max     int `json:"max"`
 }
 
+// Rand is implemented by *rand.Rand, world generation uses seeded ones to be reproducible
+type Rand interface {
+	Intn(n int) int
+	Float64() float64
+	Read(p []byte) (int, error)
+}
+
+// GlobalRand uses the global math/rand functions and crypto random bytes (for uuids)
+var GlobalRand Rand = globalRand{}
+
+type globalRand struct{}
+
+func (globalRand) Intn(n int) int {
+	return rand.Intn(n)
+}
+
+func (globalRand) Float64() float64 {
+	return rand.Float64()
+}
+
+func (globalRand) Read(p []byte) (int, error) {
+	return cryptorand.Read(p)
+}
+
 func RandIntInRange(min int, max int) int {
+	return RandIntInRangeFrom(GlobalRand, min, max)
+}
+
+// RandIntInRangeFrom returns a value in [min, max) drawn from r
+func RandIntInRangeFrom(r Rand, min int, max int) int {
 
 	if max < min || min > max || min == max {
 		panic(fmt.Sprintf("wrong value range for RandIntInRange %d %d\n", min, max))
 	}
 
-	return rand.Intn(max-min) + min
+	return r.Intn(max-min) + min
 }
 
 type Vector struct {